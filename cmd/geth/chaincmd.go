@@ -603,7 +603,7 @@ func inspect(ctx *cli.Context) error {
 	_, chainDb := utils.MakeChain(ctx, node, true)
 	defer chainDb.Close()
 
-	return rawdb.InspectDatabase(chainDb)
+	return rawdb.InspectDatabase(chainDb, nil, nil)
 }
 
 // hashish returns true for strings that look like hashes.
@@ -0,0 +1,455 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// dbFlags are the flags needed by every database subcommand to locate
+	// the chain database of the selected network.
+	dbFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.AncientRPCFlag,
+		utils.DBEngineFlag,
+		utils.CacheFlag,
+		utils.SyncModeFlag,
+		utils.RopstenFlag,
+		utils.RinkebyFlag,
+		utils.GoerliFlag,
+		utils.YoloV2Flag,
+		utils.LegacyTestnetFlag,
+	}
+
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Description: `
+The db commands operate directly on the chain database of a stopped node. They
+are meant for debugging and repairing, use the raw key/value ones with care.`,
+		Subcommands: []cli.Command{
+			dbInspectCmd,
+			dbStatCmd,
+			dbCompactCmd,
+			dbGetCmd,
+			dbDeleteCmd,
+			dbPutCmd,
+			dbDumpTrieCmd,
+			dbDumpFreezerIndex,
+			dbServeFreezerCmd,
+		},
+	}
+	dbInspectCmd = cli.Command{
+		Action:      utils.MigrateFlags(inspectDatabase),
+		Name:        "inspect",
+		ArgsUsage:   "<hex-encoded prefix> <hex-encoded start>",
+		Flags:       dbFlags,
+		Usage:       "Inspect the storage size for each type of data in the database",
+		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
+	}
+	dbStatCmd = cli.Command{
+		Action: utils.MigrateFlags(dbStats),
+		Name:   "stats",
+		Usage:  "Print leveldb statistics",
+		Flags:  dbFlags,
+	}
+	dbCompactCmd = cli.Command{
+		Action: utils.MigrateFlags(dbCompact),
+		Name:   "compact",
+		Usage:  "Compact leveldb database. WARNING: May take a very long time",
+		Flags:  dbFlags,
+		Description: `This command performs a database compaction.
+WARNING: This operation may take a very long time to finish, and may cause database
+corruption if it is aborted during execution!`,
+	}
+	dbGetCmd = cli.Command{
+		Action:      utils.MigrateFlags(dbGet),
+		Name:        "get",
+		Usage:       "Show the value of a database key",
+		ArgsUsage:   "<hex-encoded key>",
+		Flags:       dbFlags,
+		Description: "This command looks up the specified database key from the database.",
+	}
+	dbDeleteCmd = cli.Command{
+		Action:    utils.MigrateFlags(dbDelete),
+		Name:      "delete",
+		Usage:     "Delete a database key (WARNING: may corrupt your database)",
+		ArgsUsage: "<hex-encoded key>",
+		Flags:     dbFlags,
+		Description: `This command deletes the specified database key from the database.
+WARNING: This is a low-level operation which may cause database corruption!`,
+	}
+	dbPutCmd = cli.Command{
+		Action:    utils.MigrateFlags(dbPut),
+		Name:      "put",
+		Usage:     "Set the value of a database key (WARNING: may corrupt your database)",
+		ArgsUsage: "<hex-encoded key> <hex-encoded value>",
+		Flags:     dbFlags,
+		Description: `This command sets a given database key to the given value.
+WARNING: This is a low-level operation which may cause database corruption!`,
+	}
+	dbDumpTrieCmd = cli.Command{
+		Action:      utils.MigrateFlags(dbDumpTrie),
+		Name:        "dumptrie",
+		Usage:       "Show the storage key/values of a given storage trie",
+		ArgsUsage:   "<hex-encoded storage trie root> <hex-encoded start (optional)> <int max elements (optional)>",
+		Flags:       dbFlags,
+		Description: "This command iterates the given storage trie and prints its slots, optionally starting at a given key and stopping after a number of entries.",
+	}
+	dbDumpFreezerIndex = cli.Command{
+		Action:      utils.MigrateFlags(freezerInspect),
+		Name:        "freezer-index",
+		Usage:       "Dump out the index of a given freezer type",
+		ArgsUsage:   "<type> <start (int)> <end (int)>",
+		Flags:       dbFlags,
+		Description: "This command displays information about the freezer index.",
+	}
+	dbServeFreezerCmd = cli.Command{
+		Action:    utils.MigrateFlags(freezerServe),
+		Name:      "serve-freezer",
+		Usage:     "Serve the ancient chain segments to other nodes over IPC",
+		ArgsUsage: "<ipc endpoint (optional)>",
+		Flags:     dbFlags,
+		Description: `This command opens the ancient chain segment store (freezer) and serves it
+over an IPC socket (freezer.ipc in the data directory by default). Nodes on the
+same host can share it by running with --datadir.ancient.rpc=<ipc endpoint>.
+
+The first node attaching to the store becomes its single writer, moving its chain
+segments into it. It is recorded in the WRITER file of the ancient directory and
+keeps its role across restarts, delete the file to reassign it. All other nodes
+only read from the store. Frozen data can't be removed by any of them, nor can
+they rewind their chains below it.`,
+	}
+)
+
+func inspectDatabase(ctx *cli.Context) error {
+	var (
+		prefix []byte
+		start  []byte
+		err    error
+	)
+	if ctx.NArg() > 2 {
+		return fmt.Errorf("Max 2 arguments: %v", ctx.Command.ArgsUsage)
+	}
+	if ctx.NArg() >= 1 {
+		if prefix, err = parseHex(ctx.Args().Get(0)); err != nil {
+			return fmt.Errorf("failed to parse prefix: %v", err)
+		}
+	}
+	if ctx.NArg() >= 2 {
+		if start, err = parseHex(ctx.Args().Get(1)); err != nil {
+			return fmt.Errorf("failed to parse start: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	return rawdb.InspectDatabase(db, prefix, start)
+}
+
+// showLeveldbStats prints the internal statistics of the underlying leveldb
+// database, if the database exposes them.
+func showLeveldbStats(db ethdb.Stater) {
+	if stats, err := db.Stat("leveldb.stats"); err != nil {
+		log.Warn("Failed to read database stats", "error", err)
+	} else {
+		fmt.Println(stats)
+	}
+	if ioStats, err := db.Stat("leveldb.iostats"); err != nil {
+		log.Warn("Failed to read database iostats", "error", err)
+	} else {
+		fmt.Println(ioStats)
+	}
+}
+
+func dbStats(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	showLeveldbStats(db)
+	return nil
+}
+
+func dbCompact(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	log.Info("Stats before compaction")
+	showLeveldbStats(db)
+
+	log.Info("Triggering compaction")
+	start := time.Now()
+	if err := db.Compact(nil, nil); err != nil {
+		log.Info("Compact err", "error", err)
+		return err
+	}
+	log.Info("Compaction finished", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	log.Info("Stats after compaction")
+	showLeveldbStats(db)
+	return nil
+}
+
+// dbGet shows the value of a given database key
+func dbGet(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	key, err := parseHex(ctx.Args().Get(0))
+	if err != nil {
+		log.Info("Could not decode the key", "error", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	data, err := db.Get(key)
+	if err != nil {
+		log.Info("Get operation failed", "key", fmt.Sprintf("%#x", key), "error", err)
+		return err
+	}
+	fmt.Printf("key %#x: %#x\n", key, data)
+	return nil
+}
+
+// dbDelete deletes a key from the database
+func dbDelete(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	key, err := parseHex(ctx.Args().Get(0))
+	if err != nil {
+		log.Info("Could not decode the key", "error", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	data, err := db.Get(key)
+	if err == nil {
+		fmt.Printf("Previous value: %#x\n", data)
+	}
+	if err = db.Delete(key); err != nil {
+		log.Info("Delete operation returned an error", "key", fmt.Sprintf("%#x", key), "error", err)
+		return err
+	}
+	return nil
+}
+
+// dbPut overwrite a value in the database
+func dbPut(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	key, err := parseHex(ctx.Args().Get(0))
+	if err != nil {
+		log.Info("Could not decode the key", "error", err)
+		return err
+	}
+	value, err := parseHex(ctx.Args().Get(1))
+	if err != nil {
+		log.Info("Could not decode the value", "error", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	data, err := db.Get(key)
+	if err == nil {
+		fmt.Printf("Previous value: %#x\n", data)
+	}
+	return db.Put(key, value)
+}
+
+// dbDumpTrie shows the key-value slots of a given storage trie
+func dbDumpTrie(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		root  []byte
+		start []byte
+		max   = int64(-1)
+		err   error
+	)
+	if root, err = parseHex(ctx.Args().Get(0)); err != nil {
+		log.Info("Could not decode the root", "error", err)
+		return err
+	}
+	if ctx.NArg() >= 2 {
+		if start, err = parseHex(ctx.Args().Get(1)); err != nil {
+			log.Info("Could not decode the seek position", "error", err)
+			return err
+		}
+	}
+	if ctx.NArg() >= 3 {
+		if max, err = strconv.ParseInt(ctx.Args().Get(2), 10, 64); err != nil {
+			log.Info("Could not decode the max count", "error", err)
+			return err
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	theTrie, err := trie.New(common.BytesToHash(root), trie.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var count int64
+	it := trie.NewIterator(theTrie.NodeIterator(start))
+	for it.Next() {
+		if max > 0 && count == max {
+			fmt.Printf("Exiting after %d values\n", count)
+			break
+		}
+		fmt.Printf("  %d. key %#x: %#x\n", count, it.Key, it.Value)
+		count++
+	}
+	return it.Err
+}
+
+// freezerInspect dumps out the index of a freezer table within the given range
+func freezerInspect(ctx *cli.Context) error {
+	if ctx.NArg() < 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	kind := ctx.Args().Get(0)
+	start, err := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		log.Info("Could not read start-param", "error", err)
+		return err
+	}
+	end, err := strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+	if err != nil {
+		log.Info("Could not read count param", "error", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ancientPath(ctx, stack)
+	log.Info("Opening freezer", "location", path, "name", kind)
+	return rawdb.InspectFreezerTable(path, kind, start, end)
+}
+
+// freezerServe opens the local ancient store and serves it over IPC until the
+// process is interrupted.
+func freezerServe(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("Max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ancientPath(ctx, stack)
+	store, err := rawdb.NewFreezer(path, "")
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	api, err := rawdb.NewAncientStoreAPI(store, path)
+	if err != nil {
+		return err
+	}
+	endpoint := stack.ResolvePath("freezer.ipc")
+	if ctx.NArg() == 1 {
+		endpoint = ctx.Args().Get(0)
+	}
+	listener, server, err := rpc.StartIPCEndpoint(endpoint, []rpc.API{{
+		Namespace: "freezer",
+		Version:   "1.0",
+		Service:   api,
+		Public:    true,
+	}})
+	if err != nil {
+		return err
+	}
+	defer server.Stop()
+	defer listener.Close()
+
+	log.Info("Serving ancient database", "database", path, "endpoint", endpoint)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+
+	log.Info("Shutting down ancient database server")
+	return nil
+}
+
+// ancientPath resolves the location of the local ancient store the same way
+// the node opens it.
+func ancientPath(ctx *cli.Context, stack *node.Node) string {
+	path := ctx.GlobalString(utils.AncientFlag.Name)
+	switch {
+	case path == "":
+		path = filepath.Join(stack.ResolvePath("chaindata"), "ancient")
+	case !filepath.IsAbs(path):
+		path = stack.ResolvePath(path)
+	}
+	return path
+}
+
+// parseHex decodes the given hex string, with or without the 0x prefix.
+func parseHex(str string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(str, "0x"), "0X"))
+}
@@ -65,6 +65,8 @@ var (
 		utils.LegacyBootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.AncientFlag,
+		utils.AncientRPCFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
@@ -88,11 +90,13 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolDropHistoryFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.LogIndexFlag,
 		utils.LightServeFlag,
 		utils.LegacyLightServFlag,
 		utils.LightIngressFlag,
@@ -131,6 +135,8 @@ var (
 		utils.LegacyMinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerBlockBuilderFlag,
+		utils.MinerRemoteSealFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -174,7 +180,9 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLTracingFlag,
 		utils.HTTPApiFlag,
+		utils.HTTPJWTSecretFlag,
 		utils.LegacyRPCApiFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
@@ -182,6 +190,7 @@ var (
 		utils.WSPortFlag,
 		utils.LegacyWSPortFlag,
 		utils.WSApiFlag,
+		utils.WSJWTSecretFlag,
 		utils.LegacyWSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.LegacyWSAllowedOriginsFlag,
@@ -190,6 +199,13 @@ var (
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCLogRangeCapFlag,
+		utils.RPCLogResultCapFlag,
+		utils.RPCBatchItemLimitFlag,
+		utils.RPCResponseSizeLimitFlag,
+		utils.RPCMethodLimitsFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -244,6 +260,10 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See snapshot.go
+		snapshotCommand,
+		// See dbcmd.go
+		dbCommand,
 		// See retesteth.go
 		retestethCommand,
 		// See cmd/utils/flags_legacy.go
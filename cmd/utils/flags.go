@@ -113,6 +113,14 @@ var (
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	AncientRPCFlag = cli.StringFlag{
+		Name:  "datadir.ancient.rpc",
+		Usage: "RPC endpoint (IPC socket, http or ws URL) of a shared ancient chain segment store",
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'pebble', default = autodetect or leveldb)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -149,7 +157,7 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, instant sealing enabled",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
@@ -187,7 +195,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -204,6 +212,10 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index by-hash for (default = index all blocks)",
 		Value: 0,
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an index of log addresses and topics to speed up log filtering",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -343,6 +355,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolDropHistoryFlag = cli.Uint64Flag{
+		Name:  "txpool.drophistory",
+		Usage: "Number of recently dropped transactions to remember the drop reasons of",
+		Value: eth.DefaultConfig.TxPool.DropHistory,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -383,6 +400,11 @@ var (
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 	}
+	BloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to bloom-filter for pruning",
+		Value: 2048,
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -430,6 +452,15 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerBlockBuilderFlag = cli.StringFlag{
+		Name:  "miner.builder",
+		Usage: "Strategy selecting and ordering the transactions of mined blocks (price, fcfs)",
+		Value: miner.DefaultBlockBuilder,
+	}
+	MinerRemoteSealFlag = cli.BoolFlag{
+		Name:  "miner.remoteseal",
+		Usage: "Mine without a local clique signer key, leaving blocks to be sealed via miner_getBlockTemplate",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -464,6 +495,34 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: eth.DefaultConfig.RPCTxFeeCap,
 	}
+	RPCLogRangeCapFlag = cli.Uint64Flag{
+		Name:  "rpc.lograngecap",
+		Usage: "Sets a cap on the number of blocks a log query can span via eth_getLogs/getFilterLogs (0 = no cap)",
+	}
+	RPCLogResultCapFlag = cli.IntFlag{
+		Name:  "rpc.logresultcap",
+		Usage: "Sets a cap on the number of logs a log query can return via eth_getLogs/getFilterLogs (0 = no cap)",
+	}
+	RPCBatchItemLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a batch served via HTTP/WS (0 = no limit)",
+	}
+	RPCResponseSizeLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum total size in bytes of the results of a request or batch served via HTTP/WS (0 = no limit)",
+	}
+	RPCMethodLimitsFlag = cli.StringFlag{
+		Name:  "rpc.methodlimits",
+		Usage: "Comma separated list of method=count pairs capping the concurrent calls of methods (e.g. eth_getLogs=4)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Sustained requests per second allowed per remote host via HTTP/WS (0 = no limit)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.ratelimit.burst",
+		Usage: "Requests a remote host may burst above the rate limit (defaults to one second's worth)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -515,6 +574,11 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	HTTPJWTSecretFlag = cli.StringFlag{
+		Name:  "http.jwtsecret",
+		Usage: "Path to a hex encoded secret requiring HS256 JWT authentication on the HTTP-RPC interface (generated if missing)",
+		Value: "",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -529,6 +593,10 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLTracingFlag = cli.BoolFlag{
+		Name:  "graphql.tracing",
+		Usage: "Enable transaction tracing with native tracers over GraphQL (expensive)",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -548,6 +616,11 @@ var (
 		Usage: "API's offered over the WS-RPC interface",
 		Value: "",
 	}
+	WSJWTSecretFlag = cli.StringFlag{
+		Name:  "ws.jwtsecret",
+		Usage: "Path to a hex encoded secret requiring HS256 JWT authentication on the WS-RPC interface (generated if missing)",
+		Value: "",
+	}
 	WSAllowedOriginsFlag = cli.StringFlag{
 		Name:  "ws.origins",
 		Usage: "Origins from which to accept websockets requests",
@@ -938,6 +1011,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.GlobalString(HTTPVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(HTTPJWTSecretFlag.Name) {
+		cfg.HTTPJWTSecret = ctx.GlobalString(HTTPJWTSecretFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -949,6 +1025,9 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.GlobalBool(GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -987,6 +1066,40 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = SplitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSJWTSecretFlag.Name) {
+		cfg.WSJWTSecret = ctx.GlobalString(WSJWTSecretFlag.Name)
+	}
+}
+
+// setRPCLimits configures the resource limits of the HTTP and WebSocket RPC
+// servers from the command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchItemLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchItemLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseSizeLimitFlag.Name) {
+		cfg.RPCLimits.ResponseBytes = ctx.GlobalInt(RPCResponseSizeLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodLimitsFlag.Name) {
+		cfg.RPCLimits.MethodConcurrency = make(map[string]int)
+		for _, limit := range SplitAndTrim(ctx.GlobalString(RPCMethodLimitsFlag.Name)) {
+			parts := strings.Split(limit, "=")
+			if len(parts) != 2 {
+				Fatalf("Invalid method limit %q, expected method=count", limit)
+			}
+			count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil || count <= 0 {
+				Fatalf("Invalid concurrency limit for method %s: %q", parts[0], parts[1])
+			}
+			cfg.RPCLimits.MethodConcurrency[strings.TrimSpace(parts[0])] = count
+		}
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.RateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCLimits.RateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1207,6 +1320,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1218,6 +1332,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		dbEngine := ctx.GlobalString(DBEngineFlag.Name)
+		if dbEngine != rawdb.DBLeveldb && dbEngine != rawdb.DBPebble {
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'pebble'", dbEngine)
+		}
+		cfg.DBEngine = dbEngine
+	}
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
@@ -1341,6 +1462,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolDropHistoryFlag.Name) {
+		cfg.DropHistory = ctx.GlobalUint64(TxPoolDropHistoryFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1404,6 +1528,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerBlockBuilderFlag.Name) {
+		cfg.BlockBuilder = ctx.GlobalString(MinerBlockBuilderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerRemoteSealFlag.Name) {
+		cfg.RemoteSeal = ctx.GlobalBool(MinerRemoteSealFlag.Name)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
@@ -1516,6 +1646,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
 	}
+	if ctx.GlobalIsSet(AncientRPCFlag.Name) {
+		cfg.DatabaseFreezer = ancientRPCEndpoint(ctx)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1529,6 +1662,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1574,6 +1710,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCLogRangeCapFlag.Name) {
+		cfg.RPCLogRangeCap = ctx.GlobalUint64(RPCLogRangeCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogResultCapFlag.Name) {
+		cfg.RPCLogResultCap = ctx.GlobalInt(RPCLogResultCapFlag.Name)
+	}
 	if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
 		urls := ctx.GlobalString(DNSDiscoveryFlag.Name)
 		if urls == "" {
@@ -1654,6 +1796,11 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		if !ctx.GlobalIsSet(MinerGasPriceFlag.Name) && !ctx.GlobalIsSet(LegacyMinerGasPriceFlag.Name) {
 			cfg.Miner.GasPrice = big.NewInt(1)
 		}
+		// Seal blocks on demand, retaining all states so the dev API can rewind
+		cfg.Developer = true
+		if !ctx.GlobalIsSet(GCModeFlag.Name) {
+			cfg.NoPruning = true
+		}
 	default:
 		if cfg.NetworkId == 1 {
 			SetDNSDiscoveryDefaults(cfg, params.MainnetGenesisHash)
@@ -1709,8 +1856,8 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config, lightMode bool) {
+	if err := graphql.New(stack, backend, lightMode, cfg.GraphQLCors, cfg.GraphQLVirtualHosts, cfg.GraphQLTracing); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
@@ -1774,7 +1921,11 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		chainDb, err = stack.OpenDatabase(name, cache, handles, "")
 	} else {
 		name := "chaindata"
-		chainDb, err = stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name), "")
+		freezer := ctx.GlobalString(AncientFlag.Name)
+		if ctx.GlobalIsSet(AncientRPCFlag.Name) {
+			freezer = ancientRPCEndpoint(ctx)
+		}
+		chainDb, err = stack.OpenDatabaseWithFreezer(name, cache, handles, freezer, "")
 	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
@@ -1782,6 +1933,19 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	return chainDb
 }
 
+// ancientRPCEndpoint retrieves the endpoint of the remote ancient store, making
+// sure it's not combined with a local one.
+func ancientRPCEndpoint(ctx *cli.Context) string {
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		Fatalf("Flags --%s and --%s are mutually exclusive", AncientFlag.Name, AncientRPCFlag.Name)
+	}
+	endpoint := ctx.GlobalString(AncientRPCFlag.Name)
+	if !rawdb.IsRemoteFreezer(endpoint) {
+		Fatalf("Invalid remote ancient store endpoint %q, need an IPC socket, http or ws URL", endpoint)
+	}
+	return endpoint
+}
+
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
 	switch {
@@ -0,0 +1,290 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxReplayBlocks is the maximum number of blocks a single activity or vote
+// history query may cover.
+const maxReplayBlocks = 8192
+
+// SignerActivity is the sealing record of a signer over a range of blocks.
+type SignerActivity struct {
+	InTurn     uint64 `json:"inTurn"`     // Number of blocks sealed in-turn
+	OutOfTurn  uint64 `json:"outOfTurn"`  // Number of blocks sealed out-of-turn
+	Missed     uint64 `json:"missed"`     // Number of in-turn slots sealed by another signer
+	LastSealed uint64 `json:"lastSealed"` // Number of the last block sealed, 0 if none
+}
+
+// ActivityReport is the sealing record of all signers over a range of blocks.
+type ActivityReport struct {
+	From    uint64                             `json:"from"`
+	To      uint64                             `json:"to"`
+	Signers map[common.Address]*SignerActivity `json:"signers"`
+}
+
+// VoteEvent is a vote cast in a block, along with its effect on the tally of
+// the proposal voted on.
+type VoteEvent struct {
+	Block     uint64         `json:"block"`     // Block number the vote was cast in
+	Signer    common.Address `json:"signer"`    // Authorized signer that cast the vote
+	Address   common.Address `json:"address"`   // Account being voted on to change its authorization
+	Authorize bool           `json:"authorize"` // Whether to authorize or deauthorize the voted account
+	Votes     int            `json:"votes"`     // Number of votes for the proposal after this one
+	Passed    bool           `json:"passed"`    // Whether the vote pushed the proposal through
+}
+
+// activityTracker keeps the per-signer missed slot gauges up to date with the
+// canonical chain. Blocks are only accounted for once they become canonical, so
+// locally sealed blocks which are discarded or reorged out are never counted.
+//
+// The tracker is updated on chain head changes (see Clique.UpdateActivity), never
+// while verifying headers, as catching up might replay thousands of blocks.
+type activityTracker struct {
+	lock   sync.Mutex
+	number uint64                    // Number of the last block recorded
+	hash   common.Hash               // Hash of the last block recorded
+	missed map[common.Address]uint64 // Consecutive in-turn slots missed by each signer
+}
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{missed: make(map[common.Address]uint64)}
+}
+
+// UpdateActivity accounts for the canonical blocks imported since the last update
+// in the missed slot metrics of the signers. It is meant to be called on chain
+// head changes.
+func (c *Clique) UpdateActivity(chain consensus.ChainHeaderReader) {
+	c.activity.sync(c, chain)
+}
+
+// sync records the canonical blocks imported since the last sync. If the chain
+// was reorged or rewound below the last recorded block, or progressed too far
+// since, the gauges are rebuilt from the recent canonical blocks.
+func (t *activityTracker) sync(c *Clique, chain consensus.ChainHeaderReader) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	head := chain.CurrentHeader()
+	if head == nil || head.Hash() == t.hash {
+		return
+	}
+	number := head.Number.Uint64()
+
+	from := t.number + 1
+	if prev := chain.GetHeaderByNumber(t.number); t.number == 0 || number < t.number || number-t.number > maxReplayBlocks || prev == nil || prev.Hash() != t.hash {
+		for signer := range t.missed {
+			t.update(signer, 0)
+		}
+		from = 1
+		if number >= maxReplayBlocks {
+			from = number - maxReplayBlocks + 1
+		}
+	}
+	for ; from <= number; from++ {
+		header := chain.GetHeaderByNumber(from)
+		if header == nil {
+			return // chain rewound while syncing, retry on the next one
+		}
+		snap, err := c.snapshot(chain, from-1, header.ParentHash, nil)
+		if err != nil {
+			return
+		}
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return
+		}
+		t.record(snap, header, signer)
+	}
+}
+
+// record accounts for a block sealed by the given signer on top of the given
+// snapshot.
+func (t *activityTracker) record(snap *Snapshot, header *types.Header, signer common.Address) {
+	number := header.Number.Uint64()
+	t.number, t.hash = number, header.Hash()
+
+	t.update(signer, 0)
+	if inturn := snap.inturnSigner(number); inturn != signer {
+		t.update(inturn, t.missed[inturn]+1)
+	}
+}
+
+// update sets the number of consecutive in-turn slots missed by a signer.
+func (t *activityTracker) update(signer common.Address, missed uint64) {
+	t.missed[signer] = missed
+	metrics.GetOrRegisterGauge("clique/missed/"+signer.Hex(), nil).Update(int64(missed))
+}
+
+// inturnSigner returns the signer whose turn it is to seal the given block.
+func (s *Snapshot) inturnSigner(number uint64) common.Address {
+	signers := s.signers()
+	return signers[number%uint64(len(signers))]
+}
+
+// replayRange resolves the block range of an activity or vote history query,
+// defaulting to the last maxReplayBlocks blocks up to the current head.
+func (api *API) replayRange(from, to *rpc.BlockNumber) (uint64, uint64, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+
+	last := head
+	if to != nil && *to >= 0 {
+		last = uint64(*to)
+	}
+	if last > head {
+		return 0, 0, fmt.Errorf("block #%d beyond head #%d", last, head)
+	}
+	first := uint64(1)
+	if from != nil && *from >= 0 {
+		first = uint64(*from)
+	} else if last >= maxReplayBlocks {
+		first = last - maxReplayBlocks + 1
+	}
+	if first == 0 {
+		first = 1 // genesis is not sealed
+	}
+	if first > last {
+		return 0, 0, errors.New("empty block range")
+	}
+	if last-first >= maxReplayBlocks {
+		return 0, 0, fmt.Errorf("block range too large (%d > %d)", last-first+1, maxReplayBlocks)
+	}
+	return first, last, nil
+}
+
+// replay walks the canonical headers from..to (inclusive), calling fn with the
+// snapshot preceding each header, the header, its signer and the snapshot after
+// applying it.
+func (api *API) replay(from, to uint64, fn func(parent *Snapshot, header *types.Header, signer common.Address, snap *Snapshot)) error {
+	prev := api.chain.GetHeaderByNumber(from - 1)
+	if prev == nil {
+		return errUnknownBlock
+	}
+	snap, err := api.clique.snapshot(api.chain, prev.Number.Uint64(), prev.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	for number := from; number <= to; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil || header.ParentHash != prev.Hash() {
+			return errUnknownBlock // chain reorged or rewound while replaying
+		}
+		signer, err := ecrecover(header, api.clique.signatures)
+		if err != nil {
+			return err
+		}
+		next, err := snap.apply([]*types.Header{header})
+		if err != nil {
+			return err
+		}
+		fn(snap, header, signer, next)
+		prev, snap = header, next
+	}
+	return nil
+}
+
+// signerActivity collects the sealing record of the signers over the given
+// range of blocks.
+func (api *API) signerActivity(from, to uint64) (*ActivityReport, error) {
+	report := &ActivityReport{
+		From:    from,
+		To:      to,
+		Signers: make(map[common.Address]*SignerActivity),
+	}
+	activity := func(signer common.Address) *SignerActivity {
+		if report.Signers[signer] == nil {
+			report.Signers[signer] = new(SignerActivity)
+		}
+		return report.Signers[signer]
+	}
+	err := api.replay(from, to, func(parent *Snapshot, header *types.Header, signer common.Address, snap *Snapshot) {
+		number := header.Number.Uint64()
+		for _, s := range parent.signers() {
+			activity(s)
+		}
+		sealer := activity(signer)
+		sealer.LastSealed = number
+
+		if inturn := parent.inturnSigner(number); inturn == signer {
+			sealer.InTurn++
+		} else {
+			sealer.OutOfTurn++
+			activity(inturn).Missed++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// voteHistory collects the votes cast over the given range of blocks.
+func (api *API) voteHistory(from, to uint64) ([]*VoteEvent, error) {
+	votes := []*VoteEvent{}
+	err := api.replay(from, to, func(parent *Snapshot, header *types.Header, signer common.Address, snap *Snapshot) {
+		authorize := bytes.Equal(header.Nonce[:], nonceAuthVote)
+		if header.Number.Uint64()%parent.config.EpochAt(header.Number.Uint64()) == 0 || !parent.validVote(header.Coinbase, authorize) {
+			return // checkpoint or no vote cast
+		}
+		_, before := parent.Signers[header.Coinbase]
+		_, after := snap.Signers[header.Coinbase]
+
+		vote := &VoteEvent{
+			Block:     header.Number.Uint64(),
+			Signer:    signer,
+			Address:   header.Coinbase,
+			Authorize: authorize,
+			Votes:     snap.Tally[header.Coinbase].Votes,
+			Passed:    before != after,
+		}
+		if vote.Passed {
+			// The tally of passed proposals is discarded, recount it
+			vote.Votes = parent.recount(signer, header.Coinbase, authorize)
+		}
+		votes = append(votes, vote)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// recount returns the number of votes a proposal would have after casting the
+// given vote on top of the snapshot, replacing any earlier vote of the signer.
+func (s *Snapshot) recount(signer, address common.Address, authorize bool) int {
+	snap := s.copy()
+	for i, vote := range snap.Votes {
+		if vote.Signer == signer && vote.Address == address {
+			snap.uncast(vote.Address, vote.Authorize)
+			snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+			break
+		}
+	}
+	snap.cast(address, authorize)
+	return snap.Tally[address].Votes
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newActivityTestChain creates a chain sealed by the given sequence of votes on
+// top of a genesis authorizing the given signers, returning the API serving it.
+func newActivityTestChain(t *testing.T, accounts *testerAccountPool, signers []string, votes []testerVote) *API {
+	auths := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		auths[i] = accounts.address(signer)
+	}
+	sort.Sort(signersAscending(auths))
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(auths)+extraSeal),
+	}
+	for i, auth := range auths {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], auth[:])
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis.Commit(db)
+
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	engine := New(config.Clique, db)
+	engine.fakeDiff = true
+
+	blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(votes), func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(accounts.address(votes[i].voted))
+		if votes[i].auth {
+			var nonce types.BlockNonce
+			copy(nonce[:], nonceAuthVote)
+			gen.SetNonce(nonce)
+		}
+	})
+	for i, block := range blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		header.Difficulty = diffInTurn // Ignored, we just need a valid number
+
+		accounts.sign(header, votes[i].signer)
+		blocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	return &API{chain: chain, clique: engine}
+}
+
+// Tests that the signer activity reports in-turn, out-of-turn and missed slots
+// correctly, and that the missed slot tracker follows the canonical chain.
+func TestSignerActivity(t *testing.T) {
+	accounts := newTesterAccountPool()
+
+	// Order the signers by their turns
+	names := []string{"A", "B", "C"}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := accounts.address(names[i]), accounts.address(names[j])
+		return signersAscending{a, b}.Less(0, 1)
+	})
+	votes := []testerVote{
+		{signer: names[1]}, // in-turn
+		{signer: names[2]}, // in-turn
+		{signer: names[1]}, // out-of-turn, names[0] missed
+		{signer: names[2]}, // out-of-turn, names[1] missed
+		{signer: names[1]}, // out-of-turn, names[2] missed
+		{signer: names[0]}, // in-turn
+	}
+	api := newActivityTestChain(t, accounts, names, votes)
+
+	report, err := api.GetSignerActivity(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve signer activity: %v", err)
+	}
+	want := &ActivityReport{
+		From: 1,
+		To:   6,
+		Signers: map[common.Address]*SignerActivity{
+			accounts.address(names[0]): {InTurn: 1, Missed: 1, LastSealed: 6},
+			accounts.address(names[1]): {InTurn: 1, OutOfTurn: 2, Missed: 1, LastSealed: 5},
+			accounts.address(names[2]): {InTurn: 1, OutOfTurn: 1, Missed: 1, LastSealed: 4},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("activity mismatch: have %+v, want %+v", report, want)
+	}
+	// Check that the range is honoured
+	from, to := rpc.BlockNumber(4), rpc.BlockNumber(5)
+	if report, err = api.GetSignerActivity(&from, &to); err != nil {
+		t.Fatalf("failed to retrieve signer activity: %v", err)
+	}
+	if have := report.Signers[accounts.address(names[1])]; *have != (SignerActivity{OutOfTurn: 1, Missed: 1, LastSealed: 5}) {
+		t.Errorf("ranged activity mismatch: have %+v", have)
+	}
+	to = 7
+	if _, err := api.GetSignerActivity(&from, &to); err == nil {
+		t.Errorf("activity beyond head reported")
+	}
+	// Only the last signer is left with a missed slot since it last sealed
+	api.clique.UpdateActivity(api.chain)
+	for i, want := range []uint64{0, 0, 1} {
+		if have := api.clique.activity.missed[accounts.address(names[i])]; have != want {
+			t.Errorf("signer %d: missed slots mismatch: have %d, want %d", i, have, want)
+		}
+	}
+	// Rewind the chain and ensure the dropped blocks are not accounted for anymore
+	if err := api.chain.(*core.BlockChain).SetHead(3); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	api.clique.UpdateActivity(api.chain)
+	for i, want := range []uint64{1, 0, 0} {
+		if have := api.clique.activity.missed[accounts.address(names[i])]; have != want {
+			t.Errorf("signer %d: missed slots mismatch after rewind: have %d, want %d", i, have, want)
+		}
+	}
+}
+
+// Tests that the vote history reports the votes cast along with the evolution
+// of the tallies.
+func TestVoteHistory(t *testing.T) {
+	accounts := newTesterAccountPool()
+
+	votes := []testerVote{
+		{signer: "A", voted: "D", auth: true},
+		{signer: "B"},
+		{signer: "C", voted: "D", auth: true},
+		{signer: "A", voted: "B"},
+		{signer: "D", voted: "D", auth: true}, // D already authorized, not a vote
+	}
+	api := newActivityTestChain(t, accounts, []string{"A", "B", "C"}, votes)
+
+	history, err := api.GetVoteHistory(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve vote history: %v", err)
+	}
+	want := []*VoteEvent{
+		{Block: 1, Signer: accounts.address("A"), Address: accounts.address("D"), Authorize: true, Votes: 1},
+		{Block: 3, Signer: accounts.address("C"), Address: accounts.address("D"), Authorize: true, Votes: 2, Passed: true},
+		{Block: 4, Signer: accounts.address("A"), Address: accounts.address("B"), Votes: 1},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("vote history mismatch:\nhave %+v\nwant %+v", history, want)
+	}
+}
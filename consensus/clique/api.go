@@ -120,6 +120,28 @@ func (api *API) Discard(address common.Address) {
 	delete(api.clique.proposals, address)
 }
 
+// GetSignerActivity returns the number of blocks each signer sealed in-turn and
+// out-of-turn, along with the in-turn slots it missed, over the given range of
+// blocks (inclusive). Without bounds, the most recent blocks are reported.
+func (api *API) GetSignerActivity(from, to *rpc.BlockNumber) (*ActivityReport, error) {
+	first, last, err := api.replayRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.signerActivity(first, last)
+}
+
+// GetVoteHistory returns the votes cast over the given range of blocks
+// (inclusive) in chronological order, along with the tally of the proposals
+// after each vote. Without bounds, the most recent blocks are reported.
+func (api *API) GetVoteHistory(from, to *rpc.BlockNumber) ([]*VoteEvent, error) {
+	first, last, err := api.replayRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.voteHistory(first, last)
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
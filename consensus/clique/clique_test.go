@@ -18,6 +18,8 @@ package clique
 
 import (
 	"math/big"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -111,3 +113,150 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+// Tests that the scheduled forks of the clique parameters and signer set are
+// honoured when importing a chain.
+func TestCliqueForkSchedule(t *testing.T) {
+	type testerBlock struct {
+		signer     string
+		checkpoint []string
+		delay      uint64 // Seconds since the parent block, 10 if unset
+	}
+	period, epoch := uint64(15), uint64(2)
+
+	tests := []struct {
+		signers []string      // Initial signers of the genesis
+		fork    testerFork    // Scheduled fork at block 3
+		blocks  []testerBlock // Blocks to seal and import
+		results []string      // Final list of signers
+		failure error         // Failure of the last block, if any
+	}{
+		{
+			// Rotating the signer set hands the sealing over
+			signers: []string{"A"},
+			fork:    testerFork{signers: []string{"B", "C"}},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "B"}, {signer: "C"}, {signer: "B"}},
+			results: []string{"B", "C"},
+		}, {
+			// Old signers are rejected after the rotation
+			signers: []string{"A"},
+			fork:    testerFork{signers: []string{"B"}},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "A"}},
+			failure: errUnauthorizedSigner,
+		}, {
+			// New signers are rejected before the rotation
+			signers: []string{"A"},
+			fork:    testerFork{signers: []string{"B"}},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "B"}},
+			failure: errUnauthorizedSigner,
+		}, {
+			// Increased periods are enforced from the fork onwards
+			signers: []string{"A"},
+			fork:    testerFork{period: &period},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "A"}},
+			failure: errInvalidTimestamp,
+		}, {
+			signers: []string{"A"},
+			fork:    testerFork{period: &period},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "A", delay: 15}},
+			results: []string{"A"},
+		}, {
+			// Shortened epochs require checkpoints from the fork onwards
+			signers: []string{"A"},
+			fork:    testerFork{epoch: &epoch},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "A"}, {signer: "A"}},
+			failure: errMismatchingCheckpointSigners,
+		}, {
+			signers: []string{"A"},
+			fork:    testerFork{epoch: &epoch},
+			blocks:  []testerBlock{{signer: "A"}, {signer: "A"}, {signer: "A"}, {signer: "A", checkpoint: []string{"A"}}},
+			results: []string{"A"},
+		},
+	}
+	for i, tt := range tests {
+		accounts := newTesterAccountPool()
+
+		// Create the genesis block with the initial set of signers
+		genesis := &core.Genesis{
+			ExtraData: make([]byte, extraVanity+common.AddressLength*len(tt.signers)+extraSeal),
+		}
+		accounts.checkpoint(&types.Header{Extra: genesis.ExtraData}, tt.signers)
+
+		db := rawdb.NewMemoryDatabase()
+		genesis.Commit(db)
+
+		// Schedule the fork and assemble a chain of headers
+		fork := &params.CliqueFork{Block: 3, Period: tt.fork.period, Epoch: tt.fork.epoch}
+		for _, signer := range tt.fork.signers {
+			fork.Signers = append(fork.Signers, accounts.address(signer))
+		}
+		config := *params.TestChainConfig
+		config.Clique = &params.CliqueConfig{
+			Period: 1,
+			Epoch:  30000,
+			Forks:  []*params.CliqueFork{fork},
+		}
+		engine := New(config.Clique, db)
+		engine.fakeDiff = true
+
+		blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(tt.blocks), func(j int, gen *core.BlockGen) {})
+		for j, block := range blocks {
+			header := block.Header()
+			header.Time = genesis.Timestamp + 10
+			if j > 0 {
+				header.ParentHash = blocks[j-1].Hash()
+				header.Time = blocks[j-1].Time() + 10
+			}
+			if tt.blocks[j].delay != 0 {
+				header.Time += tt.blocks[j].delay - 10
+			}
+			header.Extra = make([]byte, extraVanity+extraSeal)
+			if auths := tt.blocks[j].checkpoint; auths != nil {
+				header.Extra = make([]byte, extraVanity+len(auths)*common.AddressLength+extraSeal)
+				accounts.checkpoint(header, auths)
+			}
+			header.Difficulty = diffInTurn // Ignored, we just need a valid number
+
+			accounts.sign(header, tt.blocks[j].signer)
+			blocks[j] = block.WithSeal(header)
+		}
+		// Import the headers and ensure the schedule is honoured
+		chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Errorf("test %d: failed to create test chain: %v", i, err)
+			continue
+		}
+		if k, err := chain.InsertChain(blocks[:len(blocks)-1]); err != nil {
+			t.Errorf("test %d: failed to import block %d: %v", i, k, err)
+			chain.Stop()
+			continue
+		}
+		if _, err := chain.InsertChain(blocks[len(blocks)-1:]); err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+		if tt.failure == nil {
+			head := blocks[len(blocks)-1]
+			snap, err := engine.snapshot(chain, head.NumberU64(), head.Hash(), nil)
+			if err != nil {
+				t.Errorf("test %d: failed to retrieve voting snapshot: %v", i, err)
+			} else {
+				want := make([]common.Address, len(tt.results))
+				for j, signer := range tt.results {
+					want[j] = accounts.address(signer)
+				}
+				sort.Sort(signersAscending(want))
+				if have := snap.signers(); !reflect.DeepEqual(have, want) {
+					t.Errorf("test %d: signers mismatch: have %x, want %x", i, have, want)
+				}
+			}
+		}
+		chain.Stop()
+	}
+}
+
+// testerFork is a scheduled change of the clique parameters or signers.
+type testerFork struct {
+	period  *uint64
+	epoch   *uint64
+	signers []string
+}
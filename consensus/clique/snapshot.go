@@ -206,7 +206,7 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	for i, header := range headers {
 		// Remove any votes on checkpoint blocks
 		number := header.Number.Uint64()
-		if number%s.config.Epoch == 0 {
+		if number%s.config.EpochAt(number) == 0 {
 			snap.Votes = nil
 			snap.Tally = make(map[common.Address]Tally)
 		}
@@ -291,6 +291,9 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 			}
 			delete(snap.Tally, header.Coinbase)
 		}
+		// Force the scheduled signer set, if any, onto the next block
+		snap.enforceSchedule(number + 1)
+
 		// If we're taking too much time (ecrecover), notify the user once a while
 		if time.Since(logged) > 8*time.Second {
 			log.Info("Reconstructing voting history", "processed", i, "total", len(headers), "elapsed", common.PrettyDuration(time.Since(start)))
@@ -306,6 +309,22 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	return snap, nil
 }
 
+// enforceSchedule replaces the signer set if the fork schedule forces a new one
+// from the given block onwards, discarding all votes and recent signers.
+func (s *Snapshot) enforceSchedule(number uint64) {
+	signers := s.config.SignersAt(number)
+	if signers == nil {
+		return
+	}
+	s.Signers = make(map[common.Address]struct{})
+	for _, signer := range signers {
+		s.Signers[signer] = struct{}{}
+	}
+	s.Recents = make(map[uint64]common.Address)
+	s.Votes = nil
+	s.Tally = make(map[common.Address]Tally)
+}
+
 // signers retrieves the list of authorized signers in ascending order.
 func (s *Snapshot) signers() []common.Address {
 	sigs := make([]common.Address, 0, len(s.Signers))
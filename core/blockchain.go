@@ -307,8 +307,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
-	// Ensure that a previous crash in SetHead doesn't leave extra ancients
-	if frozen, err := bc.db.Ancients(); err == nil && frozen > 0 {
+	// Ensure that a previous crash in SetHead doesn't leave extra ancients. Items
+	// in an ancient store shared with other nodes are never extra, they might be
+	// ahead of the local chain if another node froze them.
+	if frozen, err := bc.db.Ancients(); err == nil && frozen > 0 && !rawdb.IsAncientShared(bc.db) {
 		var (
 			needRewind bool
 			low        uint64
@@ -371,7 +373,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			log.Warn("Enabling snapshot recovery", "chainhead", head.NumberU64(), "diskbase", *layer)
 			recover = true
 		}
-		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, head.Root(), !bc.cacheConfig.SnapshotWait, recover)
+		bc.snaps, _ = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, head.Root(), !bc.cacheConfig.SnapshotWait, true, recover)
 	}
 	// Take ownership of this particular state
 	go bc.update()
@@ -476,8 +478,12 @@ func (bc *BlockChain) loadLastState() error {
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
 func (bc *BlockChain) SetHead(head uint64) error {
-	_, err := bc.SetHeadBeyondRoot(head, common.Hash{})
-	return err
+	if _, err := bc.SetHeadBeyondRoot(head, common.Hash{}); err != nil {
+		return err
+	}
+	// Send chain head event to update the transaction pool
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: bc.CurrentBlock()})
+	return nil
 }
 
 // SetHeadBeyondRoot rewinds the local chain to a new head with the extra condition
@@ -500,6 +506,14 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 	pivot := rawdb.ReadLastPivotNumber(bc.db)
 	frozen, _ := bc.db.Ancients()
 
+	// Frozen items shared with other nodes can't be discarded, refuse rewinding
+	// the header chain into them. The head block may still end up below them if
+	// it's missing its state.
+	shared := rawdb.IsAncientShared(bc.db)
+	if shared && head+1 < frozen {
+		return 0, fmt.Errorf("%w: can't rewind below frozen block #%d to #%d", rawdb.ErrAncientAppendOnly, frozen-1, head)
+	}
+
 	updateFn := func(db ethdb.KeyValueWriter, header *types.Header) (uint64, bool) {
 		// Rewind the block chain, ensuring we don't end up with a stateless head
 		// block. Note, depth equality is permitted to allow using SetHead as a
@@ -569,7 +583,7 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 		// intent afterwards is full block importing, delete the chain segment
 		// between the stateful-block and the sethead target.
 		var wipe bool
-		if head+1 < frozen {
+		if head+1 < frozen && !shared {
 			wipe = pivot == nil || head >= *pivot
 		}
 		return head, wipe // Only force wipe if full synced
@@ -17,10 +17,12 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"net/http/httptest"
 	"os"
 	"sync"
 	"testing"
@@ -36,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -3029,3 +3032,119 @@ func TestInitThenFailCreateContract(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a node rejoining a remote ancient store shared with a node further
+// ahead doesn't rewind its chain or truncate the store on startup, and that none
+// of the nodes can rewind their chains into the shared store.
+func TestSharedAncientStoreRejoin(t *testing.T) {
+	// Serve a shared ancient store over RPC
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := rawdb.NewFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer store.Close()
+
+	api, err := rawdb.NewAncientStoreAPI(store, dir)
+	if err != nil {
+		t.Fatalf("failed to create freezer API: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("freezer", api); err != nil {
+		t.Fatalf("failed to register freezer API: %v", err)
+	}
+	endpoint := httptest.NewServer(server)
+	defer endpoint.Close()
+
+	// Generate a chain and import it fully into the node claiming the store
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 64, nil)
+
+	writerdb, err := rawdb.NewDatabaseWithRemoteFreezer(rawdb.NewMemoryDatabase(), endpoint.URL)
+	if err != nil {
+		t.Fatalf("failed to create writer database: %v", err)
+	}
+	defer writerdb.Close()
+	gspec.MustCommit(writerdb)
+
+	writer, _ := NewBlockChain(writerdb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer writer.Stop()
+	if n, err := writer.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	writerdb.(interface{ Freeze(threshold uint64) }).Freeze(10)
+
+	frozen, _ := store.Ancients()
+	if frozen != 55 {
+		t.Fatalf("ancient count mismatch: have %d, want %d", frozen, 55)
+	}
+	// Attach a second node with its headers, but not blocks ahead of the store
+	readerdb, err := rawdb.NewDatabaseWithRemoteFreezer(rawdb.NewMemoryDatabase(), endpoint.URL)
+	if err != nil {
+		t.Fatalf("failed to create reader database: %v", err)
+	}
+	defer readerdb.Close()
+	gspec.MustCommit(readerdb)
+
+	reader, _ := NewBlockChain(readerdb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := reader.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := reader.InsertChain(blocks[:20]); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	reader.Stop()
+
+	// Restart the node behind a few times, ensuring it keeps its chain
+	for i := 0; i < 2; i++ {
+		reader, _ = NewBlockChain(readerdb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if head := reader.CurrentBlock().NumberU64(); head != 20 {
+			t.Fatalf("restart %d: head block mismatch: have %d, want %d", i, head, 20)
+		}
+		if head := reader.CurrentHeader().Number.Uint64(); head != 64 {
+			t.Fatalf("restart %d: head header mismatch: have %d, want %d", i, head, 64)
+		}
+		if have, _ := store.Ancients(); have != frozen {
+			t.Fatalf("restart %d: shared ancient store truncated: have %d items, want %d", i, have, frozen)
+		}
+		reader.Stop()
+	}
+	reader, _ = NewBlockChain(readerdb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer reader.Stop()
+
+	// Ensure neither node can rewind into the shared store, but above it
+	for _, chain := range []*BlockChain{writer, reader} {
+		if err := chain.SetHead(frozen - 2); !errors.Is(err, rawdb.ErrAncientAppendOnly) {
+			t.Fatalf("rewind error mismatch: have %v, want %v", err, rawdb.ErrAncientAppendOnly)
+		}
+	}
+	if head := writer.CurrentBlock().NumberU64(); head != 64 {
+		t.Fatalf("writer head mismatch after rejected rewind: have %d, want %d", head, 64)
+	}
+	if err := writer.SetHead(frozen - 1); err != nil {
+		t.Fatalf("failed to rewind to the shared store: %v", err)
+	}
+	if head := writer.CurrentHeader().Number.Uint64(); head != frozen-1 {
+		t.Fatalf("writer head header mismatch: have %d, want %d", head, frozen-1)
+	}
+	if have, _ := store.Ancients(); have != frozen {
+		t.Fatalf("shared ancient store truncated: have %d items, want %d", have, frozen)
+	}
+	if block := writer.GetBlockByNumber(frozen - 1); block == nil || block.Hash() != blocks[frozen-2].Hash() {
+		t.Fatalf("frozen block unavailable to the writer")
+	}
+}
@@ -24,6 +24,10 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxDropEvent is posted when transactions are dropped from the transaction pool
+// without being included in the chain.
+type TxDropEvent struct{ Drops []*TxDrop }
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
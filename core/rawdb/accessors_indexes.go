@@ -155,6 +155,21 @@ func WriteBloomBits(db ethdb.KeyValueWriter, bit uint, section uint64, head comm
 	}
 }
 
+// ReadLogIndex retrieves the compressed bit vector of the blocks within the given
+// section containing logs of the given address or topic, nil if none do.
+func ReadLogIndex(db ethdb.KeyValueReader, term []byte, section uint64, head common.Hash) []byte {
+	data, _ := db.Get(logIndexKey(term, section, head))
+	return data
+}
+
+// WriteLogIndex stores the compressed bit vector of the blocks within the given
+// section containing logs of the given address or topic.
+func WriteLogIndex(db ethdb.KeyValueWriter, term []byte, section uint64, head common.Hash, bits []byte) {
+	if err := db.Put(logIndexKey(term, section, head), bits); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
 // DeleteBloombits removes all compressed bloom bits vector belonging to the
 // given section range and bit index.
 func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
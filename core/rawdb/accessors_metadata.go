@@ -79,3 +79,18 @@ func WriteChainConfig(db ethdb.KeyValueWriter, hash common.Hash, cfg *params.Cha
 		log.Crit("Failed to store chain config", "err", err)
 	}
 }
+
+// ReadRemoteFreezerID retrieves the identifier the node uses towards a shared
+// ancient store.
+func ReadRemoteFreezerID(db ethdb.KeyValueReader) string {
+	data, _ := db.Get(remoteFreezerIDKey)
+	return string(data)
+}
+
+// WriteRemoteFreezerID stores the identifier the node uses towards a shared
+// ancient store.
+func WriteRemoteFreezerID(db ethdb.KeyValueWriter, id string) {
+	if err := db.Put(remoteFreezerIDKey, []byte(id)); err != nil {
+		log.Crit("Failed to store remote freezer identifier", "err", err)
+	}
+}
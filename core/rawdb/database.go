@@ -18,9 +18,13 @@ package rawdb
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync/atomic"
 	"time"
 
@@ -29,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -60,13 +65,13 @@ func (frdb *freezerdb) Close() error {
 func (frdb *freezerdb) Freeze(threshold uint64) {
 	// Set the freezer threshold to a temporary value
 	defer func(old uint64) {
-		atomic.StoreUint64(&frdb.AncientStore.(*freezer).threshold, old)
-	}(atomic.LoadUint64(&frdb.AncientStore.(*freezer).threshold))
-	atomic.StoreUint64(&frdb.AncientStore.(*freezer).threshold, threshold)
+		atomic.StoreUint64(&frdb.AncientStore.(*chainFreezer).threshold, old)
+	}(atomic.LoadUint64(&frdb.AncientStore.(*chainFreezer).threshold))
+	atomic.StoreUint64(&frdb.AncientStore.(*chainFreezer).threshold, threshold)
 
 	// Trigger a freeze cycle and block until it's done
 	trigger := make(chan struct{}, 1)
-	frdb.AncientStore.(*freezer).trigger <- trigger
+	frdb.AncientStore.(*chainFreezer).trigger <- trigger
 	<-trigger
 }
 
@@ -127,6 +132,49 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace st
 	if err != nil {
 		return nil, err
 	}
+	return newDatabaseWithAncientStore(db, frdb, true)
+}
+
+// NewDatabaseWithRemoteFreezer creates a high level database on top of a given
+// key-value data store with a remote freezer, served over RPC by a separate
+// process. If the node is the single writer of the remote freezer, immutable
+// chain segments are moved into it, otherwise it's only read from.
+func NewDatabaseWithRemoteFreezer(db ethdb.KeyValueStore, endpoint string) (ethdb.Database, error) {
+	// Identify the node towards the shared store, reclaiming the writer role on restarts
+	id := ReadRemoteFreezerID(db)
+	if id == "" {
+		blob := make([]byte, 16)
+		if _, err := rand.Read(blob); err != nil {
+			return nil, err
+		}
+		id = hex.EncodeToString(blob)
+		WriteRemoteFreezerID(db, id)
+	}
+	frdb, err := newRemoteFreezer(endpoint, id)
+	if err != nil {
+		return nil, err
+	}
+	if !frdb.writer {
+		log.Warn("Ancient store claimed by another node, not freezing chain segments")
+	}
+	chaindb, err := newDatabaseWithAncientStore(db, frdb, frdb.writer)
+	if err != nil {
+		frdb.Close()
+		return nil, err
+	}
+	return chaindb, nil
+}
+
+// NewFreezer opens the flat file ancient store in the given directory, without
+// attaching it to any key-value database (e.g. to serve it to remote nodes).
+func NewFreezer(datadir string, namespace string) (ethdb.AncientStore, error) {
+	return newFreezer(datadir, namespace)
+}
+
+// newDatabaseWithAncientStore combines a key-value data store with an ancient
+// store after ensuring the two are compatible, and if requested, starts moving
+// immutable chain segments into the latter.
+func newDatabaseWithAncientStore(db ethdb.KeyValueStore, frdb ethdb.AncientStore, freeze bool) (ethdb.Database, error) {
 	// Since the freezer can be stored separately from the user's key-value database,
 	// there's a fairly high probability that the user requests invalid combinations
 	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
@@ -192,11 +240,14 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace st
 		}
 	}
 	// Freezer is consistent with the key-value database, permit combining the two
-	go frdb.freeze(db)
-
+	chain := newChainFreezer(frdb)
+	if freeze {
+		chain.wg.Add(1)
+		go chain.freeze(db)
+	}
 	return &freezerdb{
 		KeyValueStore: db,
-		AncientStore:  frdb,
+		AncientStore:  chain,
 	}, nil
 }
 
@@ -238,6 +289,109 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+// NewPebbleDBDatabase creates a persistent key-value database without a freezer
+// moving immutable chain segments into cold storage.
+func NewPebbleDBDatabase(file string, cache int, handles int, namespace string) (ethdb.Database, error) {
+	db, err := newPebbleDB(file, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(db), nil
+}
+
+// NewPebbleDBDatabaseWithFreezer creates a persistent key-value database with a
+// freezer moving immutable chain segments into cold storage.
+func NewPebbleDBDatabaseWithFreezer(file string, cache int, handles int, freezer string, namespace string) (ethdb.Database, error) {
+	kvdb, err := newPebbleDB(file, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, freezer, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
+const (
+	// DBLeveldb is the database engine identifier of the LevelDB backend.
+	DBLeveldb = "leveldb"
+
+	// DBPebble is the database engine identifier of the Pebble backend.
+	DBPebble = "pebble"
+)
+
+// PreexistingDatabase checks the given data directory whether a database is
+// already instantiated at that location, and if so, returns the type of the
+// database (or the empty string).
+func PreexistingDatabase(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		return "" // No pre-existing db
+	}
+	// Pebble, unlike LevelDB, always persists its configuration into an options
+	// file next to the manifest.
+	if matches, _ := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 {
+		return DBPebble
+	}
+	return DBLeveldb
+}
+
+// OpenOptions contains the parameters needed to open a persistent chain database.
+type OpenOptions struct {
+	Type      string // Database engine to use, autodetected or LevelDB if empty
+	Directory string // Directory of the key-value store
+	Freezer   string // Directory or RPC endpoint of the ancient store, none if empty
+	Namespace string // Namespace for metrics reporting
+	Cache     int    // Memory allowance in megabytes
+	Handles   int    // Number of file handles
+}
+
+// Open opens a persistent chain database with the configured engine. If the
+// directory already contains a database, its engine is used and any conflicting
+// explicit choice is rejected. New databases default to LevelDB.
+func Open(o OpenOptions) (ethdb.Database, error) {
+	existing := PreexistingDatabase(o.Directory)
+	if o.Type != "" && existing != "" && o.Type != existing {
+		return nil, fmt.Errorf("db.engine choice was %v but found pre-existing %v database in specified data directory", o.Type, existing)
+	}
+	engine := o.Type
+	if engine == "" {
+		engine = existing
+	}
+	var (
+		kvdb ethdb.KeyValueStore
+		err  error
+	)
+	switch engine {
+	case "", DBLeveldb:
+		log.Info("Using leveldb as the backing database")
+		kvdb, err = leveldb.New(o.Directory, o.Cache, o.Handles, o.Namespace)
+	case DBPebble:
+		log.Info("Using pebble as the backing database")
+		kvdb, err = newPebbleDB(o.Directory, o.Cache, o.Handles, o.Namespace)
+	default:
+		return nil, fmt.Errorf("unknown db.engine %v", engine)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Freezer == "" {
+		return NewDatabase(kvdb), nil
+	}
+	var db ethdb.Database
+	if IsRemoteFreezer(o.Freezer) {
+		db, err = NewDatabaseWithRemoteFreezer(kvdb, o.Freezer)
+	} else {
+		db, err = NewDatabaseWithFreezer(kvdb, o.Freezer, o.Namespace)
+	}
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -269,9 +423,11 @@ func (s *stat) Count() string {
 }
 
 // InspectDatabase traverses the entire database and checks the size
-// of all different categories of data.
-func InspectDatabase(db ethdb.Database) error {
-	it := db.NewIterator(nil, nil)
+// of all different categories of data. The traversal of the key-value
+// store can be limited to the keys with the given prefix, starting at
+// the given position.
+func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
+	it := db.NewIterator(keyPrefix, keyStart)
 	defer it.Release()
 
 	var (
@@ -293,6 +449,7 @@ func InspectDatabase(db ethdb.Database) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
 		cliqueSnaps     stat
 
 		// Ancient store statistics
@@ -347,6 +504,8 @@ func InspectDatabase(db ethdb.Database) error {
 			preimages.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && (len(key) == len(logIndexPrefix)+common.AddressLength+8+common.HashLength || len(key) == len(logIndexPrefix)+common.HashLength+8+common.HashLength):
+			logIndex.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
@@ -355,7 +514,7 @@ func InspectDatabase(db ethdb.Database) error {
 			bloomTrieNodes.Add(size)
 		default:
 			var accounted bool
-			for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey} {
+			for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey, remoteFreezerIDKey} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
 					accounted = true
@@ -395,6 +554,7 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -422,3 +582,27 @@ func InspectDatabase(db ethdb.Database) error {
 
 	return nil
 }
+
+// InspectFreezerTable dumps out the index of a specific freezer table. The passed
+// ancient indicates the path of root ancient directory where the chain freezer can
+// be opened. Start and end specify the range for dumping out indexes.
+// Note this function can only be used for debugging purposes.
+func InspectFreezerTable(ancient string, tableName string, start, end int64) error {
+	noSnappy, exist := freezerNoSnappy[tableName]
+	if !exist {
+		var names []string
+		for name := range freezerNoSnappy {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown table, supported ones: %v", names)
+	}
+	table, err := newTable(ancient, tableName, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, noSnappy)
+	if err != nil {
+		return err
+	}
+	defer table.Close()
+
+	table.dumpIndex(os.Stdout, start, end)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build arm64 amd64
+
+package rawdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that the database engine of an existing data directory is detected and
+// that an explicit, conflicting engine choice is rejected.
+func TestOpenEngineDetection(t *testing.T) {
+	for _, engine := range []string{DBLeveldb, DBPebble} {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "chaindata")
+		if have := PreexistingDatabase(path); have != "" {
+			t.Fatalf("%s: fresh directory detected as %q", engine, have)
+		}
+		db, err := Open(OpenOptions{Type: engine, Directory: path})
+		if err != nil {
+			t.Fatalf("%s: failed to create database: %v", engine, err)
+		}
+		if err := db.Put([]byte("key"), []byte("value")); err != nil {
+			t.Fatalf("%s: failed to write database: %v", engine, err)
+		}
+		db.Close()
+
+		if have := PreexistingDatabase(path); have != engine {
+			t.Fatalf("detected engine mismatch: have %q, want %q", have, engine)
+		}
+		// Reopening without an explicit choice should pick up the existing data
+		db, err = Open(OpenOptions{Directory: path})
+		if err != nil {
+			t.Fatalf("%s: failed to reopen database: %v", engine, err)
+		}
+		if val, err := db.Get([]byte("key")); err != nil || string(val) != "value" {
+			t.Fatalf("%s: value mismatch: have %q (%v), want %q", engine, val, err, "value")
+		}
+		db.Close()
+
+		// Reopening with the other engine should fail
+		other := DBPebble
+		if engine == DBPebble {
+			other = DBLeveldb
+		}
+		if db, err := Open(OpenOptions{Type: other, Directory: path}); err == nil {
+			db.Close()
+			t.Fatalf("%s: opened with conflicting engine %s", engine, other)
+		}
+	}
+}
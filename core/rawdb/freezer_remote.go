@@ -0,0 +1,275 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// ErrAncientAppendOnly is returned if data is attempted to be truncated from a
+	// shared ancient store. The frozen chain segments are immutable and other nodes
+	// might depend on them.
+	ErrAncientAppendOnly = errors.New("shared ancient store is append-only")
+
+	// errNotAncientWriter is returned if a node other than the single writer of a
+	// shared ancient store attempts to append data to it.
+	errNotAncientWriter = errors.New("not the writer of the shared ancient store")
+)
+
+// ancientWriterFile is the name of the file in the directory of a served ancient
+// store which persists the identifier of its single writer across restarts.
+const ancientWriterFile = "WRITER"
+
+// IsRemoteFreezer reports whether the given ancient store location refers to the
+// RPC endpoint of a remote freezer instead of a local directory. Remote freezers
+// are reached over http(s) or ws(s) URLs, or over an existing IPC socket.
+func IsRemoteFreezer(location string) bool {
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if strings.HasPrefix(location, scheme) {
+			return true
+		}
+	}
+	if info, err := os.Stat(location); err == nil && info.Mode()&os.ModeSocket != 0 {
+		return true
+	}
+	return false
+}
+
+// IsAncientShared reports whether the ancient store of the database is a remote
+// freezer shared with other nodes, whose frozen items can't be truncated.
+func IsAncientShared(db ethdb.Database) bool {
+	frdb, ok := db.(*freezerdb)
+	if !ok {
+		return false
+	}
+	chain, ok := frdb.AncientStore.(*chainFreezer)
+	if !ok {
+		return false
+	}
+	_, ok = chain.AncientStore.(*remoteFreezer)
+	return ok
+}
+
+// remoteFreezer is an ancient store forwarding all operations over RPC to a
+// freezer served by a separate process (see AncientStoreAPI). This permits
+// multiple nodes on the same host to share a single copy of the immutable chain
+// segments.
+//
+// Only one of the nodes, the first one to claim the store, is permitted to append
+// to it. All other nodes use it read only. Data can't be truncated by any of them.
+type remoteFreezer struct {
+	client *rpc.Client
+	id     string // Identifier of the node towards the shared store
+	writer bool   // Whether this node is the single writer of the store
+}
+
+// newRemoteFreezer connects to the remote freezer at the given RPC endpoint,
+// attempting to claim it for the node with the given identifier.
+func newRemoteFreezer(endpoint string, id string) (*remoteFreezer, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	f, err := attachRemoteFreezer(client, id)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	log.Info("Connected to remote ancient database", "endpoint", endpoint, "writer", f.writer)
+	return f, nil
+}
+
+// attachRemoteFreezer claims the remote freezer behind an established connection
+// for the node with the given identifier.
+func attachRemoteFreezer(client *rpc.Client, id string) (*remoteFreezer, error) {
+	var writer bool
+	if err := client.Call(&writer, "freezer_claimWriter", id); err != nil {
+		return nil, err
+	}
+	return &remoteFreezer{client: client, id: id, writer: writer}, nil
+}
+
+// Close terminates the connection to the remote freezer. The remote freezer
+// itself is left running.
+func (f *remoteFreezer) Close() error {
+	f.client.Close()
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the remote freezer.
+func (f *remoteFreezer) HasAncient(kind string, number uint64) (bool, error) {
+	var res bool
+	err := f.client.Call(&res, "freezer_hasAncient", kind, hexutil.Uint64(number))
+	return res, err
+}
+
+// Ancient retrieves an ancient binary blob from the remote freezer.
+func (f *remoteFreezer) Ancient(kind string, number uint64) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := f.client.Call(&res, "freezer_ancient", kind, hexutil.Uint64(number)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Ancients returns the length of the frozen items.
+func (f *remoteFreezer) Ancients() (uint64, error) {
+	var res hexutil.Uint64
+	err := f.client.Call(&res, "freezer_ancients")
+	return uint64(res), err
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (f *remoteFreezer) AncientSize(kind string) (uint64, error) {
+	var res hexutil.Uint64
+	err := f.client.Call(&res, "freezer_ancientSize", kind)
+	return uint64(res), err
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// remote append-only immutable table files.
+func (f *remoteFreezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	if !f.writer {
+		return errNotAncientWriter
+	}
+	return f.client.Call(nil, "freezer_appendAncient", f.id, hexutil.Uint64(number), hexutil.Bytes(hash), hexutil.Bytes(header), hexutil.Bytes(body), hexutil.Bytes(receipts), hexutil.Bytes(td))
+}
+
+// TruncateAncients is a noop if the remote freezer doesn't contain more than the
+// requested number of items, otherwise an error as shared data can't be discarded.
+func (f *remoteFreezer) TruncateAncients(items uint64) error {
+	frozen, err := f.Ancients()
+	if err != nil {
+		return err
+	}
+	if items < frozen {
+		return ErrAncientAppendOnly
+	}
+	return nil
+}
+
+// Sync flushes all data tables of the remote freezer to disk.
+func (f *remoteFreezer) Sync() error {
+	return f.client.Call(nil, "freezer_sync")
+}
+
+// AncientStoreAPI exposes an ancient store over RPC for remote freezer clients.
+// It is meant to be registered under the "freezer" namespace.
+//
+// The first client claiming the store becomes its single writer, all others may
+// only read from it. The writer is persisted next to the store, so it keeps its
+// role across restarts of the server. Truncating the store is not exposed at all.
+type AncientStoreAPI struct {
+	store      ethdb.AncientStore
+	writer     string     // Identifier of the client permitted to append to the store
+	writerFile string     // File persisting the writer identifier
+	lock       sync.Mutex // Serializes modifications from concurrent clients
+}
+
+// NewAncientStoreAPI creates an RPC API serving the given ancient store, located
+// in the given directory. If a writer was already assigned when the store was
+// previously served, it is loaded from there.
+func NewAncientStoreAPI(store ethdb.AncientStore, datadir string) (*AncientStoreAPI, error) {
+	api := &AncientStoreAPI{
+		store:      store,
+		writerFile: filepath.Join(datadir, ancientWriterFile),
+	}
+	blob, err := ioutil.ReadFile(api.writerFile)
+	switch {
+	case err == nil:
+		api.writer = strings.TrimSpace(string(blob))
+		log.Info("Loaded ancient store writer", "id", api.writer)
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to load ancient store writer: %v", err)
+	}
+	return api, nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (api *AncientStoreAPI) HasAncient(kind string, number hexutil.Uint64) (bool, error) {
+	return api.store.HasAncient(kind, uint64(number))
+}
+
+// Ancient retrieves an ancient binary blob from the store.
+func (api *AncientStoreAPI) Ancient(kind string, number hexutil.Uint64) (hexutil.Bytes, error) {
+	return api.store.Ancient(kind, uint64(number))
+}
+
+// Ancients returns the length of the frozen items.
+func (api *AncientStoreAPI) Ancients() (hexutil.Uint64, error) {
+	n, err := api.store.Ancients()
+	return hexutil.Uint64(n), err
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (api *AncientStoreAPI) AncientSize(kind string) (hexutil.Uint64, error) {
+	n, err := api.store.AncientSize(kind)
+	return hexutil.Uint64(n), err
+}
+
+// ClaimWriter attempts to make the client with the given identifier the writer of
+// the store, returning whether it is (or already was) the writer.
+func (api *AncientStoreAPI) ClaimWriter(id string) (bool, error) {
+	if id == "" {
+		return false, errors.New("empty client identifier")
+	}
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if api.writer == "" {
+		if err := ioutil.WriteFile(api.writerFile, []byte(id), 0644); err != nil {
+			return false, fmt.Errorf("failed to persist ancient store writer: %v", err)
+		}
+		log.Info("Assigned ancient store writer", "id", id)
+		api.writer = id
+	}
+	return api.writer == id, nil
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// append-only immutable table files. Only the writer of the store is permitted
+// to do so.
+func (api *AncientStoreAPI) AppendAncient(id string, number hexutil.Uint64, hash, header, body, receipts, td hexutil.Bytes) error {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	if id != api.writer {
+		return errNotAncientWriter
+	}
+	return api.store.AppendAncient(uint64(number), hash, header, body, receipts, td)
+}
+
+// Sync flushes all data tables to disk.
+func (api *AncientStoreAPI) Sync() error {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	return api.store.Sync()
+}
@@ -0,0 +1,254 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that a freezer served over RPC can be appended to and read from through
+// the remote client, but not truncated.
+func TestRemoteFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := newFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer store.Close()
+
+	server := newAncientStoreServer(t, store, dir)
+	defer server.Stop()
+	remote, err := attachRemoteFreezer(rpc.DialInProc(server), "writer")
+	if err != nil {
+		t.Fatalf("failed to attach remote freezer: %v", err)
+	}
+	defer remote.Close()
+	if !remote.writer {
+		t.Fatalf("first client not assigned the writer")
+	}
+
+	// Append a few items and ensure they are retrievable
+	for i := byte(0); i < 3; i++ {
+		blob := []byte{i}
+		if err := remote.AppendAncient(uint64(i), bytes.Repeat(blob, 32), blob, blob, blob, blob); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := remote.AppendAncient(5, make([]byte, 32), nil, nil, nil, nil); err == nil {
+		t.Fatalf("out of order append succeeded")
+	}
+	if err := remote.Sync(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if frozen, err := remote.Ancients(); err != nil || frozen != 3 {
+		t.Fatalf("ancient count mismatch: have %d (%v), want %d", frozen, err, 3)
+	}
+	if blob, err := remote.Ancient(freezerHeaderTable, 1); err != nil || !bytes.Equal(blob, []byte{1}) {
+		t.Fatalf("ancient header mismatch: have %x (%v), want %x", blob, err, []byte{1})
+	}
+	if ok, err := remote.HasAncient(freezerBodiesTable, 2); err != nil || !ok {
+		t.Fatalf("ancient body #2 missing: %v", err)
+	}
+	if size, err := remote.AncientSize(freezerHashTable); err != nil || size == 0 {
+		t.Fatalf("ancient size mismatch: have %d (%v)", size, err)
+	}
+	if _, err := remote.Ancient("unknown", 0); err == nil {
+		t.Fatalf("retrieved item from unknown table")
+	}
+	// Ensure frozen items can't be discarded, only truncations without effect pass
+	if err := remote.TruncateAncients(1); err != ErrAncientAppendOnly {
+		t.Fatalf("truncation error mismatch: have %v, want %v", err, ErrAncientAppendOnly)
+	}
+	if err := remote.TruncateAncients(3); err != nil {
+		t.Fatalf("failed to truncate beyond the frozen items: %v", err)
+	}
+	if err := remote.client.Call(nil, "freezer_truncateAncients", hexutil.Uint64(1)); err == nil {
+		t.Fatalf("truncation served over RPC")
+	}
+	if frozen, _ := store.Ancients(); frozen != 3 {
+		t.Fatalf("local ancient count mismatch: have %d, want %d", frozen, 3)
+	}
+}
+
+// newAncientStoreServer creates an RPC server serving the given ancient store,
+// located in the given directory.
+func newAncientStoreServer(t *testing.T, store ethdb.AncientStore, dir string) *rpc.Server {
+	t.Helper()
+
+	api, err := NewAncientStoreAPI(store, dir)
+	if err != nil {
+		t.Fatalf("failed to create freezer API: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("freezer", api); err != nil {
+		t.Fatalf("failed to register freezer API: %v", err)
+	}
+	return server
+}
+
+// writeTestChain writes the given blocks into the key-value store as the
+// canonical chain, along with empty receipts and total difficulties.
+func writeTestChain(db ethdb.KeyValueWriter, blocks []*types.Block) {
+	for _, block := range blocks {
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+		WriteTd(db, block.Hash(), block.NumberU64(), block.Number())
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	head := blocks[len(blocks)-1].Hash()
+	WriteHeadHeaderHash(db, head)
+	WriteHeadBlockHash(db, head)
+}
+
+// Tests that when multiple nodes at different heads share a remote freezer, only
+// the first one to claim it moves chain segments into it, and none of them can
+// discard frozen data. The writer must keep its role across server restarts.
+func TestRemoteFreezerSharedWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := newFreezer(dir, "")
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	defer store.Close()
+
+	server := newAncientStoreServer(t, store, dir)
+	defer server.Stop()
+	open := func(kvdb ethdb.KeyValueStore, id string) *freezerdb {
+		remote, err := attachRemoteFreezer(rpc.DialInProc(server), id)
+		if err != nil {
+			t.Fatalf("failed to attach remote freezer: %v", err)
+		}
+		db, err := newDatabaseWithAncientStore(kvdb, remote, remote.writer)
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		return db.(*freezerdb)
+	}
+	// Create a chain and two nodes at different heads of it
+	blocks := make([]*types.Block, 10)
+	for i := range blocks {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test")}
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		blocks[i] = types.NewBlockWithHeader(header)
+	}
+	writerdb, readerdb := memorydb.New(), memorydb.New()
+	writeTestChain(writerdb, blocks)
+	writeTestChain(readerdb, blocks[:6])
+
+	// Freeze the chain of the node ahead, ensuring it's moved out of its database
+	writer := open(writerdb, "writer")
+	defer writer.Close()
+
+	writer.Freeze(2)
+	if frozen, _ := store.Ancients(); frozen != 8 {
+		t.Fatalf("ancient count mismatch: have %d, want %d", frozen, 8)
+	}
+	if blob, _ := writerdb.Get(headerHashKey(0)); len(blob) == 0 {
+		t.Fatalf("genesis deleted from writer database")
+	}
+	for i := uint64(1); i < 8; i++ {
+		if blob, _ := writerdb.Get(headerHashKey(i)); len(blob) != 0 {
+			t.Fatalf("frozen block #%d still in writer database", i)
+		}
+	}
+	// Attach the node behind, ensuring it can read, but not modify the shared data
+	reader := open(readerdb, "reader")
+	defer reader.Close()
+
+	if reader.AncientStore.(*chainFreezer).AncientStore.(*remoteFreezer).writer {
+		t.Fatalf("second client assigned the writer")
+	}
+	if hash := ReadCanonicalHash(reader, 7); hash != blocks[7].Hash() {
+		t.Fatalf("shared canonical hash mismatch: have %x, want %x", hash, blocks[7].Hash())
+	}
+	if err := reader.AppendAncient(8, blocks[8].Hash().Bytes(), nil, nil, nil, nil); err != errNotAncientWriter {
+		t.Fatalf("append error mismatch: have %v, want %v", err, errNotAncientWriter)
+	}
+	client := reader.AncientStore.(*chainFreezer).AncientStore.(*remoteFreezer).client
+	if err := client.Call(nil, "freezer_appendAncient", "reader", hexutil.Uint64(8), hexutil.Bytes(blocks[8].Hash().Bytes()), hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}); err == nil {
+		t.Fatalf("append from reader accepted")
+	}
+	if err := reader.TruncateAncients(6); err != ErrAncientAppendOnly {
+		t.Fatalf("truncation error mismatch: have %v, want %v", err, ErrAncientAppendOnly)
+	}
+	if frozen, _ := store.Ancients(); frozen != 8 {
+		t.Fatalf("ancient count mismatch after reader attached: have %d, want %d", frozen, 8)
+	}
+	for i := uint64(1); i < 6; i++ {
+		if blob, _ := readerdb.Get(headerHashKey(i)); len(blob) == 0 {
+			t.Fatalf("block #%d missing from reader database", i)
+		}
+	}
+	// Ensure the writer reclaims its role when reconnecting
+	remote, err := attachRemoteFreezer(rpc.DialInProc(server), "writer")
+	if err != nil {
+		t.Fatalf("failed to reattach remote freezer: %v", err)
+	}
+	defer remote.Close()
+	if !remote.writer {
+		t.Fatalf("writer role not reclaimed")
+	}
+	// Restart the server and ensure the reader can't take over the writer role,
+	// even if it reconnects first
+	restarted := newAncientStoreServer(t, store, dir)
+	defer restarted.Stop()
+
+	remote, err = attachRemoteFreezer(rpc.DialInProc(restarted), "reader")
+	if err != nil {
+		t.Fatalf("failed to reattach remote freezer: %v", err)
+	}
+	defer remote.Close()
+	if remote.writer {
+		t.Fatalf("reader assigned the writer after restart")
+	}
+	if err := remote.client.Call(nil, "freezer_appendAncient", "reader", hexutil.Uint64(8), hexutil.Bytes(blocks[8].Hash().Bytes()), hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}); err == nil {
+		t.Fatalf("append from reader accepted after restart")
+	}
+	remote, err = attachRemoteFreezer(rpc.DialInProc(restarted), "writer")
+	if err != nil {
+		t.Fatalf("failed to reattach remote freezer: %v", err)
+	}
+	defer remote.Close()
+	if !remote.writer {
+		t.Fatalf("writer role not retained after restart")
+	}
+	if err := remote.AppendAncient(8, blocks[8].Hash().Bytes(), nil, nil, nil, nil); err != nil {
+		t.Fatalf("failed to append as the writer after restart: %v", err)
+	}
+}
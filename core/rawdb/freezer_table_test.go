@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -525,7 +526,7 @@ func TestOffset(t *testing.T) {
 
 		f.Append(4, getChunk(20, 0xbb))
 		f.Append(5, getChunk(20, 0xaa))
-		f.printIndex()
+		f.dumpIndex(os.Stdout, 0, 100)
 		f.Close()
 	}
 	// Now crop it.
@@ -572,7 +573,7 @@ func TestOffset(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		f.printIndex()
+		f.dumpIndex(os.Stdout, 0, 100)
 		// It should allow writing item 6
 		f.Append(numDeleted+2, getChunk(20, 0x99))
 
@@ -640,3 +641,38 @@ func TestOffset(t *testing.T) {
 // However, all 'normal' failure modes arising due to failing to sync() or save a file should be
 // handled already, and the case described above can only (?) happen if an external process/user
 // deletes files from the filesystem.
+
+// TestFreezerDumpIndex tests that the index dump only contains the requested
+// range of items.
+func TestFreezerDumpIndex(t *testing.T) {
+	t.Parallel()
+	f, err := newCustomTable(os.TempDir(),
+		fmt.Sprintf("unittest-%d", rand.Uint64()),
+		metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge(), 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Write 15 bytes 10 times, three items per data file
+	for x := 0; x < 10; x++ {
+		f.Append(uint64(x), getChunk(15, x))
+	}
+	var buf bytes.Buffer
+	f.dumpIndex(&buf, 4, 6)
+
+	want := "| number | fileno | offset |\n" +
+		"|--------|--------|--------|\n" +
+		"|  004   |  001   |  030   | \n" +
+		"|  005   |  001   |  045   | \n" +
+		"|--------------------------|\n"
+	if have := buf.String(); have != want {
+		t.Fatalf("index dump mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+	// Dumping without an upper bound should stop at the last item
+	buf.Reset()
+	f.dumpIndex(&buf, 8, 0)
+	if lines := strings.Count(buf.String(), "\n"); lines != 5 {
+		t.Fatalf("unexpected number of dumped lines: have %d, want %d", lines, 5)
+	}
+}
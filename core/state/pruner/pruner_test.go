@@ -0,0 +1,173 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestChain creates a chain of blocks with a state snapshot on top, each
+// block mutating the state a bit. All the intermediate states are persisted.
+func makeTestChain(t *testing.T, n int) (ethdb.Database, []*types.Block) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	config := &core.CacheConfig{
+		TrieCleanLimit:    16,
+		TrieDirtyLimit:    16,
+		TrieDirtyDisabled: true,
+		SnapshotLimit:     16,
+		SnapshotWait:      true,
+	}
+	chain, err := core.NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop() // Journal the snapshot layers
+	return db, blocks
+}
+
+// newTestPruner creates a pruner with a small bloom filter, targeting the state
+// of the given head block.
+func newTestPruner(t *testing.T, db ethdb.Database, head *types.Block, datadir string) *Pruner {
+	snaptree, err := snapshot.New(db, trie.NewDatabase(db), 16, head.Root(), false, false, false)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	bloom, err := newStateBloomWithSize(1)
+	if err != nil {
+		t.Fatalf("failed to create state bloom: %v", err)
+	}
+	return &Pruner{
+		db:            db,
+		stateBloom:    bloom,
+		datadir:       datadir,
+		trieCachePath: filepath.Join(datadir, "triecache"),
+		headHeader:    head.Header(),
+		snaptree:      snaptree,
+	}
+}
+
+// checkPrunedState verifies that only the target and the genesis states are
+// retained in the database after pruning.
+func checkPrunedState(t *testing.T, db ethdb.Database, blocks []*types.Block, datadir string) {
+	t.Helper()
+
+	target := blocks[len(blocks)-1].Root()
+	for _, root := range []common.Hash{target, rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, 0), 0).Root()} {
+		tr, err := trie.NewSecure(root, trie.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("retained state %x missing: %v", root, err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("retained state %x incomplete: %v", root, err)
+		}
+	}
+	for _, block := range blocks[:len(blocks)-1] {
+		if rawdb.HasTrieNode(db, block.Root()) {
+			t.Errorf("stale state root %x of block #%d not pruned", block.Root(), block.NumberU64())
+		}
+	}
+	if path, _, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom filter not deleted: %s", path)
+	}
+}
+
+// Tests that pruning deletes all the stale states but keeps the target and the
+// genesis ones intact.
+func TestPruneState(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db, blocks := makeTestChain(t, 10)
+	head := blocks[len(blocks)-1]
+
+	if err := newTestPruner(t, db, head, datadir).Prune(head.Root()); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	checkPrunedState(t, db, blocks, datadir)
+}
+
+// Tests that an interrupted pruning, after the state bloom filter is committed,
+// is resumed and finished by the recovery procedure.
+func TestRecoverPruning(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	db, blocks := makeTestChain(t, 10)
+	head := blocks[len(blocks)-1]
+
+	// Run the pruning until the bloom filter is committed, then "crash"
+	pruner := newTestPruner(t, db, head, datadir)
+	if err := snapshot.GenerateTrie(pruner.snaptree, head.Root(), db, pruner.stateBloom); err != nil {
+		t.Fatalf("failed to generate state: %v", err)
+	}
+	if err := extractGenesis(db, pruner.stateBloom); err != nil {
+		t.Fatalf("failed to extract genesis: %v", err)
+	}
+	name := bloomFilterName(datadir, head.Root())
+	if err := pruner.stateBloom.Commit(name, name+stateBloomFileTempSuffix); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	if err := RecoverPruning(datadir, db, pruner.trieCachePath); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	checkPrunedState(t, db, blocks, datadir)
+}
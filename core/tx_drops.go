@@ -0,0 +1,154 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxDropReason is the reason a transaction was dropped from the pool.
+type TxDropReason uint8
+
+const (
+	TxDropUnderpriced    TxDropReason = iota + 1 // Pushed out by better paying transactions, or below the price threshold
+	TxDropReplaced                               // Replaced by a transaction with the same nonce paying more
+	TxDropEvicted                                // Evicted to enforce the account or global slot limits
+	TxDropExpired                                // Queued for longer than the configured lifetime
+	TxDropNonceTooLow                            // Nonce used up by another transaction of the account
+	TxDropUnpayable                              // Insufficient funds or above the block gas limit
+	TxDropReorg                                  // Invalidated by a chain reorganisation
+	TxDropPrivateExpired                         // Private transaction not included before its expiry block
+)
+
+// String implements the stringer interface.
+func (r TxDropReason) String() string {
+	switch r {
+	case TxDropUnderpriced:
+		return "underpriced"
+	case TxDropReplaced:
+		return "replaced"
+	case TxDropEvicted:
+		return "evicted"
+	case TxDropExpired:
+		return "expired"
+	case TxDropNonceTooLow:
+		return "nonce too low"
+	case TxDropUnpayable:
+		return "unpayable"
+	case TxDropReorg:
+		return "reorg"
+	case TxDropPrivateExpired:
+		return "private expired"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r TxDropReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// TxDrop records the removal of a transaction from the pool for any reason
+// other than its inclusion in the chain.
+type TxDrop struct {
+	Hash        common.Hash
+	From        common.Address
+	Nonce       uint64
+	Reason      TxDropReason
+	Replacement common.Hash // Transaction taking the place of the dropped one, if replaced
+	Time        time.Time
+}
+
+// txDropJournal remembers the most recent transaction drops of the pool, and
+// delivers them to the subscribers outside of the pool lock.
+type txDropJournal struct {
+	limit int // Maximum number of drops remembered
+
+	lock    sync.RWMutex
+	drops   map[common.Hash]*TxDrop // Remembered drops by transaction hash
+	ring    []*TxDrop               // Remembered drops in insertion order, for eviction
+	next    int                     // Position in the ring of the next drop
+	pending []*TxDrop               // Drops not yet delivered to the subscribers
+
+	wake chan struct{}
+	feed event.Feed
+}
+
+// newTxDropJournal creates a journal remembering at most limit drops.
+func newTxDropJournal(limit int) *txDropJournal {
+	return &txDropJournal{
+		limit: limit,
+		drops: make(map[common.Hash]*TxDrop),
+		ring:  make([]*TxDrop, limit),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// add records a drop, evicting the oldest one if the journal is full.
+func (j *txDropJournal) add(drop *TxDrop) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.limit > 0 {
+		// A transaction may be dropped, readded and dropped again, only forget
+		// the reason if it wasn't superseded since
+		if old := j.ring[j.next]; old != nil && j.drops[old.Hash] == old {
+			delete(j.drops, old.Hash)
+		}
+		j.ring[j.next] = drop
+		j.next = (j.next + 1) % j.limit
+		j.drops[drop.Hash] = drop
+	}
+	j.pending = append(j.pending, drop)
+
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// get returns the last recorded drop of a transaction, or nil if unknown.
+func (j *txDropJournal) get(hash common.Hash) *TxDrop {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	return j.drops[hash]
+}
+
+// loop delivers the recorded drops to the subscribers until quit is closed.
+func (j *txDropJournal) loop(quit chan struct{}) {
+	for {
+		select {
+		case <-j.wake:
+			j.lock.Lock()
+			drops := j.pending
+			j.pending = nil
+			j.lock.Unlock()
+
+			if len(drops) > 0 {
+				j.feed.Send(TxDropEvent{drops})
+			}
+		case <-quit:
+			return
+		}
+	}
+}
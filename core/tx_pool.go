@@ -79,6 +79,14 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxExpired is returned if a private transaction is submitted with an
+	// expiry block already reached by the chain.
+	ErrTxExpired = errors.New("transaction expiry block reached")
+
+	// ErrPrivateNoLocals is returned if a private transaction is submitted to a
+	// pool with local transaction handling disabled.
+	ErrPrivateNoLocals = errors.New("private transactions require local transaction handling")
 )
 
 var (
@@ -148,6 +156,8 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	DropHistory uint64 // Number of recently dropped transactions to remember the drop reasons of
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -165,6 +175,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	DropHistory: 4096,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -224,13 +236,20 @@ type TxPool struct {
 	mu          sync.RWMutex
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
+	currentNumber uint64         // Number of the current head block
+
+	locals  *accountSet            // Set of local transaction to exempt from eviction rules
+	journal *txJournal             // Journal of local transaction to back up to disk
+	drops   *txDropJournal         // Recently dropped transactions and their drop reasons
+	private map[common.Hash]uint64 // Transactions not to be propagated, with their expiry blocks (0 for none)
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	headTxs     map[common.Hash]struct{} // Transactions included by the running reset, nil if unknown
+	headReorged bool                     // Whether the running reset is a chain reorganisation
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -245,7 +264,7 @@ type TxPool struct {
 	queueTxEventCh  chan *types.Transaction
 	reorgDoneCh     chan chan struct{}
 	reorgShutdownCh chan struct{}  // requests shutdown of scheduleReorgLoop
-	wg              sync.WaitGroup // tracks loop, scheduleReorgLoop
+	wg              sync.WaitGroup // tracks loop, scheduleReorgLoop, drop delivery
 }
 
 type txpoolResetRequest struct {
@@ -263,7 +282,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:          config,
 		chainconfig:     chainconfig,
 		chain:           chain,
-		signer:          types.NewEIP155Signer(chainconfig.ChainID),
+		signer:          types.LatestSigner(chainconfig),
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
@@ -275,6 +294,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		reorgDoneCh:     make(chan chan struct{}),
 		reorgShutdownCh: make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		drops:           newTxDropJournal(int(config.DropHistory)),
+		private:         make(map[common.Hash]uint64),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -288,6 +309,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	pool.wg.Add(1)
 	go pool.scheduleReorgLoop()
 
+	pool.wg.Add(1)
+	go func() {
+		defer pool.wg.Done()
+		pool.drops.loop(pool.reorgShutdownCh)
+	}()
+
 	// If local transactions and journaling is enabled, load from disk
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
@@ -365,6 +392,7 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.dropped(tx, TxDropExpired, common.Hash{})
 						pool.removeTx(tx.Hash(), true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
@@ -406,6 +434,18 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxDropEvent registers a subscription of TxDropEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeTxDropEvent(ch chan<- TxDropEvent) event.Subscription {
+	return pool.scope.Track(pool.drops.feed.Subscribe(ch))
+}
+
+// DropReason returns the last recorded drop of a transaction, or nil if the
+// transaction wasn't dropped recently.
+func (pool *TxPool) DropReason(hash common.Hash) *TxDrop {
+	return pool.drops.get(hash)
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -422,6 +462,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
+		pool.dropped(tx, TxDropUnderpriced, common.Hash{})
 		pool.removeTx(tx.Hash(), false)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -501,22 +542,43 @@ func (pool *TxPool) Locals() []common.Address {
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
+//
+// Private transactions are omitted, they must not outlive the node as ordinary
+// local transactions.
 func (pool *TxPool) local() map[common.Address]types.Transactions {
 	txs := make(map[common.Address]types.Transactions)
 	for addr := range pool.locals.accounts {
 		if pending := pool.pending[addr]; pending != nil {
-			txs[addr] = append(txs[addr], pending.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(pending.Flatten())...)
 		}
 		if queued := pool.queue[addr]; queued != nil {
-			txs[addr] = append(txs[addr], queued.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(queued.Flatten())...)
 		}
 	}
 	return txs
 }
 
+// public filters the private transactions out of a transaction list.
+func (pool *TxPool) public(txs types.Transactions) types.Transactions {
+	if len(pool.private) == 0 {
+		return txs
+	}
+	filtered := txs[:0]
+	for _, tx := range txs {
+		if _, ok := pool.private[tx.Hash()]; !ok {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Accept only legacy transactions until EIP-2718/2930 activates.
+	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -550,7 +612,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInsufficientFunds
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true, pool.istanbul)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
 	if err != nil {
 		return err
 	}
@@ -594,6 +656,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxMeter.Mark(1)
+			pool.dropped(tx, TxDropUnderpriced, hash)
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
@@ -611,6 +674,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.dropped(old, TxDropReplaced, hash)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
@@ -663,6 +727,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.dropped(old, TxDropReplaced, hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -685,6 +750,9 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	if _, ok := pool.private[tx.Hash()]; ok {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -707,6 +775,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.dropped(tx, TxDropReplaced, list.txs.Get(tx.Nonce()).Hash())
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -714,6 +783,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.dropped(old, TxDropReplaced, hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -747,6 +817,54 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// AddPrivate enqueues a single local transaction into the pool if it is valid,
+// marking it as private: it is never propagated to the network, only included
+// in locally mined blocks. If expiry is non-zero, the transaction is dropped
+// once the chain reaches the expiry block without including it.
+//
+// Private transactions are always local, they are rejected if local transaction
+// handling is disabled: as remotes they could be evicted without ever having
+// been announced.
+func (pool *TxPool) AddPrivate(tx *types.Transaction, expiry uint64) error {
+	if pool.config.NoLocals {
+		return ErrPrivateNoLocals
+	}
+	hash := tx.Hash()
+	if pool.all.Get(hash) != nil {
+		knownTxMeter.Mark(1)
+		return ErrAlreadyKnown
+	}
+	if _, err := types.Sender(pool.signer, tx); err != nil {
+		invalidTxMeter.Mark(1)
+		return ErrInvalidSender
+	}
+	pool.mu.Lock()
+	if expiry != 0 && expiry <= pool.currentNumber {
+		pool.mu.Unlock()
+		return ErrTxExpired
+	}
+	// Mark the transaction before adding it, so it's never announced
+	pool.private[hash] = expiry
+	errs, dirtyAddrs := pool.addTxsLocked([]*types.Transaction{tx}, true)
+	if errs[0] != nil {
+		delete(pool.private, hash)
+	}
+	pool.mu.Unlock()
+
+	<-pool.requestPromoteExecutables(dirtyAddrs)
+	return errs[0]
+}
+
+// IsPrivate reports whether a pooled transaction was submitted privately, and
+// must not be propagated to the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
 // AddRemotes enqueues a batch of transactions into the pool if they are valid. If the
 // senders are not among the locally tracked ones, full pricing constraints will apply.
 //
@@ -877,6 +995,60 @@ func (pool *TxPool) Has(hash common.Hash) bool {
 	return pool.all.Get(hash) != nil
 }
 
+// dropped records the removal of a transaction from the pool for the given reason.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) dropped(tx *types.Transaction, reason TxDropReason, replacement common.Hash) {
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	pool.drops.add(&TxDrop{
+		Hash:        tx.Hash(),
+		From:        from,
+		Nonce:       tx.Nonce(),
+		Reason:      reason,
+		Replacement: replacement,
+		Time:        time.Now(),
+	})
+}
+
+// droppedStale records the removal of a transaction whose nonce is below the
+// account nonce, unless it was included in the chain by the running reset. If
+// the included transactions are unknown, nothing is recorded.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) droppedStale(tx *types.Transaction) {
+	if pool.headTxs == nil {
+		return
+	}
+	if _, ok := pool.headTxs[tx.Hash()]; ok {
+		return
+	}
+	if pool.headReorged {
+		pool.dropped(tx, TxDropReorg, common.Hash{})
+	} else {
+		pool.dropped(tx, TxDropNonceTooLow, common.Hash{})
+	}
+}
+
+// droppedUnpayable records the removal of a transaction which can't be paid for
+// by its sender, or exceeds the block gas limit.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) droppedUnpayable(tx *types.Transaction) {
+	if pool.headReorged {
+		pool.dropped(tx, TxDropReorg, common.Hash{})
+	} else {
+		pool.dropped(tx, TxDropUnpayable, common.Hash{})
+	}
+}
+
+// setHeadTxs sets the transactions included in the chain by the running reset.
+func (pool *TxPool) setHeadTxs(txs types.Transactions) {
+	pool.headTxs = make(map[common.Hash]struct{}, len(txs))
+	for _, tx := range txs {
+		pool.headTxs[tx.Hash()] = struct{}{}
+	}
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -1065,6 +1237,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		pool.expirePrivate()
 	}
 	// Ensure pool.queue and pool.pending sizes stay within the configured limits.
 	pool.truncatePending()
@@ -1075,6 +1248,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		highestPending := list.LastElement()
 		pool.pendingNonces.set(addr, highestPending.Nonce()+1)
 	}
+	pool.headTxs, pool.headReorged = nil, false
 	pool.mu.Unlock()
 
 	// Notify subsystems for newly added transactions
@@ -1100,6 +1274,13 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
 	var reinject types.Transactions
 
+	if oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		// Plain chain extension, remember the included transactions to tell them
+		// apart from the ones dropped due to their nonce being used up
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.setHeadTxs(block.Transactions())
+		}
+	}
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number.Uint64()
@@ -1157,6 +1338,9 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				}
 			}
 			reinject = types.TxDifference(discarded, included)
+
+			pool.setHeadTxs(included)
+			pool.headReorged = rem.Hash() != oldHead.Hash()
 		}
 	}
 	// Initialize the internal state to the current head
@@ -1171,6 +1355,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.currentNumber = newHead.Number.Uint64()
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -1180,6 +1365,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.eip2718 = pool.chainconfig.IsYoloV2(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1200,6 +1386,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.droppedStale(tx)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1207,6 +1394,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.droppedUnpayable(tx)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1229,6 +1417,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.dropped(tx, TxDropEvicted, common.Hash{})
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1248,6 +1437,24 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 	return promoted
 }
 
+// expirePrivate drops the private transactions whose expiry block was reached,
+// and forgets about the ones which left the pool.
+func (pool *TxPool) expirePrivate() {
+	for hash, expiry := range pool.private {
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			delete(pool.private, hash)
+			continue
+		}
+		if expiry != 0 && expiry <= pool.currentNumber {
+			log.Trace("Removed expired private transaction", "hash", hash, "expiry", expiry)
+			pool.dropped(tx, TxDropPrivateExpired, common.Hash{})
+			pool.removeTx(hash, true)
+			delete(pool.private, hash)
+		}
+	}
+}
+
 // truncatePending removes transactions from the pending queue if the pool is above the
 // pending limit. The algorithm tries to reduce transaction counts by an approximately
 // equal number for all for accounts with many pending transactions.
@@ -1291,6 +1498,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.dropped(tx, TxDropEvicted, common.Hash{})
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1318,6 +1526,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.dropped(tx, TxDropEvicted, common.Hash{})
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1364,6 +1573,7 @@ func (pool *TxPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.dropped(tx, TxDropEvicted, common.Hash{})
 				pool.removeTx(tx.Hash(), true)
 			}
 			drop -= size
@@ -1373,6 +1583,7 @@ func (pool *TxPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.dropped(txs[i], TxDropEvicted, common.Hash{})
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1393,6 +1604,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.droppedStale(tx)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1401,6 +1613,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.droppedUnpayable(tx)
 		}
 		pool.priced.Removed(len(olds) + len(drops))
 		pendingNofundsMeter.Mark(int64(len(drops)))
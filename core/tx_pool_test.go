@@ -269,6 +269,57 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that typed transactions are only accepted into the pool once the chain
+// config enables them, and that their access list is charged as intrinsic gas.
+func TestTransactionTypedAdmission(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	newPool := func(config *params.ChainConfig) *TxPool {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.AddBalance(from, big.NewInt(1000000000))
+		blockchain := &testBlockChain{statedb, 10000000, new(event.Feed)}
+		return NewTxPool(testTxPoolConfig, config, blockchain)
+	}
+	accessList := types.AccessList{{Address: common.Address{1}, StorageKeys: []common.Hash{{1}}}}
+	sign := func(config *params.ChainConfig, gas uint64) *types.Transaction {
+		return types.MustSignNewTx(key, types.NewEIP2930Signer(config.ChainID), &types.AccessListTx{
+			ChainID:    config.ChainID,
+			Nonce:      0,
+			To:         &common.Address{},
+			Gas:        gas,
+			GasPrice:   big.NewInt(1),
+			AccessList: accessList,
+		})
+	}
+	// Before the fork, typed transactions must be rejected
+	preFork := *params.TestChainConfig
+	preFork.YoloV2Block = big.NewInt(100)
+
+	pool := newPool(&preFork)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(sign(&preFork, 100000)); err != ErrTxTypeNotSupported {
+		t.Errorf("pre-fork typed transaction: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// After the fork, they must be accepted if they pay for their access list
+	postFork := *params.TestChainConfig
+	postFork.YoloV2Block = big.NewInt(0)
+
+	pool = newPool(&postFork)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(sign(&postFork, params.TxGas)); !errors.Is(err, ErrIntrinsicGas) {
+		t.Errorf("underpaid access list: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	gas := params.TxGas + params.TxAccessListAddressGas + params.TxAccessListStorageKeyGas
+	if err := pool.AddRemote(sign(&postFork, gas)); err != nil {
+		t.Errorf("post-fork typed transaction rejected: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1923,6 +1974,239 @@ func TestTransactionSlotCount(t *testing.T) {
 	}
 }
 
+// Tests that transactions leaving the pool without being included are recorded
+// along with the reason of their removal, and announced to subscribers.
+func TestTransactionDropReasons(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	drops := make(chan TxDropEvent, 16)
+	sub := pool.SubscribeTxDropEvent(drops)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+	pool.mu.Unlock()
+
+	// newHead moves the pool onto a new empty block with the given account state
+	newHead := func(nonce uint64, balance *big.Int) {
+		pool.mu.Lock()
+		pool.currentState.SetNonce(from, nonce)
+		pool.currentState.SetBalance(from, balance)
+		pool.mu.Unlock()
+
+		parent := pool.chain.CurrentBlock().Header()
+		<-pool.requestReset(parent, &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: parent.GasLimit})
+	}
+	checkDrop := func(tx *types.Transaction, reason TxDropReason, replacement common.Hash) {
+		t.Helper()
+
+		drop := pool.DropReason(tx.Hash())
+		if drop == nil {
+			t.Fatalf("drop of transaction %d not recorded", tx.Nonce())
+		}
+		if drop.Reason != reason || drop.From != from || drop.Nonce != tx.Nonce() || drop.Replacement != replacement {
+			t.Fatalf("drop mismatch: have %v from %x nonce %d replacement %x, want %v from %x nonce %d replacement %x",
+				drop.Reason, drop.From, drop.Nonce, drop.Replacement, reason, from, tx.Nonce(), replacement)
+		}
+	}
+	// Replace a pending transaction, and drop the replacement with the price threshold
+	tx0 := pricedTransaction(0, 100000, big.NewInt(1), key)
+	tx0b := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(tx0b); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	checkDrop(tx0, TxDropReplaced, tx0b.Hash())
+
+	pool.SetGasPrice(big.NewInt(3))
+	checkDrop(tx0b, TxDropUnderpriced, common.Hash{})
+
+	// Use up the nonce of a pending transaction, and run the account dry
+	tx0c := pricedTransaction(0, 100000, big.NewInt(3), key)
+	tx1 := pricedTransaction(1, 100000, big.NewInt(3), key)
+	if errs := pool.AddRemotesSync([]*types.Transaction{tx0c, tx1}); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	newHead(1, big.NewInt(1000000000))
+	checkDrop(tx0c, TxDropNonceTooLow, common.Hash{})
+
+	newHead(1, new(big.Int))
+	checkDrop(tx1, TxDropUnpayable, common.Hash{})
+
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Fatalf("pool not empty: %d pending, %d queued", pending, queued)
+	}
+	// Ensure all the drops were announced, in order
+	want := []common.Hash{tx0.Hash(), tx0b.Hash(), tx0c.Hash(), tx1.Hash()}
+	var have []common.Hash
+	for len(have) < len(want) {
+		select {
+		case ev := <-drops:
+			for _, drop := range ev.Drops {
+				have = append(have, drop.Hash)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop #%d not announced", len(have))
+		}
+	}
+	for i := range want {
+		if i >= len(have) || have[i] != want[i] {
+			t.Fatalf("announced drops mismatch: have %x, want %x", have, want)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that queued transactions over the account limit are recorded as evicted.
+func TestTransactionDropEviction(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountQueue = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{transaction(2, 100000, key), transaction(3, 100000, key)}
+	pool.AddRemotesSync(txs)
+
+	if drop := pool.DropReason(txs[0].Hash()); drop != nil {
+		t.Fatalf("kept transaction recorded as dropped: %v", drop.Reason)
+	}
+	if drop := pool.DropReason(txs[1].Hash()); drop == nil || drop.Reason != TxDropEvicted {
+		t.Fatalf("evicted transaction drop mismatch: have %v, want %v", drop, TxDropEvicted)
+	}
+}
+
+// Tests that private transactions are kept out of the local journal, and are
+// dropped once their expiry block is reached.
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000000))
+	pool.mu.Unlock()
+
+	// newHead moves the pool onto a new empty block with the given number
+	newHead := func(number int64) {
+		parent := pool.chain.CurrentBlock().Header()
+		<-pool.requestReset(parent, &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(number), GasLimit: parent.GasLimit})
+	}
+	expiring := transaction(0, 100000, key)
+	lasting := transaction(1, 100000, key)
+	if err := pool.AddPrivate(expiring, 2); err != nil {
+		t.Fatalf("failed to add expiring private transaction: %v", err)
+	}
+	if err := pool.AddPrivate(lasting, 0); err != nil {
+		t.Fatalf("failed to add lasting private transaction: %v", err)
+	}
+	if err := pool.AddPrivate(expiring, 2); err != ErrAlreadyKnown {
+		t.Fatalf("duplicate private transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if !pool.IsPrivate(expiring.Hash()) || !pool.IsPrivate(lasting.Hash()) {
+		t.Fatalf("private transactions not marked")
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	pool.mu.Lock()
+	local := pool.local()[from]
+	pool.mu.Unlock()
+	if len(local) != 0 {
+		t.Fatalf("private transactions journaled: %d", len(local))
+	}
+	// Cross the expiry block, ensuring only the expiring transaction is dropped
+	newHead(1)
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched before expiry: have %d, want %d", pending, 2)
+	}
+	if err := pool.AddPrivate(transaction(2, 100000, key), 1); err != ErrTxExpired {
+		t.Fatalf("expired private transaction error mismatch: have %v, want %v", err, ErrTxExpired)
+	}
+	newHead(2)
+	if pool.Get(expiring.Hash()) != nil {
+		t.Fatalf("expired private transaction not dropped")
+	}
+	if drop := pool.DropReason(expiring.Hash()); drop == nil || drop.Reason != TxDropPrivateExpired {
+		t.Fatalf("expired private transaction drop mismatch: have %v, want %v", drop, TxDropPrivateExpired)
+	}
+	if pool.IsPrivate(expiring.Hash()) || !pool.IsPrivate(lasting.Hash()) {
+		t.Fatalf("private transaction markers mismatch after expiry")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions are rejected if local transaction handling is
+// disabled, instead of being pooled as remotes.
+func TestTransactionPrivateNoLocals(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	tx := transaction(0, 100000, key)
+	if err := pool.AddPrivate(tx, 0); err != ErrPrivateNoLocals {
+		t.Fatalf("private transaction error mismatch: have %v, want %v", err, ErrPrivateNoLocals)
+	}
+	if pool.Get(tx.Hash()) != nil || pool.IsPrivate(tx.Hash()) {
+		t.Fatalf("rejected private transaction pooled")
+	}
+}
+
+// Tests that the drop journal only remembers the most recent drops.
+func TestTransactionDropJournalLimit(t *testing.T) {
+	journal := newTxDropJournal(2)
+
+	var (
+		first  = &TxDrop{Hash: common.Hash{1}, Reason: TxDropReplaced}
+		second = &TxDrop{Hash: common.Hash{2}, Reason: TxDropEvicted}
+		again  = &TxDrop{Hash: common.Hash{1}, Reason: TxDropUnderpriced}
+		third  = &TxDrop{Hash: common.Hash{3}, Reason: TxDropExpired}
+	)
+	journal.add(first)
+	journal.add(second)
+	journal.add(again) // Evicts the first, superseded record of the same transaction
+	if drop := journal.get(first.Hash); drop != again {
+		t.Fatalf("redropped transaction mismatch: have %v, want %v", drop, again)
+	}
+	journal.add(third)
+	if drop := journal.get(second.Hash); drop != nil {
+		t.Fatalf("oldest drop not forgotten: %v", drop)
+	}
+	for _, drop := range []*TxDrop{again, third} {
+		if have := journal.get(drop.Hash); have != drop {
+			t.Fatalf("recent drop %x mismatch: have %v, want %v", drop.Hash, have, drop)
+		}
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
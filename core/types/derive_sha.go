@@ -17,13 +17,24 @@
 package types
 
 import (
+	"bytes"
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// encodeBufferPool holds temporary encoder buffers for DeriveSha and TX encoding.
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// DerivableList is the input to DeriveSha.
+// It is implemented by the 'Transactions' and 'Receipts' types.
+// This is internal, do not use these methods.
 type DerivableList interface {
 	Len() int
-	GetRlp(i int) []byte
+	EncodeIndex(int, *bytes.Buffer)
 }
 
 // Hasher is the tool used to calculate the hash of derivable list.
@@ -33,9 +44,22 @@ type Hasher interface {
 	Hash() common.Hash
 }
 
+func encodeForDerive(list DerivableList, i int, buf *bytes.Buffer) []byte {
+	buf.Reset()
+	list.EncodeIndex(i, buf)
+	// It's really unfortunate that we need to do perform this copy.
+	// StackTrie holds onto the values until Hash is called, so the values
+	// written to it must not alias.
+	return common.CopyBytes(buf.Bytes())
+}
+
+// DeriveSha creates the tree hashes of transactions and receipts in a block header.
 func DeriveSha(list DerivableList, hasher Hasher) common.Hash {
 	hasher.Reset()
 
+	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer encodeBufferPool.Put(valueBuf)
+
 	// StackTrie requires values to be inserted in increasing
 	// hash order, which is not the order that `list` provides
 	// hashes in. This insertion sequence ensures that the
@@ -44,15 +68,15 @@ func DeriveSha(list DerivableList, hasher Hasher) common.Hash {
 	var buf []byte
 	for i := 1; i < list.Len() && i <= 0x7f; i++ {
 		buf = rlp.AppendUint64(buf[:0], uint64(i))
-		hasher.Update(buf, list.GetRlp(i))
+		hasher.Update(buf, encodeForDerive(list, i, valueBuf))
 	}
 	if list.Len() > 0 {
 		buf = rlp.AppendUint64(buf[:0], 0)
-		hasher.Update(buf, list.GetRlp(0))
+		hasher.Update(buf, encodeForDerive(list, 0, valueBuf))
 	}
 	for i := 0x80; i < list.Len(); i++ {
 		buf = rlp.AppendUint64(buf[:0], uint64(i))
-		hasher.Update(buf, list.GetRlp(i))
+		hasher.Update(buf, encodeForDerive(list, i, valueBuf))
 	}
 	return hasher.Hash()
 }
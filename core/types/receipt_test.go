@@ -29,6 +29,49 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+var to3 = common.HexToAddress("0x3")
+
+// Tests that typed receipts are encoded as the type byte followed by the RLP
+// payload and that they decode back to the same content.
+func TestTypedReceiptEncodingDecoding(t *testing.T) {
+	receipt := &Receipt{
+		Type:              AccessListTxType,
+		Status:            ReceiptStatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs:              []*Log{{Address: common.BytesToAddress([]byte{0x11}), Data: []byte{0x01}}},
+	}
+	receipt.Bloom = CreateBloom(Receipts{receipt})
+
+	enc, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+	// The envelope is wrapped into an RLP byte string
+	var inner []byte
+	if err := rlp.DecodeBytes(enc, &inner); err != nil {
+		t.Fatalf("typed receipt is not an RLP string: %v", err)
+	}
+	if inner[0] != AccessListTxType {
+		t.Fatalf("wrong type prefix: have %d, want %d", inner[0], AccessListTxType)
+	}
+	dec := new(Receipt)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+	if dec.Type != receipt.Type || dec.Status != receipt.Status || dec.CumulativeGasUsed != receipt.CumulativeGasUsed || dec.Bloom != receipt.Bloom {
+		t.Errorf("decoded receipt mismatch: have %+v, want %+v", dec, receipt)
+	}
+	if len(dec.Logs) != 1 || dec.Logs[0].Address != receipt.Logs[0].Address || !bytes.Equal(dec.Logs[0].Data, receipt.Logs[0].Data) {
+		t.Errorf("decoded receipt logs mismatch: have %v, want %v", dec.Logs, receipt.Logs)
+	}
+	// Unknown receipt types must be rejected
+	inner[0] = 0x7f
+	bad, _ := rlp.EncodeToBytes(inner)
+	if err := rlp.DecodeBytes(bad, new(Receipt)); err != ErrTxTypeNotSupported {
+		t.Errorf("unexpected error for unknown receipt type: %v", err)
+	}
+}
+
 func TestLegacyReceiptDecoding(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -157,6 +200,13 @@ func TestDeriveFields(t *testing.T) {
 	txs := Transactions{
 		NewContractCreation(1, big.NewInt(1), 1, big.NewInt(1), nil),
 		NewTransaction(2, common.HexToAddress("0x2"), big.NewInt(2), 2, big.NewInt(2), nil),
+		NewTx(&AccessListTx{
+			Nonce:    3,
+			To:       &to3,
+			Value:    big.NewInt(3),
+			Gas:      3,
+			GasPrice: big.NewInt(3),
+		}),
 	}
 	// Create the corresponding receipts
 	receipts := Receipts{
@@ -182,6 +232,18 @@ func TestDeriveFields(t *testing.T) {
 			ContractAddress: common.BytesToAddress([]byte{0x02, 0x22, 0x22}),
 			GasUsed:         2,
 		},
+		&Receipt{
+			Type:              AccessListTxType,
+			PostState:         common.Hash{3}.Bytes(),
+			CumulativeGasUsed: 6,
+			Logs: []*Log{
+				{Address: common.BytesToAddress([]byte{0x33})},
+				{Address: common.BytesToAddress([]byte{0x03, 0x33})},
+			},
+			TxHash:          txs[2].Hash(),
+			ContractAddress: common.BytesToAddress([]byte{0x03, 0x33, 0x33}),
+			GasUsed:         3,
+		},
 	}
 	// Clear all the computed fields and re-derive them
 	number := big.NewInt(1)
@@ -196,6 +258,9 @@ func TestDeriveFields(t *testing.T) {
 
 	logIndex := uint(0)
 	for i := range receipts {
+		if receipts[i].Type != txs[i].Type() {
+			t.Errorf("receipts[%d].Type = %d, want %d", i, receipts[i].Type, txs[i].Type())
+		}
 		if receipts[i].TxHash != txs[i].Hash() {
 			t.Errorf("receipts[%d].TxHash = %s, want %s", i, receipts[i].TxHash.String(), txs[i].Hash().String())
 		}
@@ -254,6 +319,7 @@ func clearComputedFieldsOnReceipts(t *testing.T, receipts Receipts) {
 func clearComputedFieldsOnReceipt(t *testing.T, receipt *Receipt) {
 	t.Helper()
 
+	receipt.Type = 0x0
 	receipt.TxHash = common.Hash{}
 	receipt.BlockHash = common.Hash{}
 	receipt.BlockNumber = big.NewInt(math.MaxUint32)
@@ -50,6 +50,18 @@ var (
 		HomesteadSigner{},
 		common.Hex2Bytes("98ff921201554726367d2be8c804a7ff89ccf285ebc57dff8ae4c44b9c19ac4a8887321be575c8095f789dd4c743dfe42c1820f9231f98a962b210e3ac2452a301"),
 	)
+
+	emptyEip2718Tx = NewTx(&AccessListTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    3,
+		To:       &testAddr,
+		Value:    big.NewInt(10),
+		Gas:      25000,
+		GasPrice: big.NewInt(1),
+		Data:     common.FromHex("5544"),
+	})
+
+	testAddr = common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
 )
 
 func TestTransactionSigHash(t *testing.T) {
@@ -73,6 +85,157 @@ func TestTransactionEncode(t *testing.T) {
 	}
 }
 
+func TestEIP2718TransactionSigHash(t *testing.T) {
+	s := NewEIP2930Signer(big.NewInt(1))
+	want := prefixedRlpHash(AccessListTxType, []interface{}{
+		big.NewInt(1), uint64(3), big.NewInt(1), uint64(25000), &testAddr, big.NewInt(10), common.FromHex("5544"), AccessList(nil),
+	})
+	if h := s.Hash(emptyEip2718Tx); h != want {
+		t.Errorf("empty EIP-2718 transaction hash mismatch, got %x want %x", h, want)
+	}
+}
+
+func TestEIP2718TransactionEncode(t *testing.T) {
+	key, _ := defaultTestKey()
+	signer := NewEIP2930Signer(big.NewInt(1))
+
+	tx := NewTx(&AccessListTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    3,
+		To:       &testAddr,
+		Value:    big.NewInt(10),
+		Gas:      25000,
+		GasPrice: big.NewInt(1),
+		Data:     common.FromHex("5544"),
+		AccessList: AccessList{
+			{Address: testAddr, StorageKeys: []common.Hash{{0x01}, {0x02}}},
+		},
+	})
+	signed, err := SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	// The canonical encoding is the type byte followed by the RLP payload
+	bin, err := signed.MarshalBinary()
+	if err != nil {
+		t.Fatalf("binary encode error: %v", err)
+	}
+	if bin[0] != AccessListTxType {
+		t.Fatalf("wrong type prefix: have %d, want %d", bin[0], AccessListTxType)
+	}
+	if uint64(len(bin)) != uint64(signed.Size()) {
+		t.Errorf("size mismatch: have %d, want %d", uint64(signed.Size()), len(bin))
+	}
+	if h := crypto.Keccak256Hash(bin); h != signed.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", signed.Hash(), h)
+	}
+	// Within RLP, the transaction is wrapped into a byte string
+	enc, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatalf("RLP encode error: %v", err)
+	}
+	want, _ := rlp.EncodeToBytes(bin)
+	if !bytes.Equal(enc, want) {
+		t.Errorf("RLP encoding mismatch: have %x, want %x", enc, want)
+	}
+	// Both forms must decode back into the same transaction
+	for i, decode := range []func() (*Transaction, error){
+		func() (*Transaction, error) { return decodeTx(enc) },
+		func() (*Transaction, error) {
+			tx := new(Transaction)
+			return tx, tx.UnmarshalBinary(bin)
+		},
+	} {
+		parsed, err := decode()
+		if err != nil {
+			t.Fatalf("decoder %d: decode error: %v", i, err)
+		}
+		if parsed.Hash() != signed.Hash() {
+			t.Errorf("decoder %d: hash mismatch: have %x, want %x", i, parsed.Hash(), signed.Hash())
+		}
+		if parsed.Type() != AccessListTxType {
+			t.Errorf("decoder %d: type mismatch: have %d", i, parsed.Type())
+		}
+		if len(parsed.AccessList()) != 1 || len(parsed.AccessList()[0].StorageKeys) != 2 {
+			t.Errorf("decoder %d: access list mismatch: %v", i, parsed.AccessList())
+		}
+	}
+	// Unknown transaction types must be rejected
+	if err := new(Transaction).UnmarshalBinary(append([]byte{0x7f}, bin[1:]...)); err != ErrTxTypeNotSupported {
+		t.Errorf("unexpected error for unknown tx type: %v", err)
+	}
+}
+
+func TestEIP2930Signer(t *testing.T) {
+	var (
+		key, keyAddr = defaultTestKey()
+		signer1      = NewEIP2930Signer(big.NewInt(1))
+		signer2      = NewEIP2930Signer(big.NewInt(2))
+		tx0          = NewTx(&AccessListTx{Nonce: 1})
+		tx1          = NewTx(&AccessListTx{ChainID: big.NewInt(1), Nonce: 1})
+		tx2, _       = SignNewTx(key, signer2, &AccessListTx{ChainID: big.NewInt(2), Nonce: 1})
+	)
+	tests := []struct {
+		tx             *Transaction
+		signer         Signer
+		wantSignerHash common.Hash
+		wantSenderErr  error
+		wantSignErr    error
+		wantHash       common.Hash // after signing
+	}{
+		{
+			tx:             tx0,
+			signer:         signer1,
+			wantSignerHash: signer1.Hash(tx1),
+			wantSenderErr:  ErrInvalidChainId,
+		},
+		{
+			tx:             tx1,
+			signer:         signer1,
+			wantSignerHash: signer1.Hash(tx1),
+			wantSenderErr:  ErrInvalidSig,
+		},
+		{
+			// This checks what happens when trying to sign an unsigned tx for the wrong chain.
+			tx:             tx1,
+			signer:         signer2,
+			wantSignerHash: signer2.Hash(tx0),
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignErr:    ErrInvalidChainId,
+		},
+		{
+			// This checks what happens when trying to re-sign a signed tx for the wrong chain.
+			tx:             tx2,
+			signer:         signer1,
+			wantSignerHash: signer1.Hash(NewTx(&AccessListTx{ChainID: big.NewInt(2), Nonce: 1})),
+			wantSenderErr:  ErrInvalidChainId,
+			wantSignErr:    ErrInvalidChainId,
+		},
+	}
+	for i, test := range tests {
+		sigHash := test.signer.Hash(test.tx)
+		if sigHash != test.wantSignerHash {
+			t.Errorf("test %d: wrong sig hash: got %x, want %x", i, sigHash, test.wantSignerHash)
+		}
+		sender, err := Sender(test.signer, test.tx)
+		if err != test.wantSenderErr {
+			t.Errorf("test %d: wrong Sender error %q", i, err)
+		}
+		if err == nil && sender != keyAddr {
+			t.Errorf("test %d: wrong sender address %x", i, sender)
+		}
+		signedTx, err := SignTx(test.tx, test.signer, key)
+		if err != test.wantSignErr {
+			t.Fatalf("test %d: wrong SignTx error %q", i, err)
+		}
+		if signedTx != nil {
+			if from, err := Sender(test.signer, signedTx); err != nil || from != keyAddr {
+				t.Errorf("test %d: wrong sender of signed tx: %x, %v", i, from, err)
+			}
+		}
+	}
+}
+
 func decodeTx(data []byte) (*Transaction, error) {
 	var tx Transaction
 	t, err := &tx, rlp.Decode(bytes.NewReader(data), &tx)
@@ -225,16 +388,26 @@ func TestTransactionJSON(t *testing.T) {
 	if err != nil {
 		t.Fatalf("could not generate key: %v", err)
 	}
-	signer := NewEIP155Signer(common.Big1)
+	signer := NewEIP2930Signer(common.Big1)
 
 	transactions := make([]*Transaction, 0, 50)
 	for i := uint64(0); i < 25; i++ {
 		var tx *Transaction
-		switch i % 2 {
+		switch i % 3 {
 		case 0:
 			tx = NewTransaction(i, common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
 		case 1:
 			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"))
+		case 2:
+			tx = NewTx(&AccessListTx{
+				ChainID:    common.Big1,
+				Nonce:      i,
+				To:         &common.Address{2},
+				Gas:        1,
+				GasPrice:   common.Big2,
+				Data:       []byte("abcdef"),
+				AccessList: AccessList{{Address: common.Address{3}, StorageKeys: []common.Hash{{4}}}},
+			})
 		}
 		transactions = append(transactions, tx)
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -101,6 +102,58 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+// Tests that the access list tracer collects the touched accounts and storage
+// slots, leaving out the sender, the recipient and the precompiles.
+func TestAccessListTracer(t *testing.T) {
+	var (
+		origin   = common.HexToAddress("0xc0ffee")
+		address  = common.BytesToAddress([]byte("contract"))
+		touched  = common.HexToAddress("0xdead")
+		compiled = common.BytesToAddress([]byte{0x01})
+	)
+	code := []byte{
+		byte(vm.PUSH1), 0x05,
+		byte(vm.SLOAD),
+		byte(vm.POP),
+		byte(vm.PUSH20),
+	}
+	code = append(code, touched.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.PUSH20))
+	code = append(code, compiled.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.ORIGIN), byte(vm.BALANCE), byte(vm.POP), byte(vm.STOP))
+
+	tracer := vm.NewAccessListTracer(nil, origin, address, vm.PrecompiledAddressesIstanbul)
+	if _, _, err := Execute(code, nil, &Config{Origin: origin, EVMConfig: vm.Config{Debug: true, Tracer: tracer}}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	want := map[common.Address][]common.Hash{
+		address: {common.BigToHash(big.NewInt(5))},
+		touched: {},
+	}
+	have := tracer.AccessList()
+	if len(have) != len(want) {
+		t.Fatalf("access list length mismatch: have %d, want %d: %v", len(have), len(want), have)
+	}
+	for _, tuple := range have {
+		keys, ok := want[tuple.Address]
+		if !ok {
+			t.Errorf("unexpected address in access list: %x", tuple.Address)
+			continue
+		}
+		if !reflect.DeepEqual(tuple.StorageKeys, keys) {
+			t.Errorf("storage keys mismatch for %x: have %v, want %v", tuple.Address, tuple.StorageKeys, keys)
+		}
+	}
+	// Running the same code with the collected list should result in the same list
+	again := vm.NewAccessListTracer(have, origin, address, vm.PrecompiledAddressesIstanbul)
+	if _, _, err := Execute(code, nil, &Config{Origin: origin, EVMConfig: vm.Config{Debug: true, Tracer: again}}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if !again.Equal(tracer) {
+		t.Errorf("access list not stable: have %v, want %v", again.AccessList(), have)
+	}
+}
+
 func TestCall(t *testing.T) {
 	state, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
 	address := common.HexToAddress("0x0a")
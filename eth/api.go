@@ -149,6 +149,47 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// SetBlockBuilder switches the strategy used to select and order the
+// transactions of new blocks.
+func (api *PrivateMinerAPI) SetBlockBuilder(name string) error {
+	return api.e.Miner().SetBlockBuilder(name)
+}
+
+// BlockBuilder returns the name of the block building strategy in use.
+func (api *PrivateMinerAPI) BlockBuilder() string {
+	return api.e.Miner().BlockBuilder()
+}
+
+// BlockTemplate is a block waiting to be sealed by an external sealer.
+type BlockTemplate struct {
+	Header   *types.Header `json:"header"`   // Header to seal, including the transaction and uncle roots
+	SealHash common.Hash   `json:"sealHash"` // Hash of the header the seal is computed over
+}
+
+// GetBlockTemplate returns the most recent block waiting to be sealed, allowing
+// external sealers to seal blocks for any consensus engine. The sealed header is
+// to be returned through SubmitSealedBlock.
+func (api *PrivateMinerAPI) GetBlockTemplate() (*BlockTemplate, error) {
+	header, sealHash, err := api.e.Miner().BlockTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return &BlockTemplate{Header: header, SealHash: sealHash}, nil
+}
+
+// SubmitSealedBlock accepts a header sealed by an external sealer, completing
+// the matching block template, and returns the hash of the resulting block.
+func (api *PrivateMinerAPI) SubmitSealedBlock(header *types.Header) (common.Hash, error) {
+	if header == nil {
+		return common.Hash{}, errors.New("missing header")
+	}
+	block, err := api.e.Miner().SubmitSealedBlock(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return block.Hash(), nil
+}
+
 // GetHashrate returns the current hashrate of the miner.
 func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return api.e.miner.HashRate()
@@ -0,0 +1,98 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+)
+
+// PrivateDevAPI provides private RPC methods to control the block production
+// and state of instant-sealing developer chains. These methods rewrite the chain
+// at will and must never be exposed on anything but throwaway networks.
+type PrivateDevAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateDevAPI creates a new RPC service which controls the developer chain
+// of this node.
+func NewPrivateDevAPI(e *Ethereum) *PrivateDevAPI {
+	return &PrivateDevAPI{e: e}
+}
+
+// Mine seals the given number of blocks (one if nil) on top of the current head,
+// including the executable transactions of the pool, and returns their hashes.
+func (api *PrivateDevAPI) Mine(blocks *hexutil.Uint64) ([]common.Hash, error) {
+	count := 1
+	if blocks != nil {
+		count = int(*blocks)
+	}
+	mined, err := api.e.devMiner.mine(count, nil)
+	hashes := make([]common.Hash, len(mined))
+	for i, block := range mined {
+		hashes[i] = block.Hash()
+	}
+	return hashes, err
+}
+
+// SetNextBlockTimestamp sets the timestamp of the next block sealed, which must
+// be after the current head block.
+func (api *PrivateDevAPI) SetNextBlockTimestamp(timestamp hexutil.Uint64) error {
+	return api.e.devMiner.setNextBlockTimestamp(uint64(timestamp))
+}
+
+// Snapshot records the current head block, returning the id to revert to it.
+func (api *PrivateDevAPI) Snapshot() hexutil.Uint64 {
+	return hexutil.Uint64(api.e.devMiner.snapshot())
+}
+
+// Revert rewinds the chain to the head block of the given snapshot, discarding
+// it along with all later snapshots. It returns false if the snapshot is unknown.
+func (api *PrivateDevAPI) Revert(id hexutil.Uint64) (bool, error) {
+	return api.e.devMiner.revert(uint64(id))
+}
+
+// SetBalance seals a block setting the balance of an account.
+func (api *PrivateDevAPI) SetBalance(address common.Address, balance hexutil.Big) error {
+	return api.override(func(statedb *state.StateDB) {
+		statedb.SetBalance(address, (*big.Int)(&balance))
+	})
+}
+
+// SetCode seals a block setting the code of an account.
+func (api *PrivateDevAPI) SetCode(address common.Address, code hexutil.Bytes) error {
+	return api.override(func(statedb *state.StateDB) {
+		statedb.SetCode(address, code)
+	})
+}
+
+// SetStorageAt seals a block setting a storage slot of an account.
+func (api *PrivateDevAPI) SetStorageAt(address common.Address, key common.Hash, value common.Hash) error {
+	return api.override(func(statedb *state.StateDB) {
+		statedb.SetState(address, key, value)
+	})
+}
+
+// override seals a block applying the given state modifications before any of
+// the transactions included.
+func (api *PrivateDevAPI) override(modify func(*state.StateDB)) error {
+	_, err := api.e.devMiner.mine(1, modify)
+	return err
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTestDevBackend creates an instant-sealing developer chain signed by the
+// given key.
+func newTestDevBackend(t *testing.T, key *ecdsa.PrivateKey) (*node.Node, *Ethereum) {
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	n, err := node.New(&node.Config{UseLightweightKDF: true})
+	if err != nil {
+		t.Fatalf("can't create new node: %v", err)
+	}
+	// Import the signer into the keystore, as done by the dev mode
+	ks := n.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		t.Fatalf("failed to import signer: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock signer: %v", err)
+	}
+	config := DefaultConfig
+	config.Genesis = core.DeveloperGenesisBlock(0, addr)
+	config.Developer = true
+	config.NoPruning = true
+	config.Miner.Etherbase = addr
+	config.Miner.GasPrice = big.NewInt(1)
+
+	ethservice, err := New(n, &config)
+	if err != nil {
+		t.Fatalf("can't create new ethereum service: %v", err)
+	}
+	ethservice.Engine().(*clique.Clique).Authorize(addr, ks.Wallets()[0].SignData)
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	return n, ethservice
+}
+
+// devTestTx creates a signed value transfer to the zero address.
+func devTestTx(t *testing.T, eth *Ethereum, key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+	signer := types.MakeSigner(eth.BlockChain().Config(), eth.BlockChain().CurrentBlock().Number())
+	tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// Tests that blocks are sealed on request, including the pending transactions,
+// honouring any requested timestamps.
+func TestDevMine(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	n, eth := newTestDevBackend(t, key)
+	defer n.Close()
+
+	api := NewPrivateDevAPI(eth)
+	if err := eth.TxPool().AddLocal(devTestTx(t, eth, key, 0)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	hashes, err := api.Mine(nil)
+	if err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	head := eth.BlockChain().CurrentBlock()
+	if len(hashes) != 1 || hashes[0] != head.Hash() {
+		t.Fatalf("mined block mismatch: have %x, want [%x]", hashes, head.Hash())
+	}
+	if head.NumberU64() != 1 || len(head.Transactions()) != 1 {
+		t.Fatalf("block #%d contains %d transactions, want #1 with 1", head.NumberU64(), len(head.Transactions()))
+	}
+	if receipts := eth.BlockChain().GetReceiptsByHash(head.Hash()); len(receipts) != 1 || receipts[0].Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("mined transaction receipt missing or failed")
+	}
+	// Seal multiple blocks at once
+	blocks := hexutil.Uint64(3)
+	if hashes, err = api.Mine(&blocks); err != nil {
+		t.Fatalf("failed to mine blocks: %v", err)
+	}
+	if len(hashes) != 3 || eth.BlockChain().CurrentBlock().NumberU64() != 4 {
+		t.Fatalf("mined %d blocks up to #%d, want 3 up to #4", len(hashes), eth.BlockChain().CurrentBlock().NumberU64())
+	}
+	// Seal a block in the future
+	timestamp := eth.BlockChain().CurrentBlock().Time() + 1000
+	if err := api.SetNextBlockTimestamp(hexutil.Uint64(eth.BlockChain().CurrentBlock().Time())); err == nil {
+		t.Fatalf("timestamp of the head block accepted")
+	}
+	if err := api.SetNextBlockTimestamp(hexutil.Uint64(timestamp)); err != nil {
+		t.Fatalf("failed to set timestamp: %v", err)
+	}
+	if _, err := api.Mine(&blocks); err != nil {
+		t.Fatalf("failed to mine blocks: %v", err)
+	}
+	for i, want := range []uint64{timestamp, timestamp + 1, timestamp + 2} {
+		if have := eth.BlockChain().GetBlockByNumber(uint64(5 + i)).Time(); have != want {
+			t.Errorf("block #%d: timestamp mismatch: have %d, want %d", 5+i, have, want)
+		}
+	}
+}
+
+// Tests that state overrides are sealed into blocks and discarded when reverting
+// to an earlier snapshot.
+func TestDevSnapshotRevert(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	n, eth := newTestDevBackend(t, key)
+	defer n.Close()
+
+	var (
+		api     = NewPrivateDevAPI(eth)
+		addr    = common.Address{0xaa}
+		balance = big.NewInt(1000)
+		code    = []byte{0x60, 0x00}
+		slot    = common.Hash{0x01}
+		value   = common.Hash{0x02}
+	)
+	id := api.Snapshot()
+	head := eth.BlockChain().CurrentBlock()
+
+	if err := api.SetBalance(addr, hexutil.Big(*balance)); err != nil {
+		t.Fatalf("failed to set balance: %v", err)
+	}
+	if err := api.SetCode(addr, code); err != nil {
+		t.Fatalf("failed to set code: %v", err)
+	}
+	if err := api.SetStorageAt(addr, slot, value); err != nil {
+		t.Fatalf("failed to set storage: %v", err)
+	}
+	statedb, err := eth.BlockChain().State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if have := statedb.GetBalance(addr); have.Cmp(balance) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", have, balance)
+	}
+	if have := statedb.GetCode(addr); !bytes.Equal(have, code) {
+		t.Errorf("code mismatch: have %x, want %x", have, code)
+	}
+	if have := statedb.GetState(addr, slot); have != value {
+		t.Errorf("storage mismatch: have %x, want %x", have, value)
+	}
+	// Revert to the snapshot, which may only be done once
+	if ok, err := api.Revert(id); !ok || err != nil {
+		t.Fatalf("failed to revert: %v %v", ok, err)
+	}
+	if have := eth.BlockChain().CurrentBlock().Hash(); have != head.Hash() {
+		t.Fatalf("head mismatch after revert: have %x, want %x", have, head.Hash())
+	}
+	if statedb, err = eth.BlockChain().State(); err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if statedb.Exist(addr) {
+		t.Errorf("overridden account survived revert")
+	}
+	if ok, _ := api.Revert(id); ok {
+		t.Errorf("snapshot reverted twice")
+	}
+}
+
+// Tests that blocks are sealed automatically as soon as transactions arrive
+// while mining.
+func TestDevAutoMine(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	n, eth := newTestDevBackend(t, key)
+	defer n.Close()
+
+	if err := eth.StartMining(0); err != nil {
+		t.Fatalf("failed to start mining: %v", err)
+	}
+	if !eth.IsMining() {
+		t.Fatalf("developer mode not mining")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if number := eth.BlockChain().CurrentBlock().NumberU64(); number != 0 {
+		t.Fatalf("empty block #%d sealed", number)
+	}
+	tx := devTestTx(t, eth, key, 0)
+	if err := eth.TxPool().AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); eth.BlockChain().CurrentBlock().NumberU64() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("transaction not sealed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if head := eth.BlockChain().CurrentBlock(); len(head.Transactions()) != 1 || head.Transactions()[0].Hash() != tx.Hash() {
+		t.Fatalf("sealed block misses transaction")
+	}
+	eth.StopMining()
+	if eth.IsMining() {
+		t.Fatalf("developer mode still mining")
+	}
+}
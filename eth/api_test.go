@@ -26,9 +26,11 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/node"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -201,3 +203,38 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that mining on a clique network without the signer's key available is
+// rejected, unless remote sealing was explicitly requested.
+func TestStartMiningWithoutSigner(t *testing.T) {
+	for _, remote := range []bool{false, true} {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+
+		n, err := node.New(&node.Config{})
+		if err != nil {
+			t.Fatalf("can't create new node: %v", err)
+		}
+		config := DefaultConfig
+		config.Genesis = core.DeveloperGenesisBlock(0, addr)
+		config.Miner.Etherbase = addr
+		config.Miner.RemoteSeal = remote
+
+		ethservice, err := New(n, &config)
+		if err != nil {
+			t.Fatalf("can't create new ethereum service: %v", err)
+		}
+		if err := n.Start(); err != nil {
+			t.Fatalf("can't start test node: %v", err)
+		}
+		err = ethservice.StartMining(0)
+		switch {
+		case !remote && err == nil:
+			t.Errorf("mining started without a signer")
+		case remote && err != nil:
+			t.Errorf("failed to start remotely sealed mining: %v", err)
+		}
+		ethservice.StopMining()
+		n.Close()
+	}
+}
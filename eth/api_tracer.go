@@ -798,15 +798,23 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
-			return nil, err
+		// Construct the native tracer if one exists by the requested name, or
+		// fall back to the JavaScript tracer to execute with
+		var stop func(err error)
+		if native, ok := tracers.NewNative(*config.Tracer); ok {
+			tracer, stop = native, native.Stop
+		} else {
+			js, err := tracers.New(*config.Tracer)
+			if err != nil {
+				return nil, err
+			}
+			tracer, stop = js, js.Stop
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -841,6 +849,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	case *tracers.Tracer:
 		return tracer.GetResult()
 
+	case tracers.NativeTracer:
+		return tracer.GetResult()
+
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
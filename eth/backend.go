@@ -34,11 +34,13 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -73,10 +75,13 @@ type Ethereum struct {
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
+	logIndexer        *core.ChainIndexer // Log address and topic indexer, nil if disabled
+	activitySub       event.Subscription // Chain head subscription updating the clique signer metrics, nil otherwise
 
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
+	devMiner  *devMiner // Instant-sealing block producer of developer chains, nil otherwise
 	gasPrice  *big.Int
 	etherbase common.Address
 
@@ -124,6 +129,11 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	// Resume any offline state pruning that was interrupted midway
+	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
+		log.Error("Failed to recover state", "error", err)
+	}
+
 	eth := &Ethereum{
 		config:            config,
 		chainDb:           chainDb,
@@ -138,6 +148,9 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		bloomIndexer:      NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		p2pServer:         stack.Server(),
 	}
+	if config.LogIndex {
+		eth.logIndexer = NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+	}
 
 	bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 	var dbVer = "<nil>"
@@ -182,6 +195,9 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if eth.logIndexer != nil {
+		eth.logIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -199,8 +215,11 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	}
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
+	if config.Developer {
+		eth.devMiner = newDevMiner(eth)
+	}
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil}
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil, NewPrivateDebugAPI(eth)}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -280,6 +299,15 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the developer APIs if running an instant-sealing dev chain
+	if s.devMiner != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "dev",
+			Version:   "1.0",
+			Service:   NewPrivateDevAPI(s),
+			Public:    false,
+		})
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -305,8 +333,11 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
-			Public:    true,
+			Service: filters.NewPublicFilterAPI(s.APIBackend, false, filters.Config{
+				RangeCap:  s.config.RPCLogRangeCap,
+				ResultCap: s.config.RPCLogResultCap,
+			}),
+			Public: true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -319,7 +350,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
-			Service:   NewPrivateDebugAPI(s),
+			Service:   s.APIBackend.debug,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -450,17 +481,30 @@ func (s *Ethereum) StartMining(threads int) error {
 		}
 		if clique, ok := s.engine.(*clique.Clique); ok {
 			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-			if wallet == nil || err != nil {
+			switch {
+			case wallet != nil && err == nil:
+				clique.Authorize(eb, wallet.SignData)
+
+			case s.config.Miner.RemoteSeal:
+				// Without a local signer, blocks need to be sealed remotely via the
+				// block template API
+				log.Warn("Etherbase account unavailable locally, awaiting remote seals", "etherbase", eb, "err", err)
+				clique.Authorize(eb, nil)
+
+			default:
 				log.Error("Etherbase account unavailable locally", "err", err)
 				return fmt.Errorf("signer missing: %v", err)
 			}
-			clique.Authorize(eb, wallet.SignData)
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		atomic.StoreUint32(&s.protocolManager.acceptTxs, 1)
 
-		go s.miner.Start(eb)
+		if s.devMiner != nil {
+			s.devMiner.start()
+		} else {
+			go s.miner.Start(eb)
+		}
 	}
 	return nil
 }
@@ -476,10 +520,21 @@ func (s *Ethereum) StopMining() {
 		th.SetThreads(-1)
 	}
 	// Stop the block creating itself
+	if s.devMiner != nil {
+		s.devMiner.stop()
+	}
 	s.miner.Stop()
 }
 
-func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
+// IsMining returns whether blocks are being produced, either by the miner or the
+// developer mode block producer.
+func (s *Ethereum) IsMining() bool {
+	if s.devMiner != nil && s.devMiner.isRunning() {
+		return true
+	}
+	return s.miner.Mining()
+}
+
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
 func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
@@ -505,6 +560,11 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 		protos[i].Attributes = []enr.Entry{s.currentEthEntry()}
 		protos[i].DialCandidates = s.dialCandidates
 	}
+	// Serve the snap protocol if snapshots are maintained, or if it's needed to
+	// retrieve the state during snap sync
+	if s.config.SnapshotCache > 0 || s.config.SyncMode == downloader.SnapSync {
+		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.protocolManager), nil)...)
+	}
 	return protos
 }
 
@@ -516,6 +576,11 @@ func (s *Ethereum) Start() error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Keep the clique signer metrics up to date with the canonical chain
+	if clique, ok := s.engine.(*clique.Clique); ok {
+		s.startActivityTracker(clique)
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -529,6 +594,24 @@ func (s *Ethereum) Start() error {
 	return nil
 }
 
+// startActivityTracker updates the missed slot metrics of the clique signers on
+// every chain head change, until the subscription is torn down on shutdown.
+func (s *Ethereum) startActivityTracker(engine *clique.Clique) {
+	heads := make(chan core.ChainHeadEvent, 10)
+	s.activitySub = s.blockchain.SubscribeChainHeadEvent(heads)
+
+	go func() {
+		for {
+			select {
+			case <-heads:
+				engine.UpdateActivity(s.blockchain)
+			case <-s.activitySub.Err():
+				return
+			}
+		}
+	}()
+}
+
 // Stop implements node.Lifecycle, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
@@ -537,7 +620,16 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	close(s.closeBloomHandler)
+	if s.activitySub != nil {
+		s.activitySub.Unsubscribe()
+	}
+	if s.devMiner != nil {
+		s.devMiner.stop()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.blockchain.Stop()
@@ -121,6 +121,7 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	LogIndex      bool   `toml:",omitempty"` // Whether to maintain an index of log addresses and topics
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
@@ -153,6 +154,10 @@ type Config struct {
 	// Mining options
 	Miner miner.Config
 
+	// Developer enables the instant-sealing developer mode, producing blocks only
+	// when transactions arrive or on request through the dev API
+	Developer bool `toml:",omitempty"`
+
 	// Ethash options
 	Ethash ethash.Config
 
@@ -181,6 +186,12 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64 `toml:",omitempty"`
 
+	// RPCLogRangeCap is the maximum number of blocks a single log query may span.
+	RPCLogRangeCap uint64 `toml:",omitempty"`
+
+	// RPCLogResultCap is the maximum number of logs a single log query may return.
+	RPCLogResultCap int `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -0,0 +1,294 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errNoInstantSeal     = errors.New("consensus engine doesn't support instant sealing")
+	errTimestampTooEarly = errors.New("timestamp not after the head block")
+)
+
+// instantSealer is implemented by consensus engines able to seal blocks on
+// demand, without waiting for their turn.
+type instantSealer interface {
+	InstantSeal(chain consensus.ChainHeaderReader, block *types.Block) (*types.Block, error)
+}
+
+// devMiner is the block producer of developer chains. Instead of sealing work
+// continuously, it seals a block as soon as transactions arrive, every block
+// period if the chain has one, or whenever requested through the dev API.
+type devMiner struct {
+	eth *Ethereum
+
+	lock      sync.Mutex    // Serializes block production and chain rewinds
+	nextTime  uint64        // Timestamp of the next block, automatic if zero
+	snapshots []common.Hash // Head blocks at the time of the snapshots, by id - 1
+
+	running int32 // Whether blocks are sealed automatically
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newDevMiner(eth *Ethereum) *devMiner {
+	return &devMiner{eth: eth}
+}
+
+// start begins sealing blocks automatically as transactions arrive.
+func (d *devMiner) start() {
+	if !atomic.CompareAndSwapInt32(&d.running, 0, 1) {
+		return
+	}
+	d.quit = make(chan struct{})
+	d.wg.Add(1)
+	go d.loop(d.quit)
+}
+
+// stop ceases sealing blocks automatically.
+func (d *devMiner) stop() {
+	if !atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		return
+	}
+	close(d.quit)
+	d.wg.Wait()
+}
+
+// isRunning returns whether blocks are sealed automatically.
+func (d *devMiner) isRunning() bool {
+	return atomic.LoadInt32(&d.running) == 1
+}
+
+// loop seals a block whenever new transactions are added to the pool, and on
+// every block period if the chain has one.
+func (d *devMiner) loop(quit chan struct{}) {
+	defer d.wg.Done()
+
+	txsCh := make(chan core.NewTxsEvent, txChanSize)
+	txsSub := d.eth.txPool.SubscribeNewTxsEvent(txsCh)
+	defer txsSub.Unsubscribe()
+
+	var periodCh <-chan time.Time
+	if clique := d.eth.blockchain.Config().Clique; clique != nil {
+		if period := clique.PeriodAt(d.eth.blockchain.CurrentBlock().NumberU64() + 1); period > 0 {
+			ticker := time.NewTicker(time.Duration(period) * time.Second)
+			defer ticker.Stop()
+			periodCh = ticker.C
+		}
+	}
+	for {
+		select {
+		case <-txsCh:
+			// Include everything arrived in the meantime in the same block
+			for drained := false; !drained; {
+				select {
+				case <-txsCh:
+				default:
+					drained = true
+				}
+			}
+		case <-periodCh:
+		case <-txsSub.Err():
+			return
+		case <-quit:
+			return
+		}
+		if _, err := d.mine(1, nil); err != nil {
+			log.Warn("Failed to seal developer block", "err", err)
+		}
+	}
+}
+
+// mine seals the given number of blocks on top of the current head, including
+// the executable transactions of the pool. The optional modify callback alters
+// the state of the first block before its transactions are executed.
+func (d *devMiner) mine(blocks int, modify func(*state.StateDB)) ([]*types.Block, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	mined := make([]*types.Block, 0, blocks)
+	for i := 0; i < blocks; i++ {
+		block, err := d.mineBlock(modify)
+		if err != nil {
+			return mined, err
+		}
+		mined = append(mined, block)
+		modify = nil
+	}
+	return mined, nil
+}
+
+// mineBlock assembles, seals and imports a single block on top of the current
+// head. The caller must hold the lock.
+func (d *devMiner) mineBlock(modify func(*state.StateDB)) (*types.Block, error) {
+	var (
+		chain  = d.eth.blockchain
+		config = chain.Config()
+		parent = chain.CurrentBlock()
+	)
+	sealer, ok := d.eth.engine.(instantSealer)
+	if !ok {
+		return nil, errNoInstantSeal
+	}
+	etherbase, err := d.eth.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent, d.eth.config.Miner.GasFloor, d.eth.config.Miner.GasCeil),
+		Extra:      makeExtraData(d.eth.config.Miner.ExtraData),
+		Time:       uint64(time.Now().Unix()),
+		Coinbase:   etherbase,
+	}
+	if err := d.eth.engine.Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	// Honour any requested timestamp, keeping them strictly increasing
+	if d.nextTime != 0 {
+		header.Time, d.nextTime = d.nextTime, 0
+	}
+	if header.Time <= parent.Time() {
+		header.Time = parent.Time() + 1
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	if modify != nil {
+		modify(statedb)
+	}
+	// Execute the pending transactions, skipping any that fail
+	pending, err := d.eth.txPool.Pending()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer   = types.MakeSigner(config, header.Number)
+		txs      = types.NewTransactionsByPriceAndNonce(signer, pending)
+		gasPool  = new(core.GasPool).AddGas(header.GasLimit)
+		included []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for tx := txs.Peek(); tx != nil && gasPool.Gas() >= params.TxGas; tx = txs.Peek() {
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+		snap := statedb.Snapshot()
+
+		receipt, err := core.ApplyTransaction(config, chain, &etherbase, gasPool, statedb, header, tx, &header.GasUsed, *chain.GetVMConfig())
+		if err != nil {
+			statedb.RevertToSnapshot(snap)
+			log.Debug("Skipping transaction of developer block", "hash", tx.Hash(), "err", err)
+			txs.Pop()
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+		txs.Shift()
+	}
+	block, err := d.eth.engine.FinalizeAndAssemble(chain, header, statedb, included, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	if block, err = sealer.InstantSeal(chain, block); err != nil {
+		return nil, err
+	}
+	// Update the block location fields of the receipts and logs, now known
+	var (
+		hash = block.Hash()
+		logs []*types.Log
+	)
+	for i, receipt := range receipts {
+		receipt.BlockHash = hash
+		receipt.BlockNumber = block.Number()
+		receipt.TransactionIndex = uint(i)
+		for _, log := range receipt.Logs {
+			log.BlockHash = hash
+		}
+		logs = append(logs, receipt.Logs...)
+	}
+	if _, err := chain.WriteBlockWithState(block, receipts, logs, statedb, true); err != nil {
+		return nil, err
+	}
+	log.Info("Sealed new developer block", "number", block.Number(), "hash", hash, "txs", len(included), "gas", block.GasUsed())
+	d.eth.eventMux.Post(core.NewMinedBlockEvent{Block: block})
+
+	return block, nil
+}
+
+// setNextBlockTimestamp sets the timestamp of the next block sealed.
+func (d *devMiner) setNextBlockTimestamp(timestamp uint64) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if timestamp <= d.eth.blockchain.CurrentBlock().Time() {
+		return errTimestampTooEarly
+	}
+	d.nextTime = timestamp
+	return nil
+}
+
+// snapshot records the current head block, returning the id to revert to it.
+func (d *devMiner) snapshot() uint64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.snapshots = append(d.snapshots, d.eth.blockchain.CurrentBlock().Hash())
+	return uint64(len(d.snapshots))
+}
+
+// revert rewinds the chain to the head block of a snapshot, dropping it along
+// with all later snapshots. It reports false if the snapshot is unknown.
+func (d *devMiner) revert(id uint64) (bool, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if id == 0 || id > uint64(len(d.snapshots)) {
+		return false, nil
+	}
+	var (
+		chain = d.eth.blockchain
+		hash  = d.snapshots[id-1]
+	)
+	header := chain.GetHeaderByHash(hash)
+	if header == nil || chain.GetCanonicalHash(header.Number.Uint64()) != hash {
+		return false, fmt.Errorf("snapshot block %x no longer canonical", hash)
+	}
+	if err := chain.SetHead(header.Number.Uint64()); err != nil {
+		return false, err
+	}
+	if head := chain.CurrentBlock(); head.Hash() != hash {
+		return false, fmt.Errorf("state of snapshot block #%d unavailable, rewound to #%d", header.Number, head.Number())
+	}
+	d.snapshots = d.snapshots[:id-1]
+	d.nextTime = 0
+	return true, nil
+}
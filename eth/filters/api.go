@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -49,6 +50,12 @@ type filter struct {
 	s        *Subscription // associated subscription in event system
 }
 
+// Config are the limits enforced by the filter API on historical log queries.
+type Config struct {
+	RangeCap  uint64 // Maximum number of blocks a log query may span (0 = no cap)
+	ResultCap int    // Maximum number of logs a log query may return (0 = no cap)
+}
+
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
@@ -59,12 +66,14 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	config    Config
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, config Config) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend: backend,
+		config:  config,
 		chainDb: backend.ChainDb(),
 		events:  NewEventSystem(backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
@@ -234,12 +243,25 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// If a cursor (typically the last log notification received) is given, the
+// subscription is resumable: the matching logs after the cursor are replayed
+// before switching to the new ones, and logs of reorged blocks are retracted
+// by sending them again with the removed flag set. A cursor without a block
+// hash (e.g. an empty object) replays the logs from the start block of the
+// criteria instead. Replays spanning too many blocks are rejected, and if the
+// replay fails midway, the subscription is ended with the error.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria, cursor *LogCursor) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
+	if cursor != nil {
+		if crit.BlockHash != nil {
+			return nil, errors.New("can't resume logs of a single block")
+		}
+		return api.resumableLogs(ctx, notifier, crit, cursor)
+	}
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
@@ -271,6 +293,84 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// resumableLogs creates a log subscription replaying the logs after the cursor
+// or the start block of the criteria, then following the canonical chain.
+func (api *PublicFilterAPI) resumableLogs(ctx context.Context, notifier *rpc.Notifier, crit FilterCriteria, cursor *LogCursor) (*rpc.Subscription, error) {
+	rpcSub := notifier.CreateSubscription()
+	rangeCap := api.config.RangeCap
+	if rangeCap == 0 {
+		rangeCap = defaultReplayRangeCap
+	}
+	resumer, err := newLogResumer(ctx, api.backend, crit, cursor, rangeCap, func(log *types.Log) error {
+		return notifier.Notify(rpcSub.ID, log)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Chain heads merely trigger the resumer, make sure they never block the
+	// event system while logs are being delivered
+	var (
+		headers = make(chan *types.Header)
+		wakeup  = make(chan struct{}, 1)
+	)
+	headersSub := api.events.SubscribeNewHeads(headers)
+
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		defer headersSub.Unsubscribe()
+
+		wakeup <- struct{}{}
+		for {
+			select {
+			case <-headers:
+				select {
+				case wakeup <- struct{}{}:
+				default:
+				}
+			case <-wakeup:
+				// Deliver the logs in the background, aborting on unsubscribe
+				done := make(chan error, 1)
+				go func() {
+					done <- resumer.advance(ctx)
+				}()
+			deliver:
+				for {
+					select {
+					case err := <-done:
+						if err != nil {
+							// Logs can't be delivered exactly once anymore, end the
+							// subscription so the client can resume it
+							log.Debug("Failed to deliver resumed logs", "id", rpcSub.ID, "err", err)
+							notifier.Terminate(rpcSub.ID, err)
+							return
+						}
+						break deliver
+					case <-headers:
+						select {
+						case wakeup <- struct{}{}:
+						default:
+						}
+					case <-rpcSub.Err():
+						cancel()
+						<-done
+						return
+					case <-notifier.Closed():
+						cancel()
+						<-done
+						return
+					}
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -340,7 +440,10 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+		filter.rangeCap = api.config.RangeCap
 	}
+	filter.resultCap = api.config.ResultCap
+
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -395,7 +498,10 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+		filter.rangeCap = api.config.RangeCap
 	}
+	filter.resultCap = api.config.ResultCap
+
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -19,11 +19,14 @@ package filters
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -47,6 +50,21 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// logIndexBackend is implemented by backends maintaining a log index, mapping
+// the addresses and topics of logs to the blocks containing them.
+type logIndexBackend interface {
+	// LogIndexStatus returns the section size of the log index and the number
+	// of sections indexed so far.
+	LogIndexStatus() (uint64, uint64)
+}
+
+// limitExceededError is returned when a log query exceeds a configured limit.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -58,7 +76,11 @@ type Filter struct {
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
 
+	rangeCap  uint64 // Maximum number of blocks the range may span (0 = no cap)
+	resultCap int    // Maximum number of logs the filter may return (0 = no cap)
+
 	matcher *bloombits.Matcher
+	terms   [][][]byte // Non-wildcard address and topic clauses for the log index
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -88,6 +110,11 @@ func NewRangeFilter(backend Backend, begin, end int64, addresses []common.Addres
 	filter := newFilter(backend, addresses, topics)
 
 	filter.matcher = bloombits.NewMatcher(size, filters)
+	for _, clause := range filters {
+		if len(clause) > 0 {
+			filter.terms = append(filter.terms, clause)
+		}
+	}
 	filter.begin = begin
 	filter.end = end
 
@@ -126,7 +153,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		if header == nil {
 			return nil, errors.New("unknown block")
 		}
-		return f.blockLogs(ctx, header)
+		logs, err := f.blockLogs(ctx, header)
+		if err != nil {
+			return logs, err
+		}
+		return logs, f.checkResults(len(logs))
 	}
 	// Figure out the limits of the filter range
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
@@ -142,17 +173,39 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	if f.rangeCap > 0 && end >= uint64(f.begin) && end-uint64(f.begin)+1 > f.rangeCap {
+		return nil, &limitExceededError{fmt.Sprintf("block range too large (%d > %d)", end-uint64(f.begin)+1, f.rangeCap)}
+	}
+	// Gather all logs covered by the log index, if there's one
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(logIndexBackend); ok && len(f.terms) > 0 {
+		size, sections := backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.logIndexLogs(ctx, size, end)
+			} else {
+				logs, err = f.logIndexLogs(ctx, size, indexed-1)
+			}
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
+	// Gather all bloom indexed logs, and finish with non indexed ones
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	if indexed := sections * size; indexed > uint64(f.begin) && uint64(f.begin) <= end {
+		var found []*types.Log
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
+		}
+		logs = append(logs, found...)
+		if err == nil {
+			err = f.checkResults(len(logs))
 		}
 		if err != nil {
 			return logs, err
@@ -160,9 +213,93 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	rest, err := f.unindexedLogs(ctx, end)
 	logs = append(logs, rest...)
+	if err == nil {
+		err = f.checkResults(len(logs))
+	}
 	return logs, err
 }
 
+// checkResults returns an error if the given number of logs exceeds the result
+// cap of the filter.
+func (f *Filter) checkResults(n int) error {
+	if f.resultCap > 0 && n > f.resultCap {
+		return &limitExceededError{fmt.Sprintf("query returned more than %d results", f.resultCap)}
+	}
+	return nil
+}
+
+// logIndexLogs returns the logs matching the filter criteria based on the log
+// index sections available locally.
+func (f *Filter) logIndexLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for uint64(f.begin) <= end {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		var (
+			section = uint64(f.begin) / size
+			first   = section * size
+			last    = first + size - 1
+		)
+		if last > end {
+			last = end
+		}
+		matches, err := f.logIndexMatches(section, size)
+		if err != nil {
+			return logs, err
+		}
+		for number := uint64(f.begin); number <= last; number++ {
+			if matches[(number-first)/8]&(1<<(7-(number-first)%8)) == 0 {
+				continue
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			if err := f.checkResults(len(logs)); err != nil {
+				return logs, err
+			}
+		}
+		f.begin = int64(last) + 1
+	}
+	return logs, nil
+}
+
+// logIndexMatches returns the bitset of the blocks in a log index section which
+// may contain logs matching the filter: the blocks referencing any address and
+// at least one topic of every topic clause.
+func (f *Filter) logIndexMatches(section uint64, size uint64) ([]byte, error) {
+	head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+
+	var matches []byte
+	for _, clause := range f.terms {
+		union := make([]byte, size/8)
+		for _, term := range clause {
+			blob := rawdb.ReadLogIndex(f.db, term, section, head)
+			if len(blob) == 0 {
+				continue
+			}
+			bits, err := bitutil.DecompressBytes(blob, int(size/8))
+			if err != nil {
+				return nil, err
+			}
+			bitutil.ORBytes(union, union, bits)
+		}
+		if matches == nil {
+			matches = union
+		} else {
+			bitutil.ANDBytes(matches, matches, union)
+		}
+	}
+	return matches, nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -102,13 +102,16 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+
+	quit      chan struct{} // Channel closed to stop the event loop
+	closeOnce sync.Once
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
 // parses and filters them. It uses the all map to retrieve filter changes. The
 // work loop holds its own index that is used to forward events to filters.
 //
-// The returned manager has a loop that needs to be stopped with the Stop function
+// The returned manager has a loop that needs to be stopped with the Close function
 // or by stopping the given mux.
 func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
@@ -121,6 +124,7 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		quit:          make(chan struct{}),
 	}
 
 	// Subscribe events
@@ -164,6 +168,8 @@ func (sub *Subscription) Unsubscribe() {
 			select {
 			case sub.es.uninstall <- sub.f:
 				break uninstallLoop
+			case <-sub.es.quit:
+				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
@@ -179,11 +185,22 @@ func (sub *Subscription) Unsubscribe() {
 
 // subscribe installs the subscription in the event broadcast loop.
 func (es *EventSystem) subscribe(sub *subscription) *Subscription {
-	es.install <- sub
-	<-sub.installed
+	select {
+	case es.install <- sub:
+		<-sub.installed
+	case <-es.quit:
+		// The event loop is gone, hand out an already ended subscription
+		close(sub.err)
+	}
 	return &Subscription{ID: sub.id, f: sub, es: es}
 }
 
+// Close stops the event loop and releases the backend event subscriptions.
+// All installed subscriptions are ended, new ones are ended on creation.
+func (es *EventSystem) Close() {
+	es.closeOnce.Do(func() { close(es.quit) })
+}
+
 // SubscribeLogs creates a subscription that will write all logs matching the
 // given criteria to the given logs channel. Default value for the from and to
 // block is "latest". If the fromBlock > toBlock an error is returned.
@@ -454,6 +471,18 @@ func (es *EventSystem) eventLoop() {
 	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
 		index[i] = make(map[rpc.ID]*subscription)
 	}
+	// End all subscriptions still installed when the loop stops
+	defer func() {
+		ended := make(map[rpc.ID]bool)
+		for _, filters := range index {
+			for id, f := range filters {
+				if !ended[id] {
+					ended[id] = true
+					close(f.err)
+				}
+			}
+		}
+	}()
 
 	for {
 		select {
@@ -497,6 +526,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.quit:
+			return
 		}
 	}
 }
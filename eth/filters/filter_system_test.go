@@ -163,7 +163,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false)
+		api         = NewPublicFilterAPI(backend, false, Config{})
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -208,6 +208,38 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestEventSystemClose tests that closing the event system ends the installed
+// subscriptions and hands out ended ones afterwards.
+func TestEventSystemClose(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		events  = NewEventSystem(backend, false)
+	)
+	headers := make(chan *types.Header)
+	sub := events.SubscribeNewHeads(headers)
+
+	events.Close()
+	select {
+	case <-sub.Err():
+	case <-time.After(time.Second):
+		t.Fatal("installed subscription not ended on close")
+	}
+	sub.Unsubscribe()
+
+	hashes := make(chan []common.Hash)
+	late := events.SubscribePendingTxs(hashes)
+	select {
+	case <-late.Err():
+	case <-time.After(time.Second):
+		t.Fatal("subscription created after close not ended")
+	}
+	late.Unsubscribe()
+	events.Close()
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -215,7 +247,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false)
+		api     = NewPublicFilterAPI(backend, false, Config{})
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -270,7 +302,7 @@ func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false)
+		api     = NewPublicFilterAPI(backend, false, Config{})
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -314,7 +346,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false)
+		api     = NewPublicFilterAPI(backend, false, Config{})
 	)
 
 	// different situations where log filter creation should fail.
@@ -336,7 +368,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false)
+		api       = NewPublicFilterAPI(backend, false, Config{})
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -361,7 +393,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false)
+		api     = NewPublicFilterAPI(backend, false, Config{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -475,7 +507,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false)
+		api     = NewPublicFilterAPI(backend, false, Config{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -608,3 +640,152 @@ func flattenLogs(pl [][]*types.Log) []*types.Log {
 	}
 	return logs
 }
+
+// TestResumableLogsSubscription tests that log subscriptions starting at a given
+// block or cursor replay the logs from there, and follow reorgs exactly. Plain
+// log subscriptions must not replay anything, and failing replays must end the
+// subscription.
+func TestResumableLogsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, Config{})
+		addr    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		genesis = core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	)
+	makeChain := func(parent *types.Block, n int, seed byte) []*types.Block {
+		chain, receipts := core.GenerateChain(params.TestChainConfig, parent, ethash.NewFaker(), db, n, func(i int, gen *core.BlockGen) {
+			gen.SetCoinbase(common.Address{seed})
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{seed}, big.NewInt(1), 1, big.NewInt(1), nil))
+		})
+		for i, block := range chain {
+			rawdb.WriteBlock(db, block)
+			rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+			rawdb.WriteHeadBlockHash(db, block.Hash())
+			rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		}
+		return chain
+	}
+	chainA := makeChain(genesis, 80, 1)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	subscribe := func(crit map[string]interface{}, cursor interface{}) (chan *types.Log, *rpc.ClientSubscription) {
+		logs := make(chan *types.Log, 128)
+		var (
+			sub *rpc.ClientSubscription
+			err error
+		)
+		if cursor != nil {
+			sub, err = client.EthSubscribe(context.Background(), logs, "logs", crit, cursor)
+		} else {
+			sub, err = client.EthSubscribe(context.Background(), logs, "logs", crit)
+		}
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		return logs, sub
+	}
+	expect := func(logs chan *types.Log, blocks []*types.Block, removed bool) {
+		t.Helper()
+		for _, block := range blocks {
+			select {
+			case log := <-logs:
+				if log.BlockHash != block.Hash() || log.Removed != removed {
+					t.Fatalf("log mismatch: have block #%d [%x] removed %v, want block #%d [%x] removed %v",
+						log.BlockNumber, log.BlockHash[:4], log.Removed, block.NumberU64(), block.Hash().Bytes()[:4], removed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for log of block #%d", block.NumberU64())
+			}
+		}
+	}
+	reversed := func(blocks []*types.Block) []*types.Block {
+		out := make([]*types.Block, len(blocks))
+		for i, block := range blocks {
+			out[len(blocks)-1-i] = block
+		}
+		return out
+	}
+	// Plain subscriptions only deliver new logs, even with a start block
+	plain, sub := subscribe(map[string]interface{}{"fromBlock": "0x3", "address": addr}, nil)
+	defer sub.Unsubscribe()
+
+	// Replay history from a start block, requested by an empty cursor
+	logs, sub := subscribe(map[string]interface{}{"fromBlock": "0x3", "address": addr}, struct{}{})
+	defer sub.Unsubscribe()
+	expect(logs, chainA[2:], false)
+	select {
+	case log := <-plain:
+		t.Fatalf("plain subscription replayed log of block #%d", log.BlockNumber)
+	default:
+	}
+
+	// Reorg the last blocks, logs of the old ones must be retracted first
+	chainB := makeChain(chainA[74], 7, 2)
+	backend.chainFeed.Send(core.ChainEvent{Block: chainB[len(chainB)-1], Hash: chainB[len(chainB)-1].Hash()})
+
+	expect(logs, reversed(chainA[75:]), true)
+	expect(logs, chainB, false)
+
+	// Resume after a log of a reorged block, and after a removed notification
+	cursor := &types.Log{BlockNumber: chainA[78].NumberU64(), BlockHash: chainA[78].Hash(), Index: 0}
+	logs, sub = subscribe(map[string]interface{}{"address": addr}, cursor)
+	defer sub.Unsubscribe()
+	expect(logs, reversed(chainA[75:79]), true)
+	expect(logs, chainB, false)
+
+	cursor.Removed = true
+	logs, sub = subscribe(map[string]interface{}{"address": addr}, cursor)
+	defer sub.Unsubscribe()
+	expect(logs, reversed(chainA[75:78]), true)
+	expect(logs, chainB, false)
+
+	// Resume after a canonical log, with nothing to retract
+	cursor = &types.Log{BlockNumber: chainB[3].NumberU64(), BlockHash: chainB[3].Hash(), Index: 0}
+	logs, sub = subscribe(map[string]interface{}{"address": addr}, cursor)
+	defer sub.Unsubscribe()
+	expect(logs, chainB[4:], false)
+
+	// Unknown cursors, empty cursors without a start block and replays of too
+	// many blocks must be rejected
+	if _, err := client.EthSubscribe(context.Background(), make(chan *types.Log), "logs", map[string]interface{}{}, &types.Log{BlockHash: common.Hash{1}}); err == nil {
+		t.Fatal("subscription with unknown cursor succeeded")
+	}
+	if _, err := client.EthSubscribe(context.Background(), make(chan *types.Log), "logs", map[string]interface{}{}, struct{}{}); err == nil {
+		t.Fatal("subscription with empty cursor and no start block succeeded")
+	}
+	cappedServer := rpc.NewServer()
+	defer cappedServer.Stop()
+	if err := cappedServer.RegisterName("eth", NewPublicFilterAPI(backend, false, Config{RangeCap: 10})); err != nil {
+		t.Fatal(err)
+	}
+	cappedClient := rpc.DialInProc(cappedServer)
+	defer cappedClient.Close()
+	if _, err := cappedClient.EthSubscribe(context.Background(), make(chan *types.Log), "logs", map[string]interface{}{"fromBlock": "0x3"}, struct{}{}); err == nil {
+		t.Fatal("subscription replaying beyond the range cap succeeded")
+	}
+	// Reorg the block the last subscription is positioned at out and forget it,
+	// the subscription must end as its logs can't be retracted anymore
+	makeChain(chainB[5], 2, 3)
+	rawdb.DeleteHeader(db, chainB[6].Hash(), chainB[6].NumberU64())
+	backend.chainFeed.Send(core.ChainEvent{Block: chainB[6], Hash: chainB[6].Hash()})
+
+	select {
+	case err := <-sub.Err():
+		if err == nil || err.Error() != errUnknownCursor.Error() {
+			t.Fatalf("subscription error mismatch: have %v, want %v", err, errUnknownCursor)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed subscription not ended")
+	}
+}
@@ -21,15 +21,18 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -223,3 +226,161 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// logIndexTestBackend is a testBackend additionally serving a log index.
+type logIndexTestBackend struct {
+	*testBackend
+	logSections uint64
+}
+
+func (b *logIndexTestBackend) LogIndexStatus() (uint64, uint64) {
+	return params.BloomBitsBlocks, b.logSections
+}
+
+func TestLogIndexFilters(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &logIndexTestBackend{testBackend: &testBackend{db: db}, logSections: 1}
+		addr1   = common.BytesToAddress([]byte("addr1"))
+		addr2   = common.BytesToAddress([]byte("addr2"))
+		topic1  = common.BytesToHash([]byte("topic1"))
+		topic2  = common.BytesToHash([]byte("topic2"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, int(params.BloomBitsBlocks)+10, func(i int, gen *core.BlockGen) {
+		var logs []*types.Log
+		switch i {
+		case 10, 2000:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{topic1}}}
+		case 3000:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{topic2}}, {Address: addr2, Topics: []common.Hash{topic1}}}
+		case int(params.BloomBitsBlocks) + 5:
+			logs = []*types.Log{{Address: addr1, Topics: []common.Hash{topic1}}}
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = logs
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	// Write the chain and build the index of the first section by hand
+	terms := make(map[string][]byte)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+
+		number := block.NumberU64()
+		if number >= params.BloomBitsBlocks {
+			continue
+		}
+		for _, receipt := range receipts[i] {
+			for _, log := range receipt.Logs {
+				keys := [][]byte{log.Address.Bytes()}
+				for _, topic := range log.Topics {
+					keys = append(keys, topic.Bytes())
+				}
+				for _, key := range keys {
+					if terms[string(key)] == nil {
+						terms[string(key)] = make([]byte, params.BloomBitsBlocks/8)
+					}
+					terms[string(key)][number/8] |= 1 << (7 - number%8)
+				}
+			}
+		}
+	}
+	head := rawdb.ReadCanonicalHash(db, params.BloomBitsBlocks-1)
+	for term, bits := range terms {
+		rawdb.WriteLogIndex(db, []byte(term), 0, head, bitutil.CompressBytes(bits))
+	}
+	tests := []struct {
+		addresses []common.Address
+		topics    [][]common.Hash
+		begin     int64
+		want      []uint64
+	}{
+		{[]common.Address{addr1}, nil, 0, []uint64{11, 2001, 3001, params.BloomBitsBlocks + 6}},
+		{[]common.Address{addr1}, [][]common.Hash{{topic1}}, 0, []uint64{11, 2001, params.BloomBitsBlocks + 6}},
+		{[]common.Address{addr2}, [][]common.Hash{{topic1}}, 0, []uint64{3001}},
+		{[]common.Address{addr1, addr2}, [][]common.Hash{{topic1}}, 100, []uint64{2001, 3001, params.BloomBitsBlocks + 6}},
+		{nil, [][]common.Hash{{topic2}}, 0, []uint64{3001}},
+		{[]common.Address{addr2}, [][]common.Hash{{topic2}}, 0, nil},
+	}
+	for i, tt := range tests {
+		logs, err := NewRangeFilter(backend, tt.begin, -1, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: filter failed: %v", i, err)
+		}
+		var have []uint64
+		for _, log := range logs {
+			have = append(have, log.BlockNumber)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Ensure indexed sections are served from the index alone
+	rawdb.WriteLogIndex(db, topic2.Bytes(), 0, head, bitutil.CompressBytes(make([]byte, params.BloomBitsBlocks/8)))
+	if logs, err := NewRangeFilter(backend, 0, -1, nil, [][]common.Hash{{topic2}}).Logs(context.Background()); err != nil || len(logs) != 0 {
+		t.Errorf("unindexed logs returned: %v, %v", logs, err)
+	}
+}
+
+func TestFilterLimits(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, Config{RangeCap: 10, ResultCap: 3})
+		addr    = common.BytesToAddress([]byte("addr"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 20, func(i int, gen *core.BlockGen) {
+		gen.AddUncheckedReceipt(makeReceipt(addr))
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	tests := []struct {
+		from, to rpc.BlockNumber
+		logs     int
+		err      string
+	}{
+		{1, 3, 3, ""},
+		{1, 4, 0, "query returned more than 3 results"},
+		{0, 9, 0, "query returned more than 3 results"},
+		{0, 10, 0, "block range too large (11 > 10)"},
+		{15, rpc.LatestBlockNumber, 0, "query returned more than 3 results"},
+		{10, rpc.LatestBlockNumber, 0, "block range too large (11 > 10)"},
+		{18, rpc.LatestBlockNumber, 3, ""},
+	}
+	for i, tt := range tests {
+		crit := FilterCriteria{
+			FromBlock: big.NewInt(tt.from.Int64()),
+			ToBlock:   big.NewInt(tt.to.Int64()),
+			Addresses: []common.Address{addr},
+		}
+		logs, err := api.GetLogs(context.Background(), crit)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			} else if len(logs) != tt.logs {
+				t.Errorf("test %d: log count mismatch: have %d, want %d", i, len(logs), tt.logs)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+			continue
+		}
+		if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32005 {
+			t.Errorf("test %d: error is not a limit error: %v", i, err)
+		}
+	}
+}
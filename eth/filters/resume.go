@@ -0,0 +1,261 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// resumeConfirms is the distance from the head beyond which resumed log
+// subscriptions replay history in bulk, through the log filter indexes. More
+// recent blocks are walked one by one to follow reorgs exactly.
+const resumeConfirms = 64
+
+// endOfBlock is the log index marking a block as fully delivered.
+const endOfBlock = ^uint(0)
+
+// defaultReplayRangeCap is the maximum number of blocks a resumed log subscription
+// may replay if log queries are not capped explicitly.
+const defaultReplayRangeCap = 10000
+
+var errUnknownCursor = errors.New("unknown cursor block")
+
+// LogCursor is the position of a resumable log subscription, as of the last log
+// delivered to the subscriber. Any log notification can be passed back as the
+// cursor to resume a subscription right after it, removed flag included.
+type LogCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Index       hexutil.Uint   `json:"logIndex"`
+	Removed     bool           `json:"removed"`
+}
+
+// logPosition is the progress of a resumable log subscription: the logs of the
+// block with the given number and hash below index next have been delivered,
+// along with all logs of its ancestors.
+type logPosition struct {
+	number uint64
+	hash   common.Hash
+	next   uint
+}
+
+// logResumer delivers the logs of a resumable subscription exactly once. On
+// every advance, the logs of blocks delivered but since reorged out of the
+// canonical chain are retracted, and the new canonical logs delivered.
+type logResumer struct {
+	backend Backend
+	crit    FilterCriteria
+	filter  *Filter // criteria matcher for individual blocks
+	pos     logPosition
+	notify  func(*types.Log) error
+}
+
+// newLogResumer creates a resumer delivering the logs matching the criteria
+// after the given cursor or, if it has no block hash, starting at the from block
+// of the criteria.
+func newLogResumer(ctx context.Context, backend Backend, crit FilterCriteria, cursor *LogCursor, rangeCap uint64, notify func(*types.Log) error) (*logResumer, error) {
+	r := &logResumer{
+		backend: backend,
+		crit:    crit,
+		filter:  newFilter(backend, crit.Addresses, crit.Topics),
+		notify:  notify,
+	}
+	head, err := backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errors.New("unknown head block")
+	}
+	if cursor.BlockHash != (common.Hash{}) {
+		header, err := backend.HeaderByHash(ctx, cursor.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Number.Uint64() != uint64(cursor.BlockNumber) {
+			return nil, errUnknownCursor
+		}
+		r.pos = logPosition{number: uint64(cursor.BlockNumber), hash: cursor.BlockHash, next: uint(cursor.Index) + 1}
+		if cursor.Removed {
+			r.pos.next = uint(cursor.Index)
+		}
+	} else {
+		if crit.FromBlock == nil || crit.FromBlock.Sign() < 0 {
+			return nil, errors.New("resuming logs requires a cursor block hash or a from block number")
+		}
+		from := crit.FromBlock.Uint64()
+		if from > head.Number.Uint64()+1 {
+			return nil, fmt.Errorf("from block #%d beyond head #%d", from, head.Number)
+		}
+		// Position the subscription right before the from block
+		number, next := from, uint(0)
+		if from > 0 {
+			number, next = from-1, endOfBlock
+		}
+		header, err := backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("unknown block #%d", number)
+		}
+		r.pos = logPosition{number: number, hash: header.Hash(), next: next}
+	}
+	if head.Number.Uint64() >= r.pos.number+rangeCap {
+		return nil, &limitExceededError{fmt.Sprintf("replay range too large (%d > %d)", head.Number.Uint64()-r.pos.number, rangeCap)}
+	}
+	return r, nil
+}
+
+// advance reconciles the position of the subscription with the canonical chain
+// and delivers all logs up to the current head.
+func (r *logResumer) advance(ctx context.Context) error {
+	for {
+		if err := r.retract(ctx); err != nil {
+			return err
+		}
+		done, err := r.deliver(ctx)
+		if err != nil || done {
+			return err
+		}
+		// A reorg was hit while walking the chain, start over
+	}
+}
+
+// retract rolls the position back onto the canonical chain, delivering the logs
+// already sent from reorged blocks again, flagged as removed.
+func (r *logResumer) retract(ctx context.Context) error {
+	for {
+		canonical, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(r.pos.number))
+		if err != nil {
+			return err
+		}
+		if canonical != nil && canonical.Hash() == r.pos.hash {
+			return nil
+		}
+		header, err := r.backend.HeaderByHash(ctx, r.pos.hash)
+		if err != nil {
+			return err
+		}
+		if header == nil || header.Number.Uint64() == 0 {
+			return errUnknownCursor
+		}
+		logs, err := r.filter.checkMatches(ctx, header)
+		if err != nil {
+			return err
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			if logs[i].Index >= r.pos.next {
+				continue
+			}
+			removed := *logs[i]
+			removed.Removed = true
+			if err := r.notify(&removed); err != nil {
+				return err
+			}
+		}
+		r.pos = logPosition{number: r.pos.number - 1, hash: header.ParentHash, next: endOfBlock}
+	}
+}
+
+// deliver sends the canonical logs after the current position up to the head,
+// reporting false if a reorg was detected midway.
+func (r *logResumer) deliver(ctx context.Context) (bool, error) {
+	// Finish off the block of the position if it was partially delivered
+	if r.pos.next != endOfBlock {
+		header, err := r.backend.HeaderByHash(ctx, r.pos.hash)
+		if err != nil {
+			return false, err
+		}
+		if header == nil {
+			return false, errUnknownCursor
+		}
+		logs, err := r.filter.checkMatches(ctx, header)
+		if err != nil {
+			return false, err
+		}
+		for _, log := range logs {
+			if log.Index < r.pos.next {
+				continue
+			}
+			if err := r.notify(log); err != nil {
+				return false, err
+			}
+		}
+		r.pos.next = endOfBlock
+	}
+	head, err := r.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if head == nil {
+		return false, errors.New("unknown head block")
+	}
+	// Replay old history in bulk, it is not expected to reorg anymore
+	if head.Number.Uint64() > r.pos.number+resumeConfirms {
+		end := head.Number.Uint64() - resumeConfirms
+
+		logs, err := NewRangeFilter(r.backend, int64(r.pos.number+1), int64(end), r.crit.Addresses, r.crit.Topics).Logs(ctx)
+		if err != nil {
+			return false, err
+		}
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(end))
+		if err != nil {
+			return false, err
+		}
+		if header == nil {
+			return false, fmt.Errorf("unknown block #%d", end)
+		}
+		for _, log := range logs {
+			if err := r.notify(log); err != nil {
+				return false, err
+			}
+		}
+		r.pos = logPosition{number: end, hash: header.Hash(), next: endOfBlock}
+	}
+	// Walk the recent blocks one by one, checking that they form a chain
+	for r.pos.number < head.Number.Uint64() {
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(r.pos.number+1))
+		if err != nil {
+			return false, err
+		}
+		if header == nil {
+			break // head rewound in the meantime
+		}
+		if header.ParentHash != r.pos.hash {
+			return false, nil
+		}
+		logs, err := r.filter.blockLogs(ctx, header)
+		if err != nil {
+			return false, err
+		}
+		for _, log := range logs {
+			if err := r.notify(log); err != nil {
+				return false, err
+			}
+		}
+		r.pos = logPosition{number: header.Number.Uint64(), hash: header.Hash(), next: endOfBlock}
+	}
+	return true, nil
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const sampleNumber = 3 // Number of transactions sampled in a block
@@ -44,6 +45,7 @@ type Config struct {
 type OracleBackend interface {
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
+	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	ChainConfig() *params.ChainConfig
 }
 
@@ -59,6 +61,8 @@ type Oracle struct {
 
 	checkBlocks int
 	percentile  int
+
+	historyCache *lru.Cache // Gas price distributions of recently processed blocks
 }
 
 // NewOracle returns a new gasprice oracle which can recommend suitable
@@ -83,12 +87,14 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxPrice = DefaultMaxPrice
 		log.Warn("Sanitizing invalid gasprice oracle price cap", "provided", params.MaxPrice, "updated", maxPrice)
 	}
+	cache, _ := lru.New(historyCacheSize)
 	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		maxPrice:    maxPrice,
-		checkBlocks: blocks,
-		percentile:  percent,
+		backend:      backend,
+		lastPrice:    params.Default,
+		maxPrice:     maxPrice,
+		checkBlocks:  blocks,
+		percentile:   percent,
+		historyCache: cache,
 	}
 }
 
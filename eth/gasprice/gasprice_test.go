@@ -51,6 +51,10 @@ func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber)
 	return b.chain.GetBlockByNumber(uint64(number)), nil
 }
 
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
@@ -116,3 +120,106 @@ func TestSuggestPrice(t *testing.T) {
 		t.Fatalf("Gas price mismatch, want %d, got %d", expect, got)
 	}
 }
+
+func TestGasPriceHistory(t *testing.T) {
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	// Every block has a single transaction, priced at its number in gwei
+	history, err := oracle.History(context.Background(), 4, 30, []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("Failed to retrieve gas price history: %v", err)
+	}
+	if history.OldestBlock != 27 {
+		t.Fatalf("Oldest block mismatch, want %d, got %d", 27, history.OldestBlock)
+	}
+	if len(history.Prices) != 4 || len(history.GasUsedRatios) != 4 {
+		t.Fatalf("History length mismatch, want 4 blocks, got %d prices, %d ratios", len(history.Prices), len(history.GasUsedRatios))
+	}
+	for i, prices := range history.Prices {
+		block := backend.chain.GetBlockByNumber(history.OldestBlock + uint64(i))
+		expect := big.NewInt(int64(block.NumberU64()) * params.GWei)
+		for j, price := range prices {
+			if price.Cmp(expect) != 0 {
+				t.Errorf("Block %d percentile %d price mismatch, want %d, got %d", block.NumberU64(), j, expect, price)
+			}
+		}
+		if ratio := float64(block.GasUsed()) / float64(block.GasLimit()); history.GasUsedRatios[i] != ratio {
+			t.Errorf("Block %d gas used ratio mismatch, want %f, got %f", block.NumberU64(), ratio, history.GasUsedRatios[i])
+		}
+	}
+	if expect := big.NewInt(params.GWei * int64(30)); history.Suggestion.Cmp(expect) != 0 {
+		t.Fatalf("Suggested price mismatch, want %d, got %d", expect, history.Suggestion)
+	}
+	if oracle.historyCache.Len() != 4 {
+		t.Fatalf("Cached block count mismatch, want %d, got %d", 4, oracle.historyCache.Len())
+	}
+	// Ranges are capped at the genesis, invalid requests are rejected
+	if history, err = oracle.History(context.Background(), 10, 2, nil); err != nil {
+		t.Fatalf("Failed to retrieve gas price history: %v", err)
+	}
+	if history.OldestBlock != 0 || len(history.GasUsedRatios) != 3 || history.Prices != nil {
+		t.Fatalf("Capped history mismatch: oldest %d, %d ratios, prices %v", history.OldestBlock, len(history.GasUsedRatios), history.Prices)
+	}
+	if _, err := oracle.History(context.Background(), 1, 100, nil); err != errRequestBeyondHead {
+		t.Fatalf("Error mismatch for future block, want %v, got %v", errRequestBeyondHead, err)
+	}
+	if _, err := oracle.History(context.Background(), 1, rpc.LatestBlockNumber, []float64{50, 10}); err != errInvalidPercentile {
+		t.Fatalf("Error mismatch for unordered percentiles, want %v, got %v", errInvalidPercentile, err)
+	}
+}
+
+// Tests that the price suggestion and the price history see the same prices in
+// the same blocks.
+func TestBlockPricesMatchSuggestionSamples(t *testing.T) {
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	head := backend.chain.CurrentBlock().NumberU64()
+	for number := uint64(1); number <= head; number++ {
+		result := make(chan getBlockPricesResult, 1)
+		signer := types.MakeSigner(params.TestChainConfig, new(big.Int).SetUint64(number))
+		oracle.getBlockPrices(context.Background(), signer, number, sampleNumber, result, nil)
+		sampled := <-result
+		if sampled.err != nil {
+			t.Fatalf("Block %d: failed to sample prices: %v", number, sampled.err)
+		}
+		prices, err := oracle.blockPrices(context.Background(), number)
+		if err != nil {
+			t.Fatalf("Block %d: failed to retrieve price distribution: %v", number, err)
+		}
+		if len(sampled.prices) != len(prices.txs) {
+			t.Fatalf("Block %d: price count mismatch, sampled %d, distribution %d", number, len(sampled.prices), len(prices.txs))
+		}
+		for i, price := range sampled.prices {
+			if price.Cmp(prices.txs[i].price) != 0 {
+				t.Errorf("Block %d: price %d mismatch, sampled %d, distribution %d", number, i, price, prices.txs[i].price)
+			}
+		}
+		if floor := prices.percentiles([]float64{0})[0]; len(sampled.prices) > 0 && floor.Cmp(sampled.prices[0]) != 0 {
+			t.Errorf("Block %d: lowest price mismatch, sampled %d, history %d", number, sampled.prices[0], floor)
+		}
+	}
+}
+
+func TestBlockPricePercentiles(t *testing.T) {
+	prices := &blockPrices{
+		gasUsed: 100,
+		txs: []txGasAndPrice{
+			{gasUsed: 10, price: big.NewInt(1)},
+			{gasUsed: 60, price: big.NewInt(2)},
+			{gasUsed: 30, price: big.NewInt(3)},
+		},
+	}
+	have := prices.percentiles([]float64{0, 10, 11, 50, 70, 71, 100})
+	want := []int64{1, 1, 2, 2, 2, 3, 3}
+	for i := range want {
+		if have[i].Int64() != want[i] {
+			t.Errorf("Percentile %d mismatch, want %d, got %d", i, want[i], have[i])
+		}
+	}
+	empty := (&blockPrices{}).percentiles([]float64{50})
+	if empty[0].Sign() != 0 {
+		t.Errorf("Empty block price mismatch, want 0, got %d", empty[0])
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxHistoryBlocks is the maximum number of blocks a single gas price history
+	// query may cover.
+	maxHistoryBlocks = 1024
+
+	// historyCacheSize is the number of processed blocks kept in memory to make
+	// repeated and overlapping history queries cheap.
+	historyCacheSize = 2048
+
+	// historyFetchers is the number of blocks processed concurrently for a gas
+	// price history query.
+	historyFetchers = 8
+)
+
+var (
+	errInvalidBlockCount = errors.New("block count must be positive")
+	errInvalidPercentile = errors.New("percentiles must be increasing and within [0, 100]")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// History is the gas price history of a range of consecutive blocks.
+type History struct {
+	OldestBlock   uint64       // Number of the first block of the range
+	Prices        [][]*big.Int // Requested percentiles of the gas prices of each block, weighted by gas used
+	GasUsedRatios []float64    // Ratio of the gas used to the gas limit of each block
+	Suggestion    *big.Int     // Gas price suggested by the oracle for new transactions
+}
+
+// blockPrices is the gas price distribution of a block: its transactions sorted
+// by gas price, along with the gas they used.
+//
+// It is not derived from getBlockPrices on purpose. The price suggestion samples
+// the few cheapest transactions of a block not sent by its miner, and so never
+// needs receipts, which light clients would have to retrieve from the network.
+// Weighting by gas requires the receipts of every transaction of the block. The
+// suggestion only caches its final result per head, while history queries span
+// up to maxHistoryBlocks blocks and overlap heavily, hence the per-block cache.
+type blockPrices struct {
+	gasUsed  uint64
+	gasLimit uint64
+	txs      []txGasAndPrice
+}
+
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// percentiles returns the given percentiles of the gas prices paid in the block,
+// weighted by the gas used by the transactions. Empty blocks report zero prices.
+func (p *blockPrices) percentiles(percentiles []float64) []*big.Int {
+	prices := make([]*big.Int, len(percentiles))
+	if len(p.txs) == 0 {
+		for i := range prices {
+			prices[i] = new(big.Int)
+		}
+		return prices
+	}
+	var (
+		index  int
+		sumGas = p.txs[0].gasUsed
+	)
+	for i, percentile := range percentiles {
+		threshold := uint64(float64(p.gasUsed) * percentile / 100)
+		for sumGas < threshold && index < len(p.txs)-1 {
+			index++
+			sumGas += p.txs[index].gasUsed
+		}
+		prices[i] = new(big.Int).Set(p.txs[index].price)
+	}
+	return prices
+}
+
+type getBlockHistoryResult struct {
+	index  int
+	prices *blockPrices
+	err    error
+}
+
+// History returns the gas price history of the given number of blocks up to
+// and including lastBlock: the requested percentiles of the gas prices paid in
+// each block, weighted by gas used, the gas used ratios of the blocks and the
+// current suggestion of the oracle. Processed blocks are cached, so repeated
+// queries over recent blocks are cheap.
+func (gpo *Oracle) History(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*History, error) {
+	if blocks < 1 {
+		return nil, errInvalidBlockCount
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return nil, errInvalidPercentile
+		}
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errRequestBeyondHead
+	}
+	last := head.Number.Uint64()
+	if blocks > maxHistoryBlocks {
+		blocks = maxHistoryBlocks
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	var (
+		oldest = last + 1 - uint64(blocks)
+		result = make(chan getBlockHistoryResult, blocks)
+		quit   = make(chan struct{})
+		sent   int
+		exp    int
+	)
+	defer close(quit)
+
+	history := &History{
+		OldestBlock:   oldest,
+		Prices:        make([][]*big.Int, blocks),
+		GasUsedRatios: make([]float64, blocks),
+	}
+	for ; sent < blocks && sent < historyFetchers; sent++ {
+		go gpo.getBlockHistory(ctx, oldest+uint64(sent), sent, result, quit)
+		exp++
+	}
+	for exp > 0 {
+		res := <-result
+		if res.err != nil {
+			return nil, res.err
+		}
+		exp--
+		if sent < blocks {
+			go gpo.getBlockHistory(ctx, oldest+uint64(sent), sent, result, quit)
+			sent++
+			exp++
+		}
+		if len(percentiles) > 0 {
+			history.Prices[res.index] = res.prices.percentiles(percentiles)
+		}
+		if res.prices.gasLimit > 0 {
+			history.GasUsedRatios[res.index] = float64(res.prices.gasUsed) / float64(res.prices.gasLimit)
+		}
+	}
+	if len(percentiles) == 0 {
+		history.Prices = nil
+	}
+	if history.Suggestion, err = gpo.SuggestPrice(ctx); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// getBlockHistory retrieves the gas price distribution of a given block and
+// sends it to the result channel.
+func (gpo *Oracle) getBlockHistory(ctx context.Context, number uint64, index int, result chan getBlockHistoryResult, quit chan struct{}) {
+	prices, err := gpo.blockPrices(ctx, number)
+	select {
+	case result <- getBlockHistoryResult{index, prices, err}:
+	case <-quit:
+	}
+}
+
+// blockPrices returns the gas price distribution of a given block, processing
+// the block only if it isn't cached yet.
+func (gpo *Oracle) blockPrices(ctx context.Context, number uint64) (*blockPrices, error) {
+	header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	if prices, ok := gpo.historyCache.Get(header.Hash()); ok {
+		return prices.(*blockPrices), nil
+	}
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	prices := &blockPrices{
+		gasUsed:  block.GasUsed(),
+		gasLimit: block.GasLimit(),
+	}
+	if txs := block.Transactions(); len(txs) > 0 {
+		receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if len(receipts) != len(txs) {
+			return nil, fmt.Errorf("receipts of block #%d unavailable", number)
+		}
+		prices.txs = make([]txGasAndPrice, len(txs))
+		for i, tx := range txs {
+			prices.txs[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+		}
+		sort.SliceStable(prices.txs, func(i, j int) bool {
+			return prices.txs[i].price.Cmp(prices.txs[j].price) < 0
+		})
+	}
+	gpo.historyCache.Add(block.Hash(), prices)
+	return prices, nil
+}
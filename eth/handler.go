@@ -65,6 +65,7 @@ type ProtocolManager struct {
 	forkFilter forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether fast sync should operate on top of the snap protocol
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	checkpointNumber uint64      // Block number for the sync progress validator to cross reference
@@ -137,6 +138,9 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		} else {
 			// If fast sync was requested and our database is empty, grant it
 			manager.fastSync = uint32(1)
+			if mode == downloader.SnapSync {
+				manager.snapSync = uint32(1)
+			}
 		}
 	}
 
@@ -215,7 +219,7 @@ func (pm *ProtocolManager) makeProtocol(version uint) p2p.Protocol {
 		Version: version,
 		Length:  length,
 		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
-			return pm.runPeer(pm.newPeer(int(version), p, rw, pm.txpool.Get))
+			return pm.runPeer(pm.newPeer(int(version), p, rw, pm.getPooledTx))
 		},
 		NodeInfo: func() interface{} {
 			return pm.NodeInfo()
@@ -778,7 +782,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested transaction, skipping if unknown to us
-			tx := pm.txpool.Get(hash)
+			tx := pm.getPooledTx(hash)
 			if tx == nil {
 				continue
 			}
@@ -851,13 +855,34 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 }
 
+// getPooledTx retrieves a transaction from the pool to be sent to a peer, hiding
+// the private transactions which must not leave the node.
+func (pm *ProtocolManager) getPooledTx(hash common.Hash) *types.Transaction {
+	if pm.txpool.IsPrivate(hash) {
+		return nil
+	}
+	return pm.txpool.Get(hash)
+}
+
+// publicTransactions filters the private transactions out of a batch.
+func (pm *ProtocolManager) publicTransactions(txs types.Transactions) types.Transactions {
+	public := make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		if !pm.txpool.IsPrivate(tx.Hash()) {
+			public = append(public, tx)
+		}
+	}
+	return public
+}
+
 // BroadcastTransactions will propagate a batch of transactions to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. Private transactions are never propagated.
 func (pm *ProtocolManager) BroadcastTransactions(txs types.Transactions, propagate bool) {
 	var (
 		txset = make(map[*peer][]common.Hash)
 		annos = make(map[*peer][]common.Hash)
 	)
+	txs = pm.publicTransactions(txs)
 	// Broadcast transactions to a batch of peers not knowing about it
 	if propagate {
 		for _, tx := range txs {
@@ -90,9 +90,10 @@ func newTestProtocolManagerMust(t *testing.T, mode downloader.SyncMode, blocks i
 
 // testTxPool is a fake, helper transaction pool for testing purposes
 type testTxPool struct {
-	txFeed event.Feed
-	pool   map[common.Hash]*types.Transaction // Hash map of collected transactions
-	added  chan<- []*types.Transaction        // Notification channel for new transactions
+	txFeed  event.Feed
+	pool    map[common.Hash]*types.Transaction // Hash map of collected transactions
+	private map[common.Hash]bool               // Set of transactions not to propagate
+	added   chan<- []*types.Transaction        // Notification channel for new transactions
 
 	lock sync.RWMutex // Protects the transaction pool
 }
@@ -115,6 +116,27 @@ func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
 	return p.pool[hash]
 }
 
+// IsPrivate returns an indicator whether a transaction was added privately.
+func (p *testTxPool) IsPrivate(hash common.Hash) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.private[hash]
+}
+
+// addPrivate appends a transaction to the pool, marking it as private.
+func (p *testTxPool) addPrivate(tx *types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.private == nil {
+		p.private = make(map[common.Hash]bool)
+	}
+	p.private[tx.Hash()] = true
+	p.pool[tx.Hash()] = tx
+	p.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+}
+
 // AddRemotes appends a batch of transactions to the pool, and notifies any
 // listeners if the addition channel is non nil
 func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
@@ -0,0 +1,120 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	logIndexThrottling = 100 * time.Millisecond
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an index from the log
+// addresses and topics to the blocks containing them, permitting filters on an
+// address or topic to skip straight to the relevant blocks.
+type LogIndexer struct {
+	size    uint64            // section size to generate the index for
+	db      ethdb.Database    // database instance to write index data and metadata into
+	terms   map[string][]byte // block bitsets of the current section by address or topic
+	section uint64            // Section is the section number being processed currently
+	head    common.Hash       // Head is the hash of the last header processed
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *core.ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (b *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.terms, b.section, b.head = make(map[string][]byte), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the log addresses and
+// topics of a new header's receipts into the index.
+func (b *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	b.head = header.Hash()
+	if header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(b.db, b.head, number)
+	if receipts == nil {
+		return fmt.Errorf("missing receipts for block #%d [%x]", number, b.head)
+	}
+	offset := number - b.section*b.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			b.add(log.Address.Bytes(), offset)
+			for _, topic := range log.Topics {
+				b.add(topic.Bytes(), offset)
+			}
+		}
+	}
+	return nil
+}
+
+// add marks the block at the given section offset in the bitset of a term.
+func (b *LogIndexer) add(term []byte, offset uint64) {
+	bits, ok := b.terms[string(term)]
+	if !ok {
+		bits = make([]byte, b.size/8)
+		b.terms[string(term)] = bits
+	}
+	bits[offset/8] |= 1 << (7 - offset%8)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (b *LogIndexer) Commit() error {
+	batch := b.db.NewBatch()
+	for term, bits := range b.terms {
+		rawdb.WriteLogIndex(batch, []byte(term), b.section, b.head, bitutil.CompressBytes(bits))
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/bitutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the log indexer marks the blocks containing logs of an address or
+// topic in the bitset of the section.
+func TestLogIndexer(t *testing.T) {
+	const size = 64
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		addr   = common.BytesToAddress([]byte("addr"))
+		topic1 = common.BytesToHash([]byte("topic1"))
+		topic2 = common.BytesToHash([]byte("topic2"))
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 2*size, func(i int, gen *core.BlockGen) {
+		var logs []*types.Log
+		switch i {
+		case 2, size + 2:
+			logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic1}}}
+		case size + 9:
+			logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic1, topic2}}}
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = logs
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the second section and check the generated bitsets
+	indexer := &LogIndexer{db: db, size: size}
+	if err := indexer.Reset(context.Background(), 1, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for number := uint64(size); number < 2*size; number++ {
+		if err := indexer.Process(context.Background(), rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)); err != nil {
+			t.Fatalf("failed to process block %d: %v", number, err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	head := rawdb.ReadCanonicalHash(db, 2*size-1)
+	tests := []struct {
+		term   []byte
+		blocks []uint64
+	}{
+		{addr.Bytes(), []uint64{size + 3, size + 10}},
+		{topic1.Bytes(), []uint64{size + 3, size + 10}},
+		{topic2.Bytes(), []uint64{size + 10}},
+		{common.BytesToHash([]byte("other")).Bytes(), nil},
+	}
+	for i, tt := range tests {
+		want := make([]byte, size/8)
+		for _, number := range tt.blocks {
+			want[(number-size)/8] |= 1 << (7 - (number-size)%8)
+		}
+		have := make([]byte, size/8)
+		if blob := rawdb.ReadLogIndex(db, tt.term, 1, head); blob != nil {
+			var err error
+			if have, err = bitutil.DecompressBytes(blob, size/8); err != nil {
+				t.Fatalf("test %d: failed to decompress bitset: %v", i, err)
+			}
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("test %d: bitset mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
@@ -111,6 +111,10 @@ type txPool interface {
 	// tx hash.
 	Get(hash common.Hash) *types.Transaction
 
+	// IsPrivate returns an indicator whether a pooled transaction
+	// must not be propagated to the network.
+	IsPrivate(hash common.Hash) bool
+
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
@@ -414,6 +414,71 @@ loop:
 	}
 }
 
+// Tests that private transactions are neither propagated nor announced to peers,
+// neither when added nor when syncing the pool to a new peer.
+func TestPrivateTransactionPropagation(t *testing.T) {
+	pmFetcher, _ := newTestProtocolManagerMust(t, downloader.FastSync, 0, nil, nil)
+	defer pmFetcher.Stop()
+	pmSender, _ := newTestProtocolManagerMust(t, downloader.FastSync, 1024, nil, nil)
+	defer pmSender.Stop()
+
+	pool := pmSender.txpool.(*testTxPool)
+	private := map[common.Hash]bool{}
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	pool.addPrivate(tx)
+	private[tx.Hash()] = true
+
+	// Sync up the two peers
+	io1, io2 := p2p.MsgPipe()
+
+	go pmSender.handle(pmSender.newPeer(65, p2p.NewPeer(enode.ID{}, "sender", nil), io2, pmSender.getPooledTx))
+	go pmFetcher.handle(pmFetcher.newPeer(65, p2p.NewPeer(enode.ID{}, "fetcher", nil), io1, pmFetcher.txpool.Get))
+
+	time.Sleep(250 * time.Millisecond)
+	pmFetcher.doSync(peerToSyncOp(downloader.FullSync, pmFetcher.peers.BestPeer()))
+	atomic.StoreUint32(&pmFetcher.acceptTxs, 1)
+
+	newTxs := make(chan core.NewTxsEvent, 1024)
+	sub := pmFetcher.txpool.SubscribeNewTxsEvent(newTxs)
+	defer sub.Unsubscribe()
+
+	// Interleave private and public transactions
+	var public []*types.Transaction
+	for nonce := uint64(1); nonce < 64; nonce++ {
+		tx := newTestTransaction(testAccount, nonce, 0)
+		if nonce%2 == 0 {
+			pool.addPrivate(tx)
+			private[tx.Hash()] = true
+		} else {
+			public = append(public, tx)
+		}
+	}
+	pool.AddRemotes(public)
+
+	var got int
+	timeout := time.NewTimer(time.Second)
+	defer timeout.Stop()
+	for got < len(public) {
+		select {
+		case ev := <-newTxs:
+			for _, tx := range ev.Txs {
+				if private[tx.Hash()] {
+					t.Fatalf("private transaction %d propagated", tx.Nonce())
+				}
+			}
+			got += len(ev.Txs)
+		case <-timeout.C:
+			t.Fatalf("Failed to retrieve all public transactions: have %d, want %d", got, len(public))
+		}
+	}
+	select {
+	case ev := <-newTxs:
+		t.Fatalf("unexpected transactions propagated: %d", len(ev.Txs))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,2084 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+const (
+	// maxRequestSize is the maximum number of bytes to request from a remote peer.
+	maxRequestSize = 512 * 1024
+
+	// maxStorageSetFetch is the maximum number of contracts to request the
+	// storage of in a single query. If this number is too low, we're not filling
+	// responses fully and waste round trip times. If it's too high, we're capping
+	// responses and waste bandwidth.
+	maxStorageSetFetch = maxRequestSize / 1024 // Estimate of storage slots per contract
+
+	// maxCodeRequestCount is the maximum number of bytecode blobs to request in a
+	// single query. If this number is too low, we're not filling responses fully
+	// and waste round trip times. If it's too high, we're capping responses and
+	// waste bandwidth.
+	//
+	// Depoyed bytecodes are currently capped at 24KB, so the minimum request
+	// size should be maxRequestSize / 24K. Assuming that most contracts do not
+	// come close to that, requesting 4x should be a good approximation.
+	maxCodeRequestCount = maxRequestSize / (24 * 1024) * 4
+
+	// maxTrieRequestCount is the maximum number of trie node blobs to request in
+	// a single query. If this number is too low, we're not filling responses fully
+	// and waste round trip times. If it's too high, we're capping responses and
+	// waste bandwidth.
+	maxTrieRequestCount = 512
+
+	// requestTimeout is the maximum time a peer is allowed to spend on serving
+	// a single network request.
+	requestTimeout = 10 * time.Second
+
+	// accountConcurrency is the number of chunks to split the account trie into
+	// to allow concurrent retrievals.
+	accountConcurrency = 16
+)
+
+var (
+	// errCancelled is returned if a sync is aborted by the caller.
+	errCancelled = errors.New("sync cancelled")
+
+	// errUnexpectedResponse is returned if a peer delivers more data than it was
+	// asked for.
+	errUnexpectedResponse = errors.New("unexpected response")
+)
+
+// SyncPeer abstracts out the methods required for a peer to be synced against
+// with the goal of allowing the construction of mock peers without the full
+// blown networking.
+type SyncPeer interface {
+	// ID retrieves the peer's unique identifier.
+	ID() string
+
+	// RequestAccountRange fetches a batch of accounts rooted in a specific
+	// account trie, starting with the origin.
+	RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error
+
+	// RequestStorageRanges fetches a batch of storage slots belonging to one or
+	// more accounts. If slots from only one account is requested, an origin
+	// marker may also be used to retrieve from there.
+	RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error
+
+	// RequestByteCodes fetches a batch of bytecodes by hash.
+	RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error
+
+	// RequestTrieNodes fetches a batch of account or storage trie nodes rooted
+	// in a specific state trie.
+	RequestTrieNodes(id uint64, root common.Hash, paths []TrieNodePathSet, bytes uint64) error
+
+	// Log retrieves the peer's own contextual logger.
+	Log() log.Logger
+}
+
+// accountRequest tracks a pending account range request to ensure responses are
+// to actual requests and to validate any security constraints.
+type accountRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	cancel  chan struct{} // Channel to track sync cancellation
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the request was dropped
+
+	origin common.Hash // First account requested to allow continuation checks
+	limit  common.Hash // Last account requested to allow non-overlapping chunking
+
+	task *accountTask // Task which this request is filling (only access fields through the runloop!!)
+}
+
+// accountResponse is an already Merkle-verified remote response to an account
+// range request. It contains the subtrie for the requested account range and
+// the database that's going to be filled with the internal nodes on commit.
+type accountResponse struct {
+	task *accountTask // Task which this request is filling
+
+	hashes   []common.Hash    // Account hashes in the returned range
+	accounts []*state.Account // Expanded accounts in the returned range
+
+	cont bool // Whether the account range has a continuation
+}
+
+// bytecodeRequest tracks a pending bytecode request to ensure responses are to
+// actual requests and to validate any security constraints.
+type bytecodeRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	cancel  chan struct{} // Channel to track sync cancellation
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the request was dropped
+
+	hashes []common.Hash // Bytecode hashes to validate responses
+	task   *accountTask  // Task which this request is filling (only access fields through the runloop!!)
+}
+
+// bytecodeResponse is an already verified remote response to a bytecode request.
+type bytecodeResponse struct {
+	task *accountTask // Task which this request is filling
+
+	hashes []common.Hash // Hashes of the bytecode to avoid double hashing
+	codes  [][]byte      // Actual bytecodes to store into the database (nil = missing)
+}
+
+// storageRequest tracks a pending storage ranges request to ensure responses are
+// to actual requests and to validate any security constraints.
+//
+// Note, this request and response structure is used for both small contracts
+// (served as many accounts per request) as well as large contracts (served in
+// multiple consecutive chunks, one per request).
+type storageRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	cancel  chan struct{} // Channel to track sync cancellation
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the request was dropped
+
+	accounts []common.Hash // Account hashes to validate responses
+	roots    []common.Hash // Storage roots to validate responses
+
+	origin common.Hash // Start of the first account range to allow continuation checks
+
+	mainTask *accountTask // Task which this response belongs to (only access fields through the runloop!!)
+	subTask  *storageTask // Task which this response is filling (only access fields through the runloop!!)
+}
+
+// storageResponse is an already Merkle-verified remote response to a storage
+// range request. It contains the subtries for the requested storage ranges.
+type storageResponse struct {
+	mainTask *accountTask // Task which this response belongs to
+	subTask  *storageTask // Task which this response is filling
+
+	accounts []common.Hash // Account hashes requested, may be only partially filled
+	roots    []common.Hash // Storage roots requested, may be only partially filled
+
+	hashes [][]common.Hash // Storage slot hashes in the returned range
+	slots  [][][]byte      // Storage slot values in the returned range
+
+	cont bool // Whether the last storage range has a continuation
+}
+
+// trienodeHealRequest tracks a pending state trie request to ensure responses
+// are to actual requests and to validate any security constraints.
+type trienodeHealRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	cancel  chan struct{} // Channel to track sync cancellation
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the request was dropped
+
+	hashes []common.Hash   // Trie node hashes to validate responses
+	paths  []trie.SyncPath // Trie node paths requested for rescheduling
+
+	task *healTask // Task which this request is filling (only access fields through the runloop!!)
+}
+
+// trienodeHealResponse is an already verified remote response to a trie node request.
+type trienodeHealResponse struct {
+	task *healTask // Task which this request is filling
+
+	hashes []common.Hash   // Hashes of the trie nodes to avoid double hashing
+	paths  []trie.SyncPath // Trie node paths requested for rescheduling missing ones
+	nodes  [][]byte        // Actual trie nodes to store into the database (nil = missing)
+}
+
+// bytecodeHealRequest tracks a pending bytecode request to ensure responses are to
+// actual requests and to validate any security constraints.
+type bytecodeHealRequest struct {
+	peer string // Peer to which this request is assigned
+	id   uint64 // Request ID of this request
+
+	cancel  chan struct{} // Channel to track sync cancellation
+	timeout *time.Timer   // Timer to track delivery timeout
+	stale   chan struct{} // Channel to signal the request was dropped
+
+	hashes []common.Hash // Bytecode hashes to validate responses
+	task   *healTask     // Task which this request is filling (only access fields through the runloop!!)
+}
+
+// bytecodeHealResponse is an already verified remote response to a bytecode request.
+type bytecodeHealResponse struct {
+	task *healTask // Task which this request is filling
+
+	hashes []common.Hash // Hashes of the bytecode to avoid double hashing
+	codes  [][]byte      // Actual bytecodes to store into the database (nil = missing)
+}
+
+// accountTask is the sync task for a chunk of the account snapshot.
+type accountTask struct {
+	next     common.Hash                  // Next account to sync in this interval
+	last     common.Hash                  // Last account to sync in this interval
+	subTasks map[common.Hash]*storageTask // Storage intervals needing fetching for large contracts
+
+	req  *accountRequest  // Pending request to fill this task
+	res  *accountResponse // Validated response filling this task
+	pend int              // Number of pending subtasks for this round
+
+	needCode  []bool // Flags whether the filling accounts need code retrieval
+	needState []bool // Flags whether the filling accounts need storage retrieval
+	needHeal  []bool // Flags whether the filling accounts's state was chunked and need healing
+
+	codeTasks  map[common.Hash]struct{}    // Code hashes that need retrieval
+	stateTasks map[common.Hash]common.Hash // Account hashes->roots that need full state retrieval
+
+	genTrie *trie.StackTrie // Node generator from the accounts retrieved in order
+
+	done bool // Flag whether the task can be removed
+}
+
+// storageTask is the sync task for a large contract whose storage does not fit
+// into a single response and needs to be retrieved in multiple round trips.
+type storageTask struct {
+	next common.Hash     // Next storage slot to sync in this contract
+	root common.Hash     // Storage root hash for this contract
+	gen  *trie.StackTrie // Node generator from the slots retrieved in order
+}
+
+// healTask represents the sync task for healing the snap-synced chunk boundaries.
+type healTask struct {
+	scheduler *trie.Sync // State trie sync scheduler defining the tasks
+
+	trieTasks map[common.Hash]trie.SyncPath // Set of trie node tasks currently queued for retrieval
+	codeTasks map[common.Hash]struct{}      // Set of byte code tasks currently queued for retrieval
+}
+
+// syncWriter is a database writer that forwards all written trie nodes and
+// bytecodes into a batch, marking them in the sync bloom filter too.
+type syncWriter struct {
+	batch ethdb.Batch
+	bloom *trie.SyncBloom
+	size  common.StorageSize // Total number of bytes written through the writer
+}
+
+// Put inserts the given value into the batch and the key into the bloom.
+func (w *syncWriter) Put(key []byte, value []byte) error {
+	if w.bloom != nil {
+		w.bloom.Add(key)
+	}
+	w.size += common.StorageSize(len(key) + len(value))
+	return w.batch.Put(key, value)
+}
+
+// Delete removes the key from the batch.
+func (w *syncWriter) Delete(key []byte) error {
+	return w.batch.Delete(key)
+}
+
+// Syncer is an Ethereum account and storage trie syncer based on snapshots and
+// the snap protocol. Its purpose is to download all the accounts and storage
+// slots from remote peers and reassemble chunks of the state trie, on top of
+// which a state sync can be run to fix any gaps / overlaps.
+//
+// Every network request has a variety of failure events:
+//   - The peer disconnects after task assignment, failing to send the request
+//   - The peer disconnects after sending the request, before delivering on it
+//   - The peer remains connected, but does not deliver a response in time
+//   - The peer delivers a stale response after a previous timeout
+//   - The peer delivers a refusal to serve the requested state
+type Syncer struct {
+	db     ethdb.KeyValueStore // Database to store the trie nodes into (and dedup)
+	bloom  *trie.SyncBloom     // Bloom filter to deduplicate nodes for state fixup
+	batch  ethdb.Batch         // Batch accumulating the generated trie nodes and codes
+	writer *syncWriter         // Writer forwarding generated data into the batch and bloom
+
+	root    common.Hash    // Current state trie root being synced
+	tasks   []*accountTask // Current account task set being synced
+	snapped bool           // Flag to signal that snap phase is done
+	healer  *healTask      // Current state healing task being executed
+	update  chan struct{}  // Notification channel for possible sync progression
+
+	peers    map[string]SyncPeer // Currently active peers to download from
+	peerJoin chan string         // Event channel to react on newly joining peers
+	peerDrop chan string         // Event channel to react on dropping peers
+
+	// Request tracking during syncing phase
+	statelessPeers map[string]struct{} // Peers that failed to deliver state data
+	busyPeers      map[string]struct{} // Peers that have a request in flight
+
+	accountReqs      map[uint64]*accountRequest      // Account requests currently running
+	bytecodeReqs     map[uint64]*bytecodeRequest     // Bytecode requests currently running
+	storageReqs      map[uint64]*storageRequest      // Storage requests currently running
+	trienodeHealReqs map[uint64]*trienodeHealRequest // Trie node requests currently running
+	bytecodeHealReqs map[uint64]*bytecodeHealRequest // Bytecode requests currently running
+
+	accountReqFails      chan *accountRequest      // Failed account range requests to revert
+	bytecodeReqFails     chan *bytecodeRequest     // Failed bytecode requests to revert
+	storageReqFails      chan *storageRequest      // Failed storage requests to revert
+	trienodeHealReqFails chan *trienodeHealRequest // Failed trienode requests to revert
+	bytecodeHealReqFails chan *bytecodeHealRequest // Failed bytecode requests to revert
+
+	accountResps      chan *accountResponse      // Account sub-tries to integrate into the database
+	bytecodeResps     chan *bytecodeResponse     // Bytecodes to integrate into the database
+	storageResps      chan *storageResponse      // Storage sub-tries to integrate into the database
+	trienodeHealResps chan *trienodeHealResponse // Trie nodes to integrate into the database
+	bytecodeHealResps chan *bytecodeHealResponse // Bytecodes to integrate into the database
+
+	accountSynced  uint64             // Number of accounts processed
+	accountBytes   common.StorageSize // Number of account trie bytes persisted to disk
+	bytecodeSynced uint64             // Number of bytecodes downloaded
+	bytecodeBytes  common.StorageSize // Number of bytecode bytes downloaded
+	storageSynced  uint64             // Number of storage slots downloaded
+	storageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
+
+	trienodeHealSynced uint64             // Number of state trie nodes downloaded
+	trienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
+	bytecodeHealSynced uint64             // Number of bytecodes downloaded
+	bytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+
+	startTime time.Time // Time instance when snapshot sync started
+	logTime   time.Time // Time instance when status was last reported
+
+	lock sync.RWMutex // Protects fields that can change outside of sync (peers, reqs, root)
+}
+
+// NewSyncer creates a new snapshot syncer to download the Ethereum state over the
+// snap protocol.
+func NewSyncer(db ethdb.KeyValueStore, bloom *trie.SyncBloom) *Syncer {
+	batch := db.NewBatch()
+	return &Syncer{
+		db:     db,
+		bloom:  bloom,
+		batch:  batch,
+		writer: &syncWriter{batch: batch, bloom: bloom},
+
+		peers:    make(map[string]SyncPeer),
+		peerJoin: make(chan string, 1),
+		peerDrop: make(chan string, 1),
+		update:   make(chan struct{}, 1),
+
+		statelessPeers: make(map[string]struct{}),
+		busyPeers:      make(map[string]struct{}),
+
+		accountReqs:      make(map[uint64]*accountRequest),
+		bytecodeReqs:     make(map[uint64]*bytecodeRequest),
+		storageReqs:      make(map[uint64]*storageRequest),
+		trienodeHealReqs: make(map[uint64]*trienodeHealRequest),
+		bytecodeHealReqs: make(map[uint64]*bytecodeHealRequest),
+
+		accountReqFails:      make(chan *accountRequest),
+		bytecodeReqFails:     make(chan *bytecodeRequest),
+		storageReqFails:      make(chan *storageRequest),
+		trienodeHealReqFails: make(chan *trienodeHealRequest),
+		bytecodeHealReqFails: make(chan *bytecodeHealRequest),
+
+		accountResps:      make(chan *accountResponse),
+		bytecodeResps:     make(chan *bytecodeResponse),
+		storageResps:      make(chan *storageResponse),
+		trienodeHealResps: make(chan *trienodeHealResponse),
+		bytecodeHealResps: make(chan *bytecodeHealResponse),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *Syncer) Register(peer SyncPeer) error {
+	// Make sure the peer is not registered yet
+	id := peer.ID()
+
+	s.lock.Lock()
+	if _, ok := s.peers[id]; ok {
+		log.Error("Snap peer already registered", "id", id)
+
+		s.lock.Unlock()
+		return errors.New("already registered")
+	}
+	s.peers[id] = peer
+	s.lock.Unlock()
+
+	// Notify any active syncs that a new peer can be assigned data
+	select {
+	case s.peerJoin <- id:
+	default:
+	}
+	return nil
+}
+
+// Unregister removes a data source from the syncer's peerset.
+func (s *Syncer) Unregister(id string) error {
+	// Remove all traces of the peer from the registry
+	s.lock.Lock()
+	if _, ok := s.peers[id]; !ok {
+		log.Error("Snap peer not registered", "id", id)
+
+		s.lock.Unlock()
+		return errors.New("not registered")
+	}
+	delete(s.peers, id)
+
+	// Remove status markers, even if no sync is running
+	delete(s.statelessPeers, id)
+	delete(s.busyPeers, id)
+
+	// Gather all the requests assigned to the peer, they will be reverted by
+	// the runloop (or dropped wholesale if no sync is running)
+	var (
+		accountReqs  []*accountRequest
+		bytecodeReqs []*bytecodeRequest
+		storageReqs  []*storageRequest
+		trienodeReqs []*trienodeHealRequest
+		codeHealReqs []*bytecodeHealRequest
+	)
+	for _, req := range s.accountReqs {
+		if req.peer == id {
+			accountReqs = append(accountReqs, req)
+		}
+	}
+	for _, req := range s.bytecodeReqs {
+		if req.peer == id {
+			bytecodeReqs = append(bytecodeReqs, req)
+		}
+	}
+	for _, req := range s.storageReqs {
+		if req.peer == id {
+			storageReqs = append(storageReqs, req)
+		}
+	}
+	for _, req := range s.trienodeHealReqs {
+		if req.peer == id {
+			trienodeReqs = append(trienodeReqs, req)
+		}
+	}
+	for _, req := range s.bytecodeHealReqs {
+		if req.peer == id {
+			codeHealReqs = append(codeHealReqs, req)
+		}
+	}
+	s.lock.Unlock()
+
+	// Revert all the requests outside of the lock, the runloop needs it
+	for _, req := range accountReqs {
+		s.scheduleRevertAccountRequest(req)
+	}
+	for _, req := range bytecodeReqs {
+		s.scheduleRevertBytecodeRequest(req)
+	}
+	for _, req := range storageReqs {
+		s.scheduleRevertStorageRequest(req)
+	}
+	for _, req := range trienodeReqs {
+		s.scheduleRevertTrienodeHealRequest(req)
+	}
+	for _, req := range codeHealReqs {
+		s.scheduleRevertBytecodeHealRequest(req)
+	}
+	// Notify any active syncs that pending requests need to be reverted
+	select {
+	case s.peerDrop <- id:
+	default:
+	}
+	return nil
+}
+
+// Sync starts (or resumes a previous) sync cycle to iterate over an state trie
+// with the given root and reconstruct the nodes based on the snapshot leaves.
+// Previously downloaded segments will not be redownloaded or fixed, rather any
+// errors will be healed after the leaves are fully accumulated.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	// Move the trie root from any previous value, revert stateless markers for
+	// any peers and initialize the syncer if it was not yet run
+	s.lock.Lock()
+	s.statelessPeers = make(map[string]struct{})
+	if len(s.tasks) == 0 || s.root != root {
+		s.loadSyncStatus(root)
+	}
+	s.root = root
+	s.healer = &healTask{
+		scheduler: state.NewStateSync(root, s.db, s.bloom),
+		trieTasks: make(map[common.Hash]trie.SyncPath),
+		codeTasks: make(map[common.Hash]struct{}),
+	}
+	s.lock.Unlock()
+
+	if s.startTime == (time.Time{}) {
+		s.startTime = time.Now()
+	}
+	// Whether sync completed or not, disregard any future packets
+	defer func() {
+		log.Debug("Terminating snapshot sync cycle", "root", root)
+		s.lock.Lock()
+		for _, req := range s.accountReqs {
+			req.timeout.Stop()
+			close(req.stale)
+		}
+		for _, req := range s.storageReqs {
+			req.timeout.Stop()
+			close(req.stale)
+		}
+		for _, req := range s.bytecodeReqs {
+			req.timeout.Stop()
+			close(req.stale)
+		}
+		for _, req := range s.trienodeHealReqs {
+			req.timeout.Stop()
+			close(req.stale)
+		}
+		for _, req := range s.bytecodeHealReqs {
+			req.timeout.Stop()
+			close(req.stale)
+		}
+		s.accountReqs = make(map[uint64]*accountRequest)
+		s.storageReqs = make(map[uint64]*storageRequest)
+		s.bytecodeReqs = make(map[uint64]*bytecodeRequest)
+		s.trienodeHealReqs = make(map[uint64]*trienodeHealRequest)
+		s.bytecodeHealReqs = make(map[uint64]*bytecodeHealRequest)
+		s.busyPeers = make(map[string]struct{})
+		s.lock.Unlock()
+
+		// Flush out any generated data, it's content addressed so can be safely
+		// reused by any subsequent sync cycle
+		s.commitBatch(true)
+	}()
+	log.Debug("Starting snapshot sync cycle", "root", root)
+
+	for {
+		// Remove all completed tasks and terminate sync if everything's done
+		s.cleanAccountTasks()
+		if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
+			s.reportSyncProgress(true)
+			return nil
+		}
+		// Assign all the data retrieval tasks to any free peers
+		s.assignAccountTasks(cancel)
+		s.assignBytecodeTasks(cancel)
+		s.assignStorageTasks(cancel)
+
+		if len(s.tasks) == 0 {
+			// Sync phase done, run heal phase
+			s.assignTrienodeHealTasks(cancel)
+			s.assignBytecodeHealTasks(cancel)
+		}
+		// Wait for something to happen
+		select {
+		case <-s.update:
+			// Something happened (new peer, delivery, timeout), recheck tasks
+		case <-s.peerJoin:
+			// A new peer joined, try to schedule it new tasks
+		case <-s.peerDrop:
+			// A peer dropped, its requests are reverted through the fail channels
+		case <-cancel:
+			return errCancelled
+
+		case req := <-s.accountReqFails:
+			s.revertAccountRequest(req)
+		case req := <-s.bytecodeReqFails:
+			s.revertBytecodeRequest(req)
+		case req := <-s.storageReqFails:
+			s.revertStorageRequest(req)
+		case req := <-s.trienodeHealReqFails:
+			s.revertTrienodeHealRequest(req)
+		case req := <-s.bytecodeHealReqFails:
+			s.revertBytecodeHealRequest(req)
+
+		case res := <-s.accountResps:
+			s.processAccountResponse(res)
+		case res := <-s.bytecodeResps:
+			s.processBytecodeResponse(res)
+		case res := <-s.storageResps:
+			s.processStorageResponse(res)
+		case res := <-s.trienodeHealResps:
+			s.processTrienodeHealResponse(res)
+		case res := <-s.bytecodeHealResps:
+			s.processBytecodeHealResponse(res)
+		}
+		// Report stats if something meaningful happened
+		s.reportSyncProgress(false)
+	}
+}
+
+// loadSyncStatus (re)initializes the account tasks for a new state root. Any
+// previously completed account chunks are retained (the nodes generated by them
+// are content addressed, the healer will fix up any differences), but partially
+// filled ranges are dropped as the storage and code data they reference might
+// no longer be available on the network under the new root.
+func (s *Syncer) loadSyncStatus(root common.Hash) {
+	if len(s.tasks) > 0 {
+		for _, task := range s.tasks {
+			task.req, task.res = nil, nil
+			task.pend = 0
+			task.needCode, task.needState, task.needHeal = nil, nil, nil
+			task.codeTasks = make(map[common.Hash]struct{})
+			task.stateTasks = make(map[common.Hash]common.Hash)
+			task.subTasks = make(map[common.Hash]*storageTask)
+		}
+		log.Debug("Resuming snapshot sync with new root", "old", s.root, "new", root, "tasks", len(s.tasks))
+		return
+	}
+	// If the account ranges were already fully retrieved, only healing remains
+	if s.snapped {
+		log.Debug("Snapshot sync already completed, healing new root", "old", s.root, "new", root)
+		return
+	}
+	// No previous sync cycle was run, start a fresh sync by chunking up the
+	// account range and scheduling them for retrieval.
+	var next common.Hash
+	step := new(big.Int).Sub(
+		new(big.Int).Div(
+			new(big.Int).Exp(common.Big2, common.Big256, nil),
+			big.NewInt(accountConcurrency),
+		), common.Big1,
+	)
+	for i := 0; i < accountConcurrency; i++ {
+		last := common.BigToHash(new(big.Int).Add(next.Big(), step))
+		if i == accountConcurrency-1 {
+			// Make sure we don't overflow if the step is not a proper divisor
+			last = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		}
+		s.tasks = append(s.tasks, &accountTask{
+			next:       next,
+			last:       last,
+			subTasks:   make(map[common.Hash]*storageTask),
+			codeTasks:  make(map[common.Hash]struct{}),
+			stateTasks: make(map[common.Hash]common.Hash),
+			genTrie:    trie.NewStackTrie(s.writer),
+		})
+		log.Debug("Created account sync task", "from", next, "last", last)
+		next = common.BigToHash(new(big.Int).Add(last.Big(), common.Big1))
+	}
+}
+
+// cleanAccountTasks removes account range retrieval tasks that have already been
+// completed.
+func (s *Syncer) cleanAccountTasks() {
+	for i := 0; i < len(s.tasks); i++ {
+		if s.tasks[i].done {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			i--
+		}
+	}
+	// If all the account tasks finished, make sure everything's on disk before
+	// the healer starts checking the database for gaps
+	if len(s.tasks) == 0 {
+		s.snapped = true
+		s.commitBatch(true)
+	}
+}
+
+// commitBatch flushes the generated trie nodes and bytecodes to disk, either
+// unconditionally, or if the batch grew large enough.
+func (s *Syncer) commitBatch(force bool) {
+	if s.batch.ValueSize() == 0 || (!force && s.batch.ValueSize() < ethdb.IdealBatchSize) {
+		return
+	}
+	if err := s.batch.Write(); err != nil {
+		log.Crit("Failed to persist snap sync data", "err", err)
+	}
+	s.batch.Reset()
+}
+
+// idlePeer returns a random peer that's neither busy serving a request, nor has
+// been marked as unable to serve the current state. The method assumes the
+// lock is held.
+func (s *Syncer) idlePeer() SyncPeer {
+	for id, peer := range s.peers {
+		if _, ok := s.busyPeers[id]; ok {
+			continue
+		}
+		if _, ok := s.statelessPeers[id]; ok {
+			continue
+		}
+		return peer
+	}
+	return nil
+}
+
+// nextRequestID generates a request id that's not yet in use by any pending
+// request. The method assumes the lock is held.
+func (s *Syncer) nextRequestID() uint64 {
+	for {
+		reqid := uint64(rand.Int63())
+		if _, ok := s.accountReqs[reqid]; ok {
+			continue
+		}
+		if _, ok := s.bytecodeReqs[reqid]; ok {
+			continue
+		}
+		if _, ok := s.storageReqs[reqid]; ok {
+			continue
+		}
+		if _, ok := s.trienodeHealReqs[reqid]; ok {
+			continue
+		}
+		if _, ok := s.bytecodeHealReqs[reqid]; ok {
+			continue
+		}
+		return reqid
+	}
+}
+
+// assignAccountTasks attempts to match idle peers to pending account range
+// retrievals.
+func (s *Syncer) assignAccountTasks(cancel chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		// Skip any tasks already filling
+		if task.req != nil || task.res != nil {
+			continue
+		}
+		// Task pending retrieval, try to find an idle peer. If no such peer
+		// exists, we probably assigned tasks for all (or they are stateless).
+		// Abort the entire assignment mechanism.
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		// Generate a unique request and track it
+		req := &accountRequest{
+			peer:   peer.ID(),
+			id:     s.nextRequestID(),
+			cancel: cancel,
+			stale:  make(chan struct{}),
+			origin: task.next,
+			limit:  task.last,
+			task:   task,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Account range request timed out", "reqid", req.id)
+			s.scheduleRevertAccountRequest(req)
+		})
+		s.accountReqs[req.id] = req
+		s.busyPeers[req.peer] = struct{}{}
+
+		// Inject the request into the task to block further assignments
+		task.req = req
+
+		go func(root common.Hash) {
+			// Attempt to send the remote request and revert if it fails
+			if err := peer.RequestAccountRange(req.id, root, req.origin, req.limit, maxRequestSize); err != nil {
+				peer.Log().Debug("Failed to request account range", "err", err)
+				s.scheduleRevertAccountRequest(req)
+			}
+		}(s.root)
+	}
+}
+
+// assignBytecodeTasks attempts to match idle peers to pending code retrievals.
+func (s *Syncer) assignBytecodeTasks(cancel chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		// Skip any tasks not in the bytecode retrieval phase
+		if task.res == nil || len(task.codeTasks) == 0 {
+			continue
+		}
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		hashes := make([]common.Hash, 0, maxCodeRequestCount)
+		for hash := range task.codeTasks {
+			delete(task.codeTasks, hash)
+			hashes = append(hashes, hash)
+			if len(hashes) >= maxCodeRequestCount {
+				break
+			}
+		}
+		req := &bytecodeRequest{
+			peer:   peer.ID(),
+			id:     s.nextRequestID(),
+			cancel: cancel,
+			stale:  make(chan struct{}),
+			hashes: hashes,
+			task:   task,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Bytecode request timed out", "reqid", req.id)
+			s.scheduleRevertBytecodeRequest(req)
+		})
+		s.bytecodeReqs[req.id] = req
+		s.busyPeers[req.peer] = struct{}{}
+
+		go func() {
+			// Attempt to send the remote request and revert if it fails
+			if err := peer.RequestByteCodes(req.id, hashes, maxRequestSize); err != nil {
+				log.Debug("Failed to request bytecodes", "err", err)
+				s.scheduleRevertBytecodeRequest(req)
+			}
+		}()
+	}
+}
+
+// assignStorageTasks attempts to match idle peers to pending storage range
+// retrievals.
+func (s *Syncer) assignStorageTasks(cancel chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		// Skip any tasks not in the storage retrieval phase
+		if task.res == nil || len(task.stateTasks) == 0 {
+			continue
+		}
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		// Large contracts are retrieved alone, continuing from where the last
+		// chunk left off. Small contracts are batched up as many per request
+		// as allowed.
+		var (
+			accounts = make([]common.Hash, 0, maxStorageSetFetch)
+			roots    = make([]common.Hash, 0, maxStorageSetFetch)
+			subtask  *storageTask
+		)
+		for account, root := range task.stateTasks {
+			if st, ok := task.subTasks[account]; ok {
+				if len(accounts) > 0 {
+					continue
+				}
+				delete(task.stateTasks, account)
+				accounts, roots, subtask = append(accounts, account), append(roots, root), st
+				break
+			}
+			delete(task.stateTasks, account)
+			accounts, roots = append(accounts, account), append(roots, root)
+
+			if len(accounts) >= maxStorageSetFetch {
+				break
+			}
+		}
+		req := &storageRequest{
+			peer:     peer.ID(),
+			id:       s.nextRequestID(),
+			cancel:   cancel,
+			stale:    make(chan struct{}),
+			accounts: accounts,
+			roots:    roots,
+			mainTask: task,
+			subTask:  subtask,
+		}
+		if subtask != nil {
+			req.origin = subtask.next
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Storage request timed out", "reqid", req.id)
+			s.scheduleRevertStorageRequest(req)
+		})
+		s.storageReqs[req.id] = req
+		s.busyPeers[req.peer] = struct{}{}
+
+		go func(root common.Hash) {
+			// Attempt to send the remote request and revert if it fails
+			var origin []byte
+			if subtask != nil {
+				origin = req.origin[:]
+			}
+			if err := peer.RequestStorageRanges(req.id, root, accounts, origin, nil, maxRequestSize); err != nil {
+				log.Debug("Failed to request storage", "err", err)
+				s.scheduleRevertStorageRequest(req)
+			}
+		}(s.root)
+	}
+}
+
+// assignTrienodeHealTasks attempts to match idle peers to trie node requests to
+// heal any trie errors caused by the snap sync's chunked retrieval model.
+func (s *Syncer) assignTrienodeHealTasks(cancel chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for {
+		// Top up the pending tasks from the healer's scheduler
+		s.fillHealTasks()
+		if len(s.healer.trieTasks) == 0 {
+			return
+		}
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		var (
+			hashes   = make([]common.Hash, 0, maxTrieRequestCount)
+			paths    = make([]trie.SyncPath, 0, maxTrieRequestCount)
+			pathsets = make([]TrieNodePathSet, 0, maxTrieRequestCount)
+		)
+		for hash, path := range s.healer.trieTasks {
+			delete(s.healer.trieTasks, hash)
+
+			hashes = append(hashes, hash)
+			paths = append(paths, path)
+			pathsets = append(pathsets, [][]byte(path))
+
+			if len(hashes) >= maxTrieRequestCount {
+				break
+			}
+		}
+		req := &trienodeHealRequest{
+			peer:   peer.ID(),
+			id:     s.nextRequestID(),
+			cancel: cancel,
+			stale:  make(chan struct{}),
+			hashes: hashes,
+			paths:  paths,
+			task:   s.healer,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Trienode heal request timed out", "reqid", req.id)
+			s.scheduleRevertTrienodeHealRequest(req)
+		})
+		s.trienodeHealReqs[req.id] = req
+		s.busyPeers[req.peer] = struct{}{}
+
+		go func(root common.Hash) {
+			// Attempt to send the remote request and revert if it fails
+			if err := peer.RequestTrieNodes(req.id, root, pathsets, maxRequestSize); err != nil {
+				log.Debug("Failed to request trienode healers", "err", err)
+				s.scheduleRevertTrienodeHealRequest(req)
+			}
+		}(s.root)
+	}
+}
+
+// assignBytecodeHealTasks attempts to match idle peers to bytecode requests to
+// heal any trie errors caused by the snap sync's chunked retrieval model.
+func (s *Syncer) assignBytecodeHealTasks(cancel chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for {
+		// Top up the pending tasks from the healer's scheduler
+		s.fillHealTasks()
+		if len(s.healer.codeTasks) == 0 {
+			return
+		}
+		peer := s.idlePeer()
+		if peer == nil {
+			return
+		}
+		hashes := make([]common.Hash, 0, maxCodeRequestCount)
+		for hash := range s.healer.codeTasks {
+			delete(s.healer.codeTasks, hash)
+
+			hashes = append(hashes, hash)
+			if len(hashes) >= maxCodeRequestCount {
+				break
+			}
+		}
+		req := &bytecodeHealRequest{
+			peer:   peer.ID(),
+			id:     s.nextRequestID(),
+			cancel: cancel,
+			stale:  make(chan struct{}),
+			hashes: hashes,
+			task:   s.healer,
+		}
+		req.timeout = time.AfterFunc(requestTimeout, func() {
+			peer.Log().Debug("Bytecode heal request timed out", "reqid", req.id)
+			s.scheduleRevertBytecodeHealRequest(req)
+		})
+		s.bytecodeHealReqs[req.id] = req
+		s.busyPeers[req.peer] = struct{}{}
+
+		go func() {
+			// Attempt to send the remote request and revert if it fails
+			if err := peer.RequestByteCodes(req.id, hashes, maxRequestSize); err != nil {
+				log.Debug("Failed to request bytecode healers", "err", err)
+				s.scheduleRevertBytecodeHealRequest(req)
+			}
+		}()
+	}
+}
+
+// fillHealTasks tops up the healer's trie node and bytecode task queues from
+// the state sync scheduler. The method assumes the lock is held.
+func (s *Syncer) fillHealTasks() {
+	have := len(s.healer.trieTasks) + len(s.healer.codeTasks)
+	want := maxTrieRequestCount + maxCodeRequestCount
+	if have >= want {
+		return
+	}
+	nodes, paths, codes := s.healer.scheduler.Missing(want - have)
+	for i, hash := range nodes {
+		s.healer.trieTasks[hash] = paths[i]
+	}
+	for _, hash := range codes {
+		s.healer.codeTasks[hash] = struct{}{}
+	}
+}
+
+// scheduleRevertAccountRequest asks the event loop to clean up an account range
+// request and return all failed retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) scheduleRevertAccountRequest(req *accountRequest) {
+	select {
+	case s.accountReqFails <- req:
+		// Sync event loop notified
+	case <-req.cancel:
+		// Sync cycle got cancelled
+	case <-req.stale:
+		// Request already reverted
+	}
+}
+
+// revertAccountRequest cleans up an account range request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+//
+// Note, this needs to run on the event runloop thread to reschedule to idle peers.
+// On peer threads, use scheduleRevertAccountRequest.
+func (s *Syncer) revertAccountRequest(req *accountRequest) {
+	log.Debug("Reverting account request", "peer", req.peer, "reqid", req.id)
+	select {
+	case <-req.stale:
+		log.Trace("Account request already reverted", "peer", req.peer, "reqid", req.id)
+		return
+	default:
+	}
+	close(req.stale)
+
+	// Remove the request from the tracked set
+	s.lock.Lock()
+	delete(s.accountReqs, req.id)
+	delete(s.busyPeers, req.peer)
+	s.lock.Unlock()
+
+	// If there's a timeout timer still running, abort it and mark the account
+	// task as not-pending, ready for resheduling
+	req.timeout.Stop()
+	if req.task.req == req {
+		req.task.req = nil
+	}
+}
+
+// scheduleRevertBytecodeRequest asks the event loop to clean up a bytecode request
+// and return all failed retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) scheduleRevertBytecodeRequest(req *bytecodeRequest) {
+	select {
+	case s.bytecodeReqFails <- req:
+		// Sync event loop notified
+	case <-req.cancel:
+		// Sync cycle got cancelled
+	case <-req.stale:
+		// Request already reverted
+	}
+}
+
+// revertBytecodeRequest cleans up a bytecode request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+//
+// Note, this needs to run on the event runloop thread to reschedule to idle peers.
+// On peer threads, use scheduleRevertBytecodeRequest.
+func (s *Syncer) revertBytecodeRequest(req *bytecodeRequest) {
+	log.Debug("Reverting bytecode request", "peer", req.peer)
+	select {
+	case <-req.stale:
+		log.Trace("Bytecode request already reverted", "peer", req.peer, "reqid", req.id)
+		return
+	default:
+	}
+	close(req.stale)
+
+	// Remove the request from the tracked set
+	s.lock.Lock()
+	delete(s.bytecodeReqs, req.id)
+	delete(s.busyPeers, req.peer)
+	s.lock.Unlock()
+
+	// If there's a timeout timer still running, abort it and mark the code
+	// retrievals as not-pending, ready for resheduling
+	req.timeout.Stop()
+	for _, hash := range req.hashes {
+		req.task.codeTasks[hash] = struct{}{}
+	}
+}
+
+// scheduleRevertStorageRequest asks the event loop to clean up a storage range
+// request and return all failed retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) scheduleRevertStorageRequest(req *storageRequest) {
+	select {
+	case s.storageReqFails <- req:
+		// Sync event loop notified
+	case <-req.cancel:
+		// Sync cycle got cancelled
+	case <-req.stale:
+		// Request already reverted
+	}
+}
+
+// revertStorageRequest cleans up a storage range request and returns all failed
+// retrieval tasks to the scheduler for reassignment.
+//
+// Note, this needs to run on the event runloop thread to reschedule to idle peers.
+// On peer threads, use scheduleRevertStorageRequest.
+func (s *Syncer) revertStorageRequest(req *storageRequest) {
+	log.Debug("Reverting storage request", "peer", req.peer)
+	select {
+	case <-req.stale:
+		log.Trace("Storage request already reverted", "peer", req.peer, "reqid", req.id)
+		return
+	default:
+	}
+	close(req.stale)
+
+	// Remove the request from the tracked set
+	s.lock.Lock()
+	delete(s.storageReqs, req.id)
+	delete(s.busyPeers, req.peer)
+	s.lock.Unlock()
+
+	// If there's a timeout timer still running, abort it and mark the storage
+	// task as not-pending, ready for resheduling
+	req.timeout.Stop()
+	for i, account := range req.accounts {
+		req.mainTask.stateTasks[account] = req.roots[i]
+	}
+}
+
+// scheduleRevertTrienodeHealRequest asks the event loop to clean up a trienode heal
+// request and return all failed retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) scheduleRevertTrienodeHealRequest(req *trienodeHealRequest) {
+	select {
+	case s.trienodeHealReqFails <- req:
+		// Sync event loop notified
+	case <-req.cancel:
+		// Sync cycle got cancelled
+	case <-req.stale:
+		// Request already reverted
+	}
+}
+
+// revertTrienodeHealRequest cleans up a trienode heal request and returns all
+// failed retrieval tasks to the scheduler for reassignment.
+//
+// Note, this needs to run on the event runloop thread to reschedule to idle peers.
+// On peer threads, use scheduleRevertTrienodeHealRequest.
+func (s *Syncer) revertTrienodeHealRequest(req *trienodeHealRequest) {
+	log.Debug("Reverting trienode heal request", "peer", req.peer)
+	select {
+	case <-req.stale:
+		log.Trace("Trienode heal request already reverted", "peer", req.peer, "reqid", req.id)
+		return
+	default:
+	}
+	close(req.stale)
+
+	// Remove the request from the tracked set
+	s.lock.Lock()
+	delete(s.trienodeHealReqs, req.id)
+	delete(s.busyPeers, req.peer)
+	s.lock.Unlock()
+
+	// If there's a timeout timer still running, abort it and mark the trie node
+	// retrievals as not-pending, ready for resheduling
+	req.timeout.Stop()
+	for i, hash := range req.hashes {
+		req.task.trieTasks[hash] = req.paths[i]
+	}
+}
+
+// scheduleRevertBytecodeHealRequest asks the event loop to clean up a bytecode heal
+// request and return all failed retrieval tasks to the scheduler for reassignment.
+func (s *Syncer) scheduleRevertBytecodeHealRequest(req *bytecodeHealRequest) {
+	select {
+	case s.bytecodeHealReqFails <- req:
+		// Sync event loop notified
+	case <-req.cancel:
+		// Sync cycle got cancelled
+	case <-req.stale:
+		// Request already reverted
+	}
+}
+
+// revertBytecodeHealRequest cleans up a bytecode heal request and returns all
+// failed retrieval tasks to the scheduler for reassignment.
+//
+// Note, this needs to run on the event runloop thread to reschedule to idle peers.
+// On peer threads, use scheduleRevertBytecodeHealRequest.
+func (s *Syncer) revertBytecodeHealRequest(req *bytecodeHealRequest) {
+	log.Debug("Reverting bytecode heal request", "peer", req.peer)
+	select {
+	case <-req.stale:
+		log.Trace("Bytecode heal request already reverted", "peer", req.peer, "reqid", req.id)
+		return
+	default:
+	}
+	close(req.stale)
+
+	// Remove the request from the tracked set
+	s.lock.Lock()
+	delete(s.bytecodeHealReqs, req.id)
+	delete(s.busyPeers, req.peer)
+	s.lock.Unlock()
+
+	// If there's a timeout timer still running, abort it and mark the code
+	// retrievals as not-pending, ready for resheduling
+	req.timeout.Stop()
+	for _, hash := range req.hashes {
+		req.task.codeTasks[hash] = struct{}{}
+	}
+}
+
+// processAccountResponse integrates an already validated account range response
+// into the account tasks.
+func (s *Syncer) processAccountResponse(res *accountResponse) {
+	// Switch the task from pending to filling
+	res.task.req = nil
+	res.task.res = res
+
+	// Ensure that the response doesn't overflow into the subsequent task
+	for i, hash := range res.hashes {
+		if bytes.Compare(hash[:], res.task.last[:]) > 0 {
+			// Chunk overflown, cut off excess and mark the range as completed
+			res.hashes = res.hashes[:i]
+			res.accounts = res.accounts[:i]
+			res.cont = false
+			break
+		}
+	}
+	// Iterate over all the accounts and assemble which ones need further sub-
+	// filling before the entire account range can be persisted.
+	res.task.needCode = make([]bool, len(res.accounts))
+	res.task.needState = make([]bool, len(res.accounts))
+	res.task.needHeal = make([]bool, len(res.accounts))
+
+	res.task.codeTasks = make(map[common.Hash]struct{})
+	res.task.stateTasks = make(map[common.Hash]common.Hash)
+	res.task.subTasks = make(map[common.Hash]*storageTask)
+
+	res.task.pend = 0
+	for i, account := range res.accounts {
+		// Check if the account is a contract with an unknown code
+		if !bytes.Equal(account.CodeHash, emptyCode[:]) {
+			if code := common.BytesToHash(account.CodeHash); !rawdb.HasCode(s.db, code) {
+				res.task.codeTasks[code] = struct{}{}
+				res.task.needCode[i] = true
+				res.task.pend++
+			}
+		}
+		// Check if the account is a contract with an unknown storage trie
+		if account.Root != emptyRoot {
+			if !rawdb.HasTrieNode(s.db, account.Root) {
+				res.task.stateTasks[res.hashes[i]] = account.Root
+				res.task.needState[i] = true
+				res.task.pend++
+			}
+		}
+	}
+	// If the account range contained no contracts, or all have been fully filled
+	// beforehand, short circuit storage filling and forward to the next task
+	if res.task.pend == 0 {
+		s.forwardAccountTask(res.task)
+		return
+	}
+	// Some accounts are incomplete, leave as is for the storage and contract
+	// task assigners to pick up and fill.
+}
+
+// processBytecodeResponse integrates an already validated bytecode response
+// into the account tasks.
+func (s *Syncer) processBytecodeResponse(res *bytecodeResponse) {
+	var (
+		codes uint64
+		size  common.StorageSize
+	)
+	for i, hash := range res.hashes {
+		code := res.codes[i]
+
+		// If the bytecode was not delivered, reschedule it
+		if code == nil {
+			res.task.codeTasks[hash] = struct{}{}
+			continue
+		}
+		// Code was delivered, mark it not needed any more
+		for j, account := range res.task.res.accounts {
+			if res.task.needCode[j] && hash == common.BytesToHash(account.CodeHash) {
+				res.task.needCode[j] = false
+				res.task.pend--
+			}
+		}
+		// Push the bytecode into a database batch
+		s.bytecodeSynced++
+		s.bytecodeBytes += common.StorageSize(len(code))
+
+		codes++
+		size += common.StorageSize(len(code))
+
+		rawdb.WriteCode(s.batch, hash, code)
+		if s.bloom != nil {
+			s.bloom.Add(hash[:])
+		}
+	}
+	s.commitBatch(false)
+	log.Debug("Persisted set of bytecodes", "count", codes, "bytes", size)
+
+	// If this delivery completed the last pending task, forward the account task
+	// to the next chunk
+	if res.task.pend == 0 {
+		s.forwardAccountTask(res.task)
+		return
+	}
+	// Some accounts are still incomplete, leave as is for the storage and contract
+	// task assigners to pick up and fill.
+}
+
+// processStorageResponse integrates an already validated storage response
+// into the account tasks.
+func (s *Syncer) processStorageResponse(res *storageResponse) {
+	// Reschedule any accounts the peer did not deliver
+	for i := len(res.hashes); i < len(res.accounts); i++ {
+		res.mainTask.stateTasks[res.accounts[i]] = res.roots[i]
+	}
+	// Iterate over all the delivered accounts and either persist the full
+	// storage trie, or continue chunking the large ones
+	written := s.writer.size
+	for i, account := range res.accounts[:len(res.hashes)] {
+		// Retrieve (or create) the node generator for this storage trie
+		var gen *trie.StackTrie
+		if i == 0 && res.subTask != nil {
+			gen = res.subTask.gen
+		}
+		if i == len(res.hashes)-1 && res.cont {
+			// The storage of this contract was not fully delivered, track it as
+			// a large contract and continue the retrieval in a subsequent round
+			if gen == nil {
+				res.subTask = &storageTask{
+					root: res.roots[i],
+					gen:  trie.NewStackTrie(s.writer),
+				}
+				res.mainTask.subTasks[account] = res.subTask
+				gen = res.subTask.gen
+			}
+			for j, hash := range res.hashes[i] {
+				gen.Update(hash[:], res.slots[i][j])
+			}
+			s.storageSynced += uint64(len(res.hashes[i]))
+
+			res.subTask.next = incHash(res.hashes[i][len(res.hashes[i])-1])
+			res.mainTask.stateTasks[account] = res.roots[i]
+			continue
+		}
+		// The storage of this contract was fully delivered, generate all the
+		// remaining nodes and check that the result matches the account
+		if gen == nil {
+			gen = trie.NewStackTrie(s.writer)
+		}
+		for j, hash := range res.hashes[i] {
+			gen.Update(hash[:], res.slots[i][j])
+		}
+		s.storageSynced += uint64(len(res.hashes[i]))
+
+		root, err := gen.Commit()
+		healing := err != nil || root != res.roots[i]
+		if healing {
+			log.Debug("Storage trie needs healing", "account", account, "want", res.roots[i], "have", root, "err", err)
+		}
+		delete(res.mainTask.subTasks, account)
+
+		// Mark the state as complete for all accounts sharing the root
+		for j, hash := range res.mainTask.res.hashes {
+			if hash == account && res.mainTask.needState[j] {
+				res.mainTask.needState[j] = false
+				res.mainTask.needHeal[j] = healing
+				res.mainTask.pend--
+			}
+		}
+	}
+	s.storageBytes += s.writer.size - written
+	s.commitBatch(false)
+
+	// If this delivery completed the last pending task, forward the account task
+	// to the next chunk
+	if res.mainTask.pend == 0 {
+		s.forwardAccountTask(res.mainTask)
+		return
+	}
+	// Some accounts are still incomplete, leave as is for the storage and contract
+	// task assigners to pick up and fill.
+}
+
+// processTrienodeHealResponse integrates an already validated trienode response
+// into the healer tasks.
+func (s *Syncer) processTrienodeHealResponse(res *trienodeHealResponse) {
+	for i, hash := range res.hashes {
+		node := res.nodes[i]
+
+		// If the trie node was not delivered, reschedule it
+		if node == nil {
+			res.task.trieTasks[hash] = res.paths[i]
+			continue
+		}
+		// Push the trie node into the state syncer
+		s.trienodeHealSynced++
+		s.trienodeHealBytes += common.StorageSize(len(node))
+
+		err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: node})
+		switch err {
+		case nil:
+		case trie.ErrAlreadyProcessed:
+		case trie.ErrNotRequested:
+		default:
+			log.Error("Invalid trienode processed", "hash", hash, "err", err)
+		}
+	}
+	batch := s.db.NewBatch()
+	if err := s.healer.scheduler.Commit(batch); err != nil {
+		log.Error("Failed to commit healing data", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist healing data", "err", err)
+	}
+	log.Debug("Persisted set of healing data", "bytes", common.StorageSize(batch.ValueSize()))
+}
+
+// processBytecodeHealResponse integrates an already validated bytecode response
+// into the healer tasks.
+func (s *Syncer) processBytecodeHealResponse(res *bytecodeHealResponse) {
+	for i, hash := range res.hashes {
+		node := res.codes[i]
+
+		// If the trie node was not delivered, reschedule it
+		if node == nil {
+			res.task.codeTasks[hash] = struct{}{}
+			continue
+		}
+		// Push the trie node into the state syncer
+		s.bytecodeHealSynced++
+		s.bytecodeHealBytes += common.StorageSize(len(node))
+
+		err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: node})
+		switch err {
+		case nil:
+		case trie.ErrAlreadyProcessed:
+		case trie.ErrNotRequested:
+		default:
+			log.Error("Invalid bytecode processed", "hash", hash, "err", err)
+		}
+	}
+	batch := s.db.NewBatch()
+	if err := s.healer.scheduler.Commit(batch); err != nil {
+		log.Error("Failed to commit healing data", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist healing data", "err", err)
+	}
+	log.Debug("Persisted set of healing data", "bytes", common.StorageSize(batch.ValueSize()))
+}
+
+// forwardAccountTask takes a filled account task and persists anything available
+// into the database, after which it forwards the next account marker so that the
+// task's next chunk may be filled.
+func (s *Syncer) forwardAccountTask(task *accountTask) {
+	// Remove any pending delivery
+	res := task.res
+	if res == nil {
+		return // nothing to forward
+	}
+	task.res = nil
+
+	// Feed all the accounts into the node generator. Accounts whose storage
+	// failed to validate are skipped: their parent nodes will not match the
+	// canonical trie and the healer will redownload them with their storage.
+	written := s.writer.size
+	for i, hash := range res.hashes {
+		if task.needCode[i] || task.needState[i] {
+			break
+		}
+		if !task.needHeal[i] {
+			blob, err := rlp.EncodeToBytes(res.accounts[i])
+			if err != nil {
+				panic(err) // Really shouldn't ever happen
+			}
+			task.genTrie.Update(hash[:], blob)
+		}
+		s.accountSynced++
+		task.next = incHash(hash)
+	}
+	// If the chunk's range was fully retrieved, flush the boundary nodes and
+	// mark the task done
+	if !res.cont {
+		if _, err := task.genTrie.Commit(); err != nil {
+			log.Error("Failed to commit account chunk", "err", err)
+		}
+		task.done = true
+	}
+	s.accountBytes += s.writer.size - written
+	s.commitBatch(false)
+
+	log.Debug("Persisted range of accounts", "accounts", len(res.accounts), "next", task.next, "done", task.done)
+}
+
+// OnAccounts is a callback method to invoke when a range of accounts are
+// received from a remote peer.
+func (s *Syncer) OnAccounts(peer SyncPeer, id uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) error {
+	size := common.StorageSize(len(hashes) * common.HashLength)
+	for _, account := range accounts {
+		size += common.StorageSize(len(account))
+	}
+	for _, node := range proof {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering range of accounts", "hashes", len(hashes), "accounts", len(accounts), "proofs", len(proof), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	if _, ok := s.peers[peer.ID()]; ok {
+		delete(s.busyPeers, peer.ID())
+	}
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+	// Ensure the response is for a valid request
+	req, ok := s.accountReqs[id]
+	if !ok {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected account range packet")
+		s.lock.Unlock()
+		return nil
+	}
+	delete(s.accountReqs, id)
+
+	// Clean up the request timeout timer, we'll see how to proceed further based
+	// on the actual delivered content
+	if !req.timeout.Stop() {
+		// The timeout is already triggered, and this request will be reverted+rescheduled
+		s.lock.Unlock()
+		return nil
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For account range queries that means the state being
+	// retrieved was either already pruned remotely, or the peer is not yet
+	// synced to our head.
+	if len(hashes) == 0 && len(accounts) == 0 && len(proof) == 0 {
+		logger.Debug("Peer rejected account range request", "root", s.root)
+		s.statelessPeers[peer.ID()] = struct{}{}
+		s.lock.Unlock()
+
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertAccountRequest(req)
+		return nil
+	}
+	root := s.root
+	s.lock.Unlock()
+
+	// Reconstruct a partial trie from the response and verify it
+	keys := make([][]byte, len(hashes))
+	for i, key := range hashes {
+		keys[i] = common.CopyBytes(key[:])
+	}
+	var proofdb ethdb.KeyValueReader
+	if len(proof) > 0 {
+		nodes := make(light.NodeList, len(proof))
+		for i, node := range proof {
+			nodes[i] = node
+		}
+		proofdb = nodes.NodeSet()
+	}
+	var end []byte
+	if len(keys) > 0 {
+		end = keys[len(keys)-1]
+	}
+	err, cont := trie.VerifyRangeProof(root, req.origin[:], end, keys, accounts, proofdb)
+	if err != nil {
+		logger.Warn("Account range failed proof", "err", err)
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertAccountRequest(req)
+		return err
+	}
+	accs := make([]*state.Account, len(accounts))
+	for i, account := range accounts {
+		acc := new(state.Account)
+		if err := rlp.DecodeBytes(account, acc); err != nil {
+			panic(err) // We created these blobs, we must be able to decode them
+		}
+		accs[i] = acc
+	}
+	response := &accountResponse{
+		task:     req.task,
+		hashes:   hashes,
+		accounts: accs,
+		cont:     cont,
+	}
+	select {
+	case s.accountResps <- response:
+	case <-req.cancel:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// OnByteCodes is a callback method to invoke when a batch of contract
+// bytes codes are received from a remote peer.
+func (s *Syncer) OnByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error {
+	s.lock.RLock()
+	_, healing := s.bytecodeHealReqs[id]
+	s.lock.RUnlock()
+
+	if healing {
+		return s.onHealByteCodes(peer, id, bytecodes)
+	}
+	return s.onByteCodes(peer, id, bytecodes)
+}
+
+// onByteCodes is a callback method to invoke when a batch of contract
+// bytes codes are received from a remote peer in the syncing phase.
+func (s *Syncer) onByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error {
+	var size common.StorageSize
+	for _, code := range bytecodes {
+		size += common.StorageSize(len(code))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering set of bytecodes", "bytecodes", len(bytecodes), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	if _, ok := s.peers[peer.ID()]; ok {
+		delete(s.busyPeers, peer.ID())
+	}
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+	// Ensure the response is for a valid request
+	req, ok := s.bytecodeReqs[id]
+	if !ok {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected bytecode packet")
+		s.lock.Unlock()
+		return nil
+	}
+	delete(s.bytecodeReqs, id)
+
+	// Clean up the request timeout timer, we'll see how to proceed further based
+	// on the actual delivered content
+	if !req.timeout.Stop() {
+		// The timeout is already triggered, and this request will be reverted+rescheduled
+		s.lock.Unlock()
+		return nil
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For bytecode range queries that means the peer is not
+	// yet synced.
+	if len(bytecodes) == 0 {
+		logger.Debug("Peer rejected bytecode request")
+		s.statelessPeers[peer.ID()] = struct{}{}
+		s.lock.Unlock()
+
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertBytecodeRequest(req)
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Cross reference the requested bytecodes with the response to find gaps
+	// that the serving node is missing
+	codes, err := matchHashes(req.hashes, bytecodes)
+	if err != nil {
+		logger.Warn("Unexpected bytecodes", "err", err)
+		s.scheduleRevertBytecodeRequest(req)
+		return err
+	}
+	// Response validated, send it to the scheduler for filling
+	response := &bytecodeResponse{
+		task:   req.task,
+		hashes: req.hashes,
+		codes:  codes,
+	}
+	select {
+	case s.bytecodeResps <- response:
+	case <-req.cancel:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// OnStorage is a callback method to invoke when ranges of storage slots
+// are received from a remote peer.
+func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) error {
+	// Gather some trace stats to aid in debugging issues
+	var (
+		hashCount int
+		slotCount int
+		size      common.StorageSize
+	)
+	for _, hashset := range hashes {
+		size += common.StorageSize(common.HashLength * len(hashset))
+		hashCount += len(hashset)
+	}
+	for _, slot := range slots {
+		slotCount += len(slot)
+		for _, node := range slot {
+			size += common.StorageSize(len(node))
+		}
+	}
+	for _, node := range proof {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering ranges of storage slots", "accounts", len(hashes), "hashes", hashCount, "slots", slotCount, "proofs", len(proof), "size", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	if _, ok := s.peers[peer.ID()]; ok {
+		delete(s.busyPeers, peer.ID())
+	}
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+	// Ensure the response is for a valid request
+	req, ok := s.storageReqs[id]
+	if !ok {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected storage ranges packet")
+		s.lock.Unlock()
+		return nil
+	}
+	delete(s.storageReqs, id)
+
+	// Clean up the request timeout timer, we'll see how to proceed further based
+	// on the actual delivered content
+	if !req.timeout.Stop() {
+		// The timeout is already triggered, and this request will be reverted+rescheduled
+		s.lock.Unlock()
+		return nil
+	}
+	// Reject the response if the hash sets and slot sets don't match, or if the
+	// peer sent more data than requested.
+	if len(hashes) != len(slots) {
+		s.lock.Unlock()
+		s.scheduleRevertStorageRequest(req) // reschedule request
+		logger.Warn("Hash and slot set size mismatch", "hashset", len(hashes), "slotset", len(slots))
+		return errors.New("hash and slot set size mismatch")
+	}
+	if len(hashes) > len(req.accounts) {
+		s.lock.Unlock()
+		s.scheduleRevertStorageRequest(req) // reschedule request
+		logger.Warn("Hash set larger than requested", "hashset", len(hashes), "requested", len(req.accounts))
+		return errors.New("hash set larger than requested")
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For storage range queries that means the state being
+	// retrieved was either already pruned remotely, or the peer is not yet
+	// synced to our head.
+	if len(hashes) == 0 {
+		logger.Debug("Peer rejected storage request")
+		s.statelessPeers[peer.ID()] = struct{}{}
+		s.lock.Unlock()
+
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertStorageRequest(req)
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Reconstruct the partial tries from the response and verify them
+	var cont bool
+	for i := 0; i < len(hashes); i++ {
+		// Convert the keys and proofs into an internal format
+		keys := make([][]byte, len(hashes[i]))
+		for j, key := range hashes[i] {
+			keys[j] = common.CopyBytes(key[:])
+		}
+		// If the storage slots are complete (all but the last set, or the last
+		// one without a proof), the range must be the entire storage trie
+		if i < len(hashes)-1 || len(proof) == 0 {
+			if err, _ := trie.VerifyRangeProof(req.roots[i], nil, nil, keys, slots[i], nil); err != nil {
+				logger.Warn("Storage slots failed proof", "err", err)
+				s.scheduleRevertStorageRequest(req) // reschedule request
+				return err
+			}
+			continue
+		}
+		// A proof was attached, the response is only partial, check that the
+		// slots are continuous and more slots are available
+		nodes := make(light.NodeList, len(proof))
+		for j, node := range proof {
+			nodes[j] = node
+		}
+		var origin common.Hash
+		if i == 0 {
+			origin = req.origin
+		}
+		var end []byte
+		if len(keys) > 0 {
+			end = keys[len(keys)-1]
+		}
+		var err error
+		err, cont = trie.VerifyRangeProof(req.roots[i], origin[:], end, keys, slots[i], nodes.NodeSet())
+		if err != nil {
+			logger.Warn("Storage range failed proof", "err", err)
+			s.scheduleRevertStorageRequest(req) // reschedule request
+			return err
+		}
+	}
+	// Partial tries reconstructed, send them to the scheduler for storage filling
+	response := &storageResponse{
+		mainTask: req.mainTask,
+		subTask:  req.subTask,
+		accounts: req.accounts,
+		roots:    req.roots,
+		hashes:   hashes,
+		slots:    slots,
+		cont:     cont,
+	}
+	select {
+	case s.storageResps <- response:
+	case <-req.cancel:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// OnTrieNodes is a callback method to invoke when a batch of trie nodes
+// are received from a remote peer.
+func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error {
+	var size common.StorageSize
+	for _, node := range trienodes {
+		size += common.StorageSize(len(node))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering set of healing trienodes", "trienodes", len(trienodes), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	if _, ok := s.peers[peer.ID()]; ok {
+		delete(s.busyPeers, peer.ID())
+	}
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+	// Ensure the response is for a valid request
+	req, ok := s.trienodeHealReqs[id]
+	if !ok {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected trienode heal packet")
+		s.lock.Unlock()
+		return nil
+	}
+	delete(s.trienodeHealReqs, id)
+
+	// Clean up the request timeout timer, we'll see how to proceed further based
+	// on the actual delivered content
+	if !req.timeout.Stop() {
+		// The timeout is already triggered, and this request will be reverted+rescheduled
+		s.lock.Unlock()
+		return nil
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For bytecode range queries that means the peer is not
+	// yet synced.
+	if len(trienodes) == 0 {
+		logger.Debug("Peer rejected trienode heal request")
+		s.statelessPeers[peer.ID()] = struct{}{}
+		s.lock.Unlock()
+
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertTrienodeHealRequest(req)
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Cross reference the requested trienodes with the response to find gaps
+	// that the serving node is missing
+	nodes, err := matchHashes(req.hashes, trienodes)
+	if err != nil {
+		logger.Warn("Unexpected healing trienodes", "err", err)
+		s.scheduleRevertTrienodeHealRequest(req)
+		return err
+	}
+	// Response validated, send it to the scheduler for filling
+	response := &trienodeHealResponse{
+		task:   req.task,
+		hashes: req.hashes,
+		paths:  req.paths,
+		nodes:  nodes,
+	}
+	select {
+	case s.trienodeHealResps <- response:
+	case <-req.cancel:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// onHealByteCodes is a callback method to invoke when a batch of contract
+// bytes codes are received from a remote peer in the healing phase.
+func (s *Syncer) onHealByteCodes(peer SyncPeer, id uint64, bytecodes [][]byte) error {
+	var size common.StorageSize
+	for _, code := range bytecodes {
+		size += common.StorageSize(len(code))
+	}
+	logger := peer.Log().New("reqid", id)
+	logger.Trace("Delivering set of healing bytecodes", "bytecodes", len(bytecodes), "bytes", size)
+
+	// Whether or not the response is valid, we can mark the peer as idle and
+	// notify the scheduler to assign a new task. If the response is invalid,
+	// we'll drop the peer in a bit.
+	s.lock.Lock()
+	if _, ok := s.peers[peer.ID()]; ok {
+		delete(s.busyPeers, peer.ID())
+	}
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+	// Ensure the response is for a valid request
+	req, ok := s.bytecodeHealReqs[id]
+	if !ok {
+		// Request stale, perhaps the peer timed out but came through in the end
+		logger.Warn("Unexpected bytecode heal packet")
+		s.lock.Unlock()
+		return nil
+	}
+	delete(s.bytecodeHealReqs, id)
+
+	// Clean up the request timeout timer, we'll see how to proceed further based
+	// on the actual delivered content
+	if !req.timeout.Stop() {
+		// The timeout is already triggered, and this request will be reverted+rescheduled
+		s.lock.Unlock()
+		return nil
+	}
+	// Response is valid, but check if peer is signalling that it does not have
+	// the requested data. For bytecode range queries that means the peer is not
+	// yet synced.
+	if len(bytecodes) == 0 {
+		logger.Debug("Peer rejected bytecode heal request")
+		s.statelessPeers[peer.ID()] = struct{}{}
+		s.lock.Unlock()
+
+		// Signal this request as failed, and ready for rescheduling
+		s.scheduleRevertBytecodeHealRequest(req)
+		return nil
+	}
+	s.lock.Unlock()
+
+	// Cross reference the requested bytecodes with the response to find gaps
+	// that the serving node is missing
+	codes, err := matchHashes(req.hashes, bytecodes)
+	if err != nil {
+		logger.Warn("Unexpected healing bytecodes", "err", err)
+		s.scheduleRevertBytecodeHealRequest(req)
+		return err
+	}
+	// Response validated, send it to the scheduler for filling
+	response := &bytecodeHealResponse{
+		task:   req.task,
+		hashes: req.hashes,
+		codes:  codes,
+	}
+	select {
+	case s.bytecodeHealResps <- response:
+	case <-req.cancel:
+	case <-req.stale:
+	}
+	return nil
+}
+
+// matchHashes cross references a list of requested hashes with the delivered
+// data blobs, which must be a subsequence of the requested items in order. The
+// returned list is aligned to the requested hashes, with missing items left nil.
+func matchHashes(hashes []common.Hash, blobs [][]byte) ([][]byte, error) {
+	var (
+		hasher = sha3.NewLegacyKeccak256().(crypto.KeccakState)
+		hash   = make([]byte, 32)
+		result = make([][]byte, len(hashes))
+	)
+	for i, j := 0, 0; i < len(blobs); i++ {
+		// Find the next hash that we've been served, leaving misses with nils
+		hasher.Reset()
+		hasher.Write(blobs[i])
+		hasher.Read(hash)
+
+		for j < len(hashes) && !bytes.Equal(hash, hashes[j][:]) {
+			j++
+		}
+		if j < len(hashes) {
+			result[j] = blobs[i]
+			j++
+			continue
+		}
+		// We've either ran out of hashes, or got unrequested data
+		return nil, fmt.Errorf("%w: %d unrequested items", errUnexpectedResponse, len(blobs)-i)
+	}
+	return result, nil
+}
+
+// reportSyncProgress calculates various status reports and provides it to the user.
+func (s *Syncer) reportSyncProgress(force bool) {
+	// Don't report all the events, just occasionally
+	if !force && time.Since(s.logTime) < 8*time.Second {
+		return
+	}
+	s.logTime = time.Now()
+
+	// Create a mega progress report
+	var (
+		accounts = fmt.Sprintf("%d@%v", s.accountSynced, s.accountBytes.TerminalString())
+		storage  = fmt.Sprintf("%d@%v", s.storageSynced, s.storageBytes.TerminalString())
+		bytecode = fmt.Sprintf("%d@%v", s.bytecodeSynced, s.bytecodeBytes.TerminalString())
+		trienode = fmt.Sprintf("%d@%v", s.trienodeHealSynced, s.trienodeHealBytes.TerminalString())
+		healcode = fmt.Sprintf("%d@%v", s.bytecodeHealSynced, s.bytecodeHealBytes.TerminalString())
+	)
+	log.Info("State sync in progress", "accounts", accounts, "slots", storage, "codes", bytecode,
+		"healnodes", trienode, "healcodes", healcode, "pending", s.healer.scheduler.Pending(),
+		"elapsed", common.PrettyDuration(time.Since(s.startTime)))
+}
+
+// incHash returns the next hash, in lexicographical order (a.k.a plus one).
+func incHash(h common.Hash) common.Hash {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,504 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// kv is a single key/value pair of a trie.
+type kv struct {
+	k, v []byte
+}
+
+// entrySlice is a sortable list of trie leaves.
+type entrySlice []*kv
+
+func (p entrySlice) Len() int           { return len(p) }
+func (p entrySlice) Less(i, j int) bool { return bytes.Compare(p[i].k, p[j].k) < 0 }
+func (p entrySlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// serveLock serializes data retrievals across all test peers, since the source
+// tries are shared between them and are not safe for concurrent use.
+var serveLock sync.Mutex
+
+// testPeer is a mock snap peer serving state data directly from in-memory tries.
+type testPeer struct {
+	id     string
+	test   *testing.T
+	remote *Syncer
+	logger log.Logger
+
+	accountTrie   *trie.Trie
+	accountValues entrySlice
+	storageTries  map[common.Hash]*trie.Trie
+	storageValues map[common.Hash]entrySlice
+	codes         map[common.Hash][]byte
+
+	limit     uint64 // Soft cap on the response sizes, overriding the requested one
+	stateless bool   // Whether the peer refuses to serve any state
+}
+
+func newTestPeer(id string, t *testing.T) *testPeer {
+	return &testPeer{
+		id:     id,
+		test:   t,
+		logger: log.New("id", id),
+		limit:  softResponseLimit,
+	}
+}
+
+func (t *testPeer) ID() string      { return t.id }
+func (t *testPeer) Log() log.Logger { return t.logger }
+
+func (t *testPeer) RequestAccountRange(id uint64, root, origin, limit common.Hash, bytes uint64) error {
+	go t.serveAccountRange(id, root, origin, limit, bytes)
+	return nil
+}
+
+func (t *testPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, bytes uint64) error {
+	go t.serveStorageRanges(id, root, accounts, origin, limit, bytes)
+	return nil
+}
+
+func (t *testPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
+	go t.serveByteCodes(id, hashes, bytes)
+	return nil
+}
+
+func (t *testPeer) RequestTrieNodes(id uint64, root common.Hash, paths []TrieNodePathSet, bytes uint64) error {
+	go t.serveTrieNodes(id, root, paths, bytes)
+	return nil
+}
+
+func (t *testPeer) serveAccountRange(id uint64, root, origin, limit common.Hash, cap uint64) {
+	serveLock.Lock()
+	defer serveLock.Unlock()
+
+	if t.stateless || root != t.accountTrie.Hash() {
+		t.remote.OnAccounts(t, id, nil, nil, nil)
+		return
+	}
+	if cap > t.limit {
+		cap = t.limit
+	}
+	var (
+		keys []common.Hash
+		vals [][]byte
+		size uint64
+	)
+	for _, entry := range t.accountValues {
+		if size > cap {
+			break
+		}
+		if bytes.Compare(origin[:], entry.k) <= 0 {
+			keys = append(keys, common.BytesToHash(entry.k))
+			vals = append(vals, entry.v)
+			size += uint64(32 + len(entry.v))
+		}
+		if bytes.Compare(entry.k, limit[:]) >= 0 {
+			break
+		}
+	}
+	// Unless we send the entire trie, we need to supply proofs
+	proof := light.NewNodeSet()
+	if err := t.accountTrie.Prove(origin[:], 0, proof); err != nil {
+		t.test.Errorf("could not prove origin %x: %v", origin, err)
+	}
+	if len(keys) > 0 {
+		if err := t.accountTrie.Prove(keys[len(keys)-1][:], 0, proof); err != nil {
+			t.test.Errorf("could not prove last item: %v", err)
+		}
+	}
+	var proofs [][]byte
+	for _, blob := range proof.NodeList() {
+		proofs = append(proofs, blob)
+	}
+	if err := t.remote.OnAccounts(t, id, keys, vals, proofs); err != nil {
+		t.test.Errorf("remote side rejected our delivery: %v", err)
+	}
+}
+
+func (t *testPeer) serveStorageRanges(id uint64, root common.Hash, accounts []common.Hash, origin, limit []byte, cap uint64) {
+	serveLock.Lock()
+	defer serveLock.Unlock()
+
+	if t.stateless || root != t.accountTrie.Hash() {
+		t.remote.OnStorage(t, id, nil, nil, nil)
+		return
+	}
+	if cap > t.limit {
+		cap = t.limit
+	}
+	var (
+		hashes [][]common.Hash
+		slots  [][][]byte
+		proofs [][]byte
+		size   uint64
+	)
+	for i, account := range accounts {
+		if size >= cap {
+			break
+		}
+		var start common.Hash
+		if i == 0 && len(origin) > 0 {
+			start = common.BytesToHash(origin)
+		}
+		var (
+			keys  []common.Hash
+			vals  [][]byte
+			abort bool
+		)
+		for _, entry := range t.storageValues[account] {
+			if size >= cap {
+				abort = true
+				break
+			}
+			if bytes.Compare(start[:], entry.k) <= 0 {
+				keys = append(keys, common.BytesToHash(entry.k))
+				vals = append(vals, entry.v)
+				size += uint64(32 + len(entry.v))
+			}
+		}
+		hashes = append(hashes, keys)
+		slots = append(slots, vals)
+
+		// Generate the Merkle proofs for the first and last storage slot, but
+		// only if the response was capped.
+		if start != (common.Hash{}) || abort {
+			stTrie := t.storageTries[account]
+			proof := light.NewNodeSet()
+			if err := stTrie.Prove(start[:], 0, proof); err != nil {
+				t.test.Errorf("could not prove origin %x: %v", start, err)
+			}
+			if len(keys) > 0 {
+				if err := stTrie.Prove(keys[len(keys)-1][:], 0, proof); err != nil {
+					t.test.Errorf("could not prove last item: %v", err)
+				}
+			}
+			for _, blob := range proof.NodeList() {
+				proofs = append(proofs, blob)
+			}
+			break
+		}
+	}
+	if err := t.remote.OnStorage(t, id, hashes, slots, proofs); err != nil {
+		t.test.Errorf("remote side rejected our delivery: %v", err)
+	}
+}
+
+func (t *testPeer) serveByteCodes(id uint64, hashes []common.Hash, cap uint64) {
+	if t.stateless {
+		t.remote.OnByteCodes(t, id, nil)
+		return
+	}
+	var codes [][]byte
+	for _, hash := range hashes {
+		if code, ok := t.codes[hash]; ok {
+			codes = append(codes, code)
+		}
+	}
+	if err := t.remote.OnByteCodes(t, id, codes); err != nil {
+		t.test.Errorf("remote side rejected our delivery: %v", err)
+	}
+}
+
+func (t *testPeer) serveTrieNodes(id uint64, root common.Hash, paths []TrieNodePathSet, cap uint64) {
+	serveLock.Lock()
+	defer serveLock.Unlock()
+
+	if t.stateless || root != t.accountTrie.Hash() {
+		t.remote.OnTrieNodes(t, id, nil)
+		return
+	}
+	var nodes [][]byte
+	for _, pathset := range paths {
+		switch len(pathset) {
+		case 1:
+			blob, _, err := t.accountTrie.TryGetNode(pathset[0])
+			if err != nil {
+				t.test.Errorf("failed to retrieve account node: %v", err)
+				continue
+			}
+			nodes = append(nodes, blob)
+		default:
+			stTrie := t.storageTries[common.BytesToHash(pathset[0])]
+			for _, path := range pathset[1:] {
+				blob, _, err := stTrie.TryGetNode(path)
+				if err != nil {
+					t.test.Errorf("failed to retrieve storage node: %v", err)
+					continue
+				}
+				nodes = append(nodes, blob)
+			}
+		}
+	}
+	if err := t.remote.OnTrieNodes(t, id, nodes); err != nil {
+		t.test.Errorf("remote side rejected our delivery: %v", err)
+	}
+}
+
+// setupSyncer creates a syncer backed by an empty database and registers all
+// the given peers to it.
+func setupSyncer(peers ...*testPeer) (*Syncer, ethdb.KeyValueStore) {
+	db := rawdb.NewMemoryDatabase()
+	syncer := NewSyncer(db, nil)
+	for _, peer := range peers {
+		syncer.Register(peer)
+		peer.remote = syncer
+	}
+	return syncer, db
+}
+
+// runSync runs a sync cycle against the given root, failing the test if it
+// doesn't complete in a reasonable amount of time.
+func runSync(t *testing.T, syncer *Syncer, root common.Hash) {
+	t.Helper()
+
+	var (
+		cancel = make(chan struct{})
+		done   = make(chan error, 1)
+	)
+	go func() { done <- syncer.Sync(root, cancel) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	case <-time.After(20 * time.Second):
+		close(cancel)
+		<-done
+		t.Fatalf("sync timed out")
+	}
+}
+
+// makeAccountTrie creates an account trie with the given number of accounts.
+// If slots is non-zero, every third account gets a storage trie with the given
+// number of slots and a contract code.
+func makeAccountTrie(n int, slots int) (*trie.Trie, entrySlice, map[common.Hash]*trie.Trie, map[common.Hash]entrySlice, map[common.Hash][]byte) {
+	var (
+		db            = trie.NewDatabase(memorydb.New())
+		accTrie, _    = trie.New(common.Hash{}, db)
+		entries       entrySlice
+		storageTries  = make(map[common.Hash]*trie.Trie)
+		storageValues = make(map[common.Hash]entrySlice)
+		codes         = make(map[common.Hash][]byte)
+	)
+	for i := uint64(1); i <= uint64(n); i++ {
+		key := key32(i)
+		acc := &state.Account{
+			Nonce:    i,
+			Balance:  big.NewInt(int64(i)),
+			Root:     emptyRoot,
+			CodeHash: emptyCode[:],
+		}
+		if slots > 0 && i%3 == 0 {
+			stTrie, stEntries := makeStorageTrie(i, slots, db)
+			storageTries[common.BytesToHash(key)] = stTrie
+			storageValues[common.BytesToHash(key)] = stEntries
+			acc.Root = stTrie.Hash()
+
+			code := []byte(fmt.Sprintf("contract code %d", i))
+			codes[crypto.Keccak256Hash(code)] = code
+			acc.CodeHash = crypto.Keccak256(code)
+		}
+		value, _ := rlp.EncodeToBytes(acc)
+		accTrie.Update(key, value)
+		entries = append(entries, &kv{key, value})
+	}
+	sort.Sort(entries)
+
+	root, _ := accTrie.Commit(nil)
+	accTrie, _ = trie.New(root, db)
+	return accTrie, entries, storageTries, storageValues, codes
+}
+
+// makeStorageTrie creates a storage trie with the given number of slots, seeded
+// by the owner's index.
+func makeStorageTrie(owner uint64, n int, db *trie.Database) (*trie.Trie, entrySlice) {
+	var (
+		stTrie, _ = trie.New(common.Hash{}, db)
+		entries   entrySlice
+	)
+	for i := uint64(1); i <= uint64(n); i++ {
+		key := crypto.Keccak256(key32(owner), key32(i))
+		value, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(key32(i * owner)))
+		stTrie.Update(key, value)
+		entries = append(entries, &kv{key, value})
+	}
+	sort.Sort(entries)
+
+	root, _ := stTrie.Commit(nil)
+	stTrie, _ = trie.New(root, db)
+	return stTrie, entries
+}
+
+// key32 returns a 32 byte key derived from the given number.
+func key32(i uint64) []byte {
+	key := make([]byte, 32)
+	binary.LittleEndian.PutUint64(key, i)
+	return key
+}
+
+// verifyTrie checks that the entire state trie with the given root is present
+// in the database, along with all the storage tries and contract codes.
+func verifyTrie(db ethdb.KeyValueStore, root common.Hash, t *testing.T) {
+	t.Helper()
+
+	triedb := trie.NewDatabase(db)
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		t.Fatalf("failed to open account trie: %v", err)
+	}
+	var (
+		accounts int
+		slots    int
+	)
+	accIt := trie.NewIterator(accTrie.NodeIterator(nil))
+	for accIt.Next() {
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			t.Fatalf("invalid account encountered: %v", err)
+		}
+		accounts++
+		if acc.Root != emptyRoot {
+			stTrie, err := trie.New(acc.Root, triedb)
+			if err != nil {
+				t.Fatalf("failed to open storage trie %x: %v", acc.Root, err)
+			}
+			stIt := trie.NewIterator(stTrie.NodeIterator(nil))
+			for stIt.Next() {
+				slots++
+			}
+			if stIt.Err != nil {
+				t.Fatalf("failed to iterate storage trie %x: %v", acc.Root, stIt.Err)
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode[:]) && !rawdb.HasCode(db, common.BytesToHash(acc.CodeHash)) {
+			t.Fatalf("missing contract code %x", acc.CodeHash)
+		}
+	}
+	if accIt.Err != nil {
+		t.Fatalf("failed to iterate account trie: %v", accIt.Err)
+	}
+	t.Logf("accounts: %d, slots: %d", accounts, slots)
+}
+
+// Tests that a trie with only accounts can be synced from a single peer.
+func TestSync(t *testing.T) {
+	sourceAccountTrie, elems, _, _, _ := makeAccountTrie(100, 0)
+
+	source := newTestPeer("source", t)
+	source.accountTrie = sourceAccountTrie
+	source.accountValues = elems
+
+	syncer, db := setupSyncer(source)
+	runSync(t, syncer, sourceAccountTrie.Hash())
+	verifyTrie(db, sourceAccountTrie.Hash(), t)
+}
+
+// Tests that a tiny trie, where all the accounts fall into a single chunk, can
+// be synced without issues.
+func TestSyncTinyTrie(t *testing.T) {
+	sourceAccountTrie, elems, _, _, _ := makeAccountTrie(1, 0)
+
+	source := newTestPeer("source", t)
+	source.accountTrie = sourceAccountTrie
+	source.accountValues = elems
+
+	syncer, db := setupSyncer(source)
+	runSync(t, syncer, sourceAccountTrie.Hash())
+	verifyTrie(db, sourceAccountTrie.Hash(), t)
+}
+
+// Tests that a trie with accounts, storage slots and contract codes can be
+// synced from multiple peers.
+func TestSyncWithStorage(t *testing.T) {
+	sourceAccountTrie, elems, storageTries, storageElems, codes := makeAccountTrie(100, 10)
+
+	mkSource := func(name string) *testPeer {
+		source := newTestPeer(name, t)
+		source.accountTrie = sourceAccountTrie
+		source.accountValues = elems
+		source.storageTries = storageTries
+		source.storageValues = storageElems
+		source.codes = codes
+		return source
+	}
+	syncer, db := setupSyncer(mkSource("source-1"), mkSource("source-2"))
+	runSync(t, syncer, sourceAccountTrie.Hash())
+	verifyTrie(db, sourceAccountTrie.Hash(), t)
+}
+
+// Tests that a peer capping its responses forces the syncer to retrieve the
+// accounts and large storage tries in multiple chunks, and that the resulting
+// state is still complete.
+func TestSyncWithCappedPeer(t *testing.T) {
+	sourceAccountTrie, elems, storageTries, storageElems, codes := makeAccountTrie(200, 100)
+
+	source := newTestPeer("capped", t)
+	source.accountTrie = sourceAccountTrie
+	source.accountValues = elems
+	source.storageTries = storageTries
+	source.storageValues = storageElems
+	source.codes = codes
+	source.limit = 500
+
+	syncer, db := setupSyncer(source)
+	runSync(t, syncer, sourceAccountTrie.Hash())
+	verifyTrie(db, sourceAccountTrie.Hash(), t)
+}
+
+// Tests that a peer refusing to serve state does not stall the sync if there
+// are other peers available.
+func TestSyncWithStatelessPeer(t *testing.T) {
+	sourceAccountTrie, elems, storageTries, storageElems, codes := makeAccountTrie(100, 10)
+
+	good := newTestPeer("good", t)
+	good.accountTrie = sourceAccountTrie
+	good.accountValues = elems
+	good.storageTries = storageTries
+	good.storageValues = storageElems
+	good.codes = codes
+
+	bad := newTestPeer("stateless", t)
+	bad.accountTrie = sourceAccountTrie
+	bad.stateless = true
+
+	syncer, db := setupSyncer(good, bad)
+	runSync(t, syncer, sourceAccountTrie.Hash())
+	verifyTrie(db, sourceAccountTrie.Hash(), t)
+}
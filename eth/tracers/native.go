@@ -0,0 +1,102 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// NativeTracer is a transaction tracer implemented in Go. It produces the same
+// output as the built-in JavaScript tracer of the same name, without the cost
+// of running every execution step through the JavaScript interpreter.
+type NativeTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace, or any error that
+	// occurred during tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// natives contains the constructors of all the built in native tracers by name.
+var natives = map[string]func() NativeTracer{
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+	"4byteTracer":    newFourByteTracer,
+}
+
+// NewNative instantiates the native tracer with the given name. The returned
+// flag is false if no native implementation of that tracer exists.
+func NewNative(name string) (NativeTracer, bool) {
+	constructor, ok := natives[name]
+	if !ok {
+		return nil, false
+	}
+	return constructor(), true
+}
+
+// peek returns the nth-from-the-top element of the stack, or zero if the stack
+// is not deep enough.
+func peek(stack *vm.Stack, idx int) *uint256.Int {
+	if len(stack.Data()) <= idx || idx < 0 {
+		log.Warn("Tracer accessed out of bound stack", "size", len(stack.Data()), "index", idx)
+		return new(uint256.Int)
+	}
+	return stack.Back(idx)
+}
+
+// peekUint64 returns the nth-from-the-top element of the stack as an uint64,
+// along with a flag whether the value fit into 64 bits.
+func peekUint64(stack *vm.Stack, idx int) (uint64, bool) {
+	val := peek(stack, idx)
+	return val.Uint64(), val.IsUint64()
+}
+
+// peekAddress returns the nth-from-the-top element of the stack interpreted as
+// an account address.
+func peekAddress(stack *vm.Stack, idx int) common.Address {
+	return common.Address(peek(stack, idx).Bytes20())
+}
+
+// memorySlice returns a copy of the memory range starting at the given offset
+// with the given length. Out of bound accesses result in an empty slice, the
+// same way they do for the JavaScript tracers.
+func memorySlice(memory *vm.Memory, offset, size uint64, ok bool) []byte {
+	if size == 0 {
+		return []byte{}
+	}
+	if !ok || offset > math.MaxInt64 || size > math.MaxInt64-offset || uint64(memory.Len()) < offset+size {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return []byte{}
+	}
+	return memory.GetCopy(int64(offset), int64(size))
+}
+
+// isPrecompiled reports whether the given address is one of the precompiled
+// contracts skipped by the built in tracers.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsIstanbul[addr]
+	return ok
+}
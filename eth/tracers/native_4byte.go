@@ -0,0 +1,117 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// fourByteTracer is the native implementation of the JavaScript 4byteTracer.
+// It searches for 4byte-identifiers, and collects them for post-processing.
+// It collects the methods identifiers along with the size of the supplied data,
+// so a reversed signature can be matched against the size of the data.
+type fourByteTracer struct {
+	ids map[string]int // Aggregated 4byte ids found
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newFourByteTracer creates a native 4byte tracer.
+func newFourByteTracer() NativeTracer {
+	return &fourByteTracer{ids: make(map[string]int)}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *fourByteTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// store saves the given identifier and datasize.
+func (t *fourByteTracer) store(id []byte, size uint64) {
+	t.ids[hexutil.Encode(id)+"-"+strconv.FormatUint(size, 10)]++
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	// Save the outer calldata also
+	if len(input) >= 4 {
+		t.store(input[:4], uint64(len(input)-4))
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	// If tracing was interrupted, abort the execution
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return nil
+	}
+	// Skip any opcodes that are not internal calls, otherwise find the peek-index
+	// for the first param after 'value', i.e. meminstart
+	var idx int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		idx = 3 // gas, addr, val, memin, meminsz, memout, memoutsz
+	case vm.DELEGATECALL, vm.STATICCALL:
+		idx = 2 // gas, addr, memin, meminsz, memout, memoutsz
+	default:
+		return nil
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(peekAddress(stack, 1)) {
+		return nil
+	}
+	// Gather internal call details
+	inSize, ok := peekUint64(stack, idx+1)
+	if ok && inSize >= 4 {
+		inOff, offOk := peekUint64(stack, idx)
+		t.store(memorySlice(memory, inOff, 4, offOk), inSize-4)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded 4byte identifiers found during execution,
+// or any error that occurred during tracing.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.ids)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
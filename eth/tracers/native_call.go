@@ -0,0 +1,283 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// callFrame is a single call in the call tree assembled by the callTracer. The
+// field order matches the JSON output of the JavaScript callTracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available before the call opcode was executed
+	gasCost uint64 // Gas cost of the call opcode itself
+	outOff  uint64 // Memory offset of the call output in the caller
+	outLen  uint64 // Memory length of the call output in the caller
+	outOk   bool   // Whether the output memory range fits into 64 bits
+}
+
+// callTracer is the native implementation of the JavaScript callTracer. It
+// extracts and reports all the internal calls made by a transaction, along
+// with any useful information.
+type callTracer struct {
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	ctx callFrame // Outer transaction context gathered throughout execution
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() NativeTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.ctx.Type = "CALL"
+	if create {
+		t.ctx.Type = "CREATE"
+	}
+	t.ctx.From, t.ctx.To = &from, &to
+	t.ctx.Input = (*hexutil.Bytes)(&input)
+	t.ctx.Gas = (*hexutil.Uint64)(&gas)
+	t.ctx.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	// If tracing was interrupted, abort the execution
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	// If a new contract is being created, add to the call stack
+	if op == vm.CREATE || op == vm.CREATE2 {
+		inOff, ok1 := peekUint64(stack, 1)
+		inLen, ok2 := peekUint64(stack, 2)
+		input := hexutil.Bytes(memorySlice(memory, inOff, inLen, ok1 && ok2))
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    addressPtr(contract.Address()),
+			Input:   &input,
+			Value:   (*hexutil.Big)(peek(stack, 0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+	}
+	// If a contract is being self destructed, gather that as a subcall too
+	if op == vm.SELFDESTRUCT {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  op.String(),
+			From:  addressPtr(contract.Address()),
+			To:    addressPtr(peekAddress(stack, 0)),
+			Value: (*hexutil.Big)(new(big.Int).Set(env.StateDB.GetBalance(contract.Address()))),
+		})
+		return nil
+	}
+	// If a new method invocation is being done, add to the call stack
+	if op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL {
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := peekAddress(stack, 1)
+		if isPrecompiled(to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff, ok1 := peekUint64(stack, 2+off)
+		inLen, ok2 := peekUint64(stack, 3+off)
+		input := hexutil.Bytes(memorySlice(memory, inOff, inLen, ok1 && ok2))
+
+		outOff, ok3 := peekUint64(stack, 4+off)
+		outLen, ok4 := peekUint64(stack, 5+off)
+
+		// Assemble the internal call report and store for completion
+		call := &callFrame{
+			Type:    op.String(),
+			From:    addressPtr(contract.Address()),
+			To:      &to,
+			Input:   &input,
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  outOff,
+			outLen:  outLen,
+			outOk:   ok3 && ok4,
+		}
+		if op != vm.DELEGATECALL && op != vm.STATICCALL {
+			call.Value = (*hexutil.Big)(peek(stack, 2).ToBig())
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].Gas = (*hexutil.Uint64)(&gas)
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := peek(stack, 0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			gasUsed := call.gasIn - call.gasCost - gas
+			call.GasUsed = (*hexutil.Uint64)(&gasUsed)
+
+			if !ret.IsZero() {
+				addr := common.Address(ret.Bytes20())
+				code := hexutil.Bytes(env.StateDB.GetCode(addr))
+				call.To, call.Output = &addr, &code
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.Gas != nil {
+				gasUsed := call.gasIn - call.gasCost + uint64(*call.Gas) - gas
+				call.GasUsed = (*hexutil.Uint64)(&gasUsed)
+			}
+			if !ret.IsZero() {
+				output := hexutil.Bytes(memorySlice(memory, call.outOff, call.outLen, call.outOk))
+				call.Output = &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	t.fault(err)
+	return nil
+}
+
+// fault is invoked when the actual execution of an opcode fails.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas and clean any leftovers
+	if call.Gas != nil {
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	t.ctx.Output = (*hexutil.Bytes)(&output)
+	t.ctx.GasUsed = (*hexutil.Uint64)(&gasUsed)
+	t.ctx.Time = elapsed.String()
+
+	if err != nil {
+		t.ctx.Error = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the JSON encoded call tree of the transaction, or any error
+// that occurred during tracing.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	result := t.ctx
+	result.Calls = t.callstack[0].Calls
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	}
+	if result.Error != "" && (result.Error != "execution reverted" || result.Output == nil || len(*result.Output) == 0) {
+		result.Output = nil
+	}
+	res, err := json.Marshal(&result)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// addressPtr returns a pointer to a copy of the given address.
+func addressPtr(addr common.Address) *common.Address {
+	return &addr
+}
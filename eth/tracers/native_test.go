@@ -0,0 +1,141 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// runTracerTest executes the transaction of a tracer test case with the given
+// tracer attached and returns the result of the trace.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer NativeTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native tracers produce the exact same output as their
+// JavaScript counterparts.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for name := range natives {
+				native, _ := NewNative(name)
+				js, err := New(name)
+				if err != nil {
+					t.Fatalf("failed to create %s: %v", name, err)
+				}
+				have := runTracerTest(t, test, native)
+				want := runTracerTest(t, test, js)
+
+				// Retrieving the result must not alter the trace
+				if again, err := native.GetResult(); err != nil || !bytes.Equal(again, have) {
+					t.Fatalf("%s repeated result mismatch: \nhave %s\nwant %s", name, again, have)
+				}
+
+				// Compare the generic JSON outputs, ignoring the execution time
+				var haveObj, wantObj map[string]interface{}
+				if err := json.Unmarshal(have, &haveObj); err != nil {
+					t.Fatalf("failed to unmarshal native %s result: %v", name, err)
+				}
+				if err := json.Unmarshal(want, &wantObj); err != nil {
+					t.Fatalf("failed to unmarshal JavaScript %s result: %v", name, err)
+				}
+				if name == "callTracer" {
+					delete(haveObj, "time")
+					delete(wantObj, "time")
+
+					ret := new(callTrace)
+					if err := json.Unmarshal(have, ret); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+					if !jsonEqual(ret, test.Result) {
+						t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+					}
+				}
+				if !reflect.DeepEqual(haveObj, wantObj) {
+					t.Fatalf("%s output mismatch: \nhave %s\nwant %s", name, have, want)
+				}
+			}
+		})
+	}
+}
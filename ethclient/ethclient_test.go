@@ -22,15 +22,18 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
@@ -368,3 +371,192 @@ func TestBlockNumber(t *testing.T) {
 		t.Fatalf("BlockNumber returned wrong number: %d", blockNumber)
 	}
 }
+
+// Tests that bundles are simulated in order on top of the overridden state, with
+// reverted transactions reported and accounted for in the coinbase payment, and
+// invalid transactions failing the whole bundle.
+func TestCallBundle(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	// Deploy a contract via state override reverting with reason "nope"
+	revert := append([]byte{0x08, 0xc3, 0x79, 0xa0}, common.LeftPadBytes([]byte{0x20}, 32)...)
+	revert = append(revert, common.LeftPadBytes([]byte{0x04}, 32)...)
+	revert = append(revert, common.RightPadBytes([]byte("nope"), 32)...)
+
+	code := []byte{
+		byte(vm.PUSH1), byte(len(revert)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(revert)), byte(vm.PUSH1), 0, byte(vm.REVERT),
+	}
+	code = append(code, revert...)
+
+	var (
+		contract = common.Address{0xc0}
+		coinbase = common.Address{0xcb}
+		signer   = types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+		funds    = big.NewInt(5e9)
+		payment  = big.NewInt(1000)
+		gasPrice = big.NewInt(1)
+	)
+	sign := func(nonce uint64, to common.Address, value *big.Int, gas uint64) hexutil.Bytes {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, value, gas, gasPrice, nil), signer, testKey)
+		blob, _ := tx.MarshalBinary()
+		return blob
+	}
+	txs := []hexutil.Bytes{
+		sign(0, coinbase, payment, params.TxGas),
+		sign(1, contract, common.Big0, 100000),
+		sign(2, common.Address{0x01}, common.Big1, params.TxGas),
+	}
+	overrides := map[common.Address]map[string]interface{}{
+		contract: {"code": hexutil.Bytes(code)},
+		coinbase: {"balance": (*hexutil.Big)(funds)},
+	}
+	var result struct {
+		StateBlockNumber hexutil.Uint64
+		Results          []struct {
+			TxHash       common.Hash
+			GasUsed      hexutil.Uint64
+			Error        string
+			RevertReason string
+		}
+		GasUsed      hexutil.Uint64
+		CoinbaseDiff *hexutil.Big
+	}
+	if err := client.Call(&result, "eth_callBundle", txs, "latest", map[string]interface{}{"coinbase": coinbase}, overrides); err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if result.StateBlockNumber != 1 {
+		t.Errorf("state block mismatch: have %d, want %d", result.StateBlockNumber, 1)
+	}
+	if len(result.Results) != len(txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(txs))
+	}
+	var gas uint64
+	for i, res := range result.Results {
+		tx := new(types.Transaction)
+		tx.UnmarshalBinary(txs[i])
+		if res.TxHash != tx.Hash() {
+			t.Errorf("tx %d: hash mismatch: have %x, want %x", i, res.TxHash, tx.Hash())
+		}
+		if reverted := i == 1; reverted != (res.Error != "") {
+			t.Errorf("tx %d: error mismatch: have %q", i, res.Error)
+		}
+		gas += uint64(res.GasUsed)
+	}
+	if result.Results[1].RevertReason != "nope" {
+		t.Errorf("revert reason mismatch: have %q, want %q", result.Results[1].RevertReason, "nope")
+	}
+	if uint64(result.GasUsed) != gas {
+		t.Errorf("bundle gas mismatch: have %d, want %d", result.GasUsed, gas)
+	}
+	// The coinbase receives the direct payment and all the fees, but the balance
+	// override must not be accounted for
+	want := new(big.Int).Add(payment, new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice))
+	if result.CoinbaseDiff.ToInt().Cmp(want) != 0 {
+		t.Errorf("coinbase diff mismatch: have %v, want %v", result.CoinbaseDiff.ToInt(), want)
+	}
+	// Transactions failing validation abort the whole bundle
+	txs = append(txs, sign(5, common.Address{0x01}, common.Big1, params.TxGas))
+	if err := client.Call(&result, "eth_callBundle", txs, "latest", nil, overrides); err == nil {
+		t.Errorf("bundle with invalid nonce succeeded")
+	}
+}
+
+// Tests that a transaction reverting mid-bundle discards its own state changes
+// only, and that the state, stateDiff and nonce overrides shared with eth_call
+// apply to bundles.
+func TestCallBundleStateOverrides(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	// Without calldata the contract returns storage slot 0, with calldata it sets
+	// the slot to 1 and reverts
+	code := []byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 15, byte(vm.JUMPI),
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+		byte(vm.JUMPDEST), byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	}
+	var (
+		contract = common.Address{0xc0}
+		signer   = types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+		slot     = common.Hash{}
+		value    = common.BigToHash(big.NewInt(42))
+	)
+	sign := func(nonce uint64, data []byte) hexutil.Bytes {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, contract, common.Big0, 100000, big.NewInt(1), data), signer, testKey)
+		blob, _ := tx.MarshalBinary()
+		return blob
+	}
+	type bundleResult struct {
+		Results []struct {
+			Error      string
+			ReturnData hexutil.Bytes
+		}
+	}
+	call := func(txs []hexutil.Bytes, overrides map[common.Address]map[string]interface{}) (*bundleResult, error) {
+		var result bundleResult
+		err := client.Call(&result, "eth_callBundle", txs, "latest", nil, overrides)
+		return &result, err
+	}
+	// Read, revert a write and read again on top of a stateDiff override
+	txs := []hexutil.Bytes{sign(0, nil), sign(1, []byte{0x01}), sign(2, nil)}
+	result, err := call(txs, map[common.Address]map[string]interface{}{
+		contract: {"code": hexutil.Bytes(code), "stateDiff": map[common.Hash]common.Hash{slot: value}},
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if len(result.Results) != len(txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(txs))
+	}
+	if result.Results[1].Error == "" {
+		t.Errorf("writing transaction didn't revert")
+	}
+	for _, i := range []int{0, 2} {
+		if result.Results[i].Error != "" {
+			t.Errorf("tx %d: unexpected error: %s", i, result.Results[i].Error)
+		}
+		if have := common.BytesToHash(result.Results[i].ReturnData); have != value {
+			t.Errorf("tx %d: slot mismatch: have %x, want %x", i, have, value)
+		}
+	}
+	// A full state override clears the slots it doesn't list
+	result, err = call(txs[:1], map[common.Address]map[string]interface{}{
+		contract: {"code": hexutil.Bytes(code), "state": map[common.Hash]common.Hash{{0x01}: value}},
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if have := common.BytesToHash(result.Results[0].ReturnData); have != (common.Hash{}) {
+		t.Errorf("slot mismatch after state override: have %x, want empty", have)
+	}
+	// Nonce overrides let the bundle start from any nonce
+	result, err = call([]hexutil.Bytes{sign(7, nil)}, map[common.Address]map[string]interface{}{
+		contract: {"code": hexutil.Bytes(code)},
+		testAddr: {"nonce": hexutil.Uint64(7)},
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate bundle with nonce override: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Error != "" {
+		t.Errorf("nonce overridden bundle result mismatch: %+v", result.Results)
+	}
+	// Specifying both state and stateDiff is rejected
+	_, err = call(txs[:1], map[common.Address]map[string]interface{}{
+		contract: {
+			"code":      hexutil.Bytes(code),
+			"state":     map[common.Hash]common.Hash{slot: value},
+			"stateDiff": map[common.Hash]common.Hash{slot: value},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "both 'state' and 'stateDiff'") {
+		t.Errorf("conflicting overrides error mismatch: have %v", err)
+	}
+}
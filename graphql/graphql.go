@@ -18,8 +18,11 @@
 package graphql
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -33,12 +36,58 @@ import (
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errNoTracing      = errors.New("transaction tracing is not supported by this node")
+	errTracingOff     = errors.New("transaction tracing is not enabled on this endpoint")
 )
 
+const (
+	// maxStorageRange is the maximum number of entries returned by a storage range
+	// query.
+	maxStorageRange = 1024
+
+	// defaultTraceTimeout is the time a native tracer is allowed to run for if
+	// no timeout was requested.
+	defaultTraceTimeout = 5 * time.Second
+
+	// maxTraceTimeout is the maximum time a native tracer is allowed to run for.
+	maxTraceTimeout = 10 * time.Second
+
+	// defaultTraceReexec is the number of blocks re-executed to regenerate the
+	// state of a traced transaction if no limit was requested.
+	defaultTraceReexec = uint64(128)
+
+	// maxTraceReexec is the maximum number of blocks re-executed to regenerate
+	// the state of a traced transaction.
+	maxTraceReexec = uint64(1024)
+)
+
+// txTracer is implemented by backends able to re-execute and trace historical
+// transactions.
+type txTracer interface {
+	TraceTransaction(ctx context.Context, hash common.Hash, config *ethapi.TxTraceConfig) (interface{}, error)
+}
+
+// JSON is an arbitrary JSON value, exposed as the JSON scalar.
+type JSON struct {
+	value interface{}
+}
+
+func (JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	j.value = input
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.value)
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend       ethapi.Backend
@@ -88,6 +137,164 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) StorageRange(ctx context.Context, args struct {
+	Start *common.Hash
+	Count int32
+}) (*StorageRange, error) {
+	if args.Count < 0 || args.Count > maxStorageRange {
+		return nil, fmt.Errorf("invalid storage range count %d, must be between 0 and %d", args.Count, maxStorageRange)
+	}
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := new(StorageRange)
+	st := state.StorageTrie(a.address)
+	if st == nil {
+		return result, nil
+	}
+	var start []byte
+	if args.Start != nil {
+		start = args.Start.Bytes()
+	}
+	it := trie.NewIterator(st.NodeIterator(start))
+	for i := int32(0); i < args.Count && it.Next(); i++ {
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry := &StorageEntry{
+			hash:  common.BytesToHash(it.Key),
+			value: common.BytesToHash(content),
+		}
+		if preimage := st.GetKey(it.Key); preimage != nil {
+			key := common.BytesToHash(preimage)
+			entry.key = &key
+		}
+		result.entries = append(result.entries, entry)
+	}
+	// Add the 'next key' so clients can continue iterating
+	if it.Next() {
+		next := common.BytesToHash(it.Key)
+		result.nextKey = &next
+	}
+	return result, nil
+}
+
+func (a *Account) Proof(ctx context.Context, args struct{ Slots *[]common.Hash }) (*AccountProof, error) {
+	var keys []string
+	if args.Slots != nil {
+		for _, slot := range *args.Slots {
+			keys = append(keys, slot.Hex())
+		}
+	}
+	result, err := ethapi.NewPublicBlockChainAPI(a.backend).GetProof(ctx, a.address, keys, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, errors.New("state not available")
+	}
+	return &AccountProof{result}, nil
+}
+
+// StorageRange represents a consecutive range of storage entries of an account.
+type StorageRange struct {
+	entries []*StorageEntry
+	nextKey *common.Hash
+}
+
+func (r *StorageRange) Entries(ctx context.Context) []*StorageEntry {
+	return r.entries
+}
+
+func (r *StorageRange) NextKey(ctx context.Context) *common.Hash {
+	return r.nextKey
+}
+
+// StorageEntry represents a single storage slot of an account.
+type StorageEntry struct {
+	hash  common.Hash
+	key   *common.Hash
+	value common.Hash
+}
+
+func (e *StorageEntry) Hash(ctx context.Context) common.Hash {
+	return e.hash
+}
+
+func (e *StorageEntry) Key(ctx context.Context) *common.Hash {
+	return e.key
+}
+
+func (e *StorageEntry) Value(ctx context.Context) common.Hash {
+	return e.value
+}
+
+// AccountProof represents the Merkle proof of an account and some of its
+// storage slots.
+type AccountProof struct {
+	result *ethapi.AccountResult
+}
+
+func (p *AccountProof) AccountProof(ctx context.Context) ([]hexutil.Bytes, error) {
+	return decodeProof(p.result.AccountProof)
+}
+
+func (p *AccountProof) Balance(ctx context.Context) hexutil.Big {
+	return *p.result.Balance
+}
+
+func (p *AccountProof) CodeHash(ctx context.Context) common.Hash {
+	return p.result.CodeHash
+}
+
+func (p *AccountProof) Nonce(ctx context.Context) hexutil.Uint64 {
+	return p.result.Nonce
+}
+
+func (p *AccountProof) StorageHash(ctx context.Context) common.Hash {
+	return p.result.StorageHash
+}
+
+func (p *AccountProof) StorageProof(ctx context.Context) []*StorageProof {
+	ret := make([]*StorageProof, 0, len(p.result.StorageProof))
+	for i := range p.result.StorageProof {
+		ret = append(ret, &StorageProof{&p.result.StorageProof[i]})
+	}
+	return ret
+}
+
+// StorageProof represents the Merkle proof of a storage slot.
+type StorageProof struct {
+	result *ethapi.StorageResult
+}
+
+func (p *StorageProof) Key(ctx context.Context) common.Hash {
+	return common.HexToHash(p.result.Key)
+}
+
+func (p *StorageProof) Value(ctx context.Context) hexutil.Big {
+	return *p.result.Value
+}
+
+func (p *StorageProof) Proof(ctx context.Context) ([]hexutil.Bytes, error) {
+	return decodeProof(p.result.Proof)
+}
+
+// decodeProof converts a list of hex encoded trie nodes into binary form.
+func decodeProof(proof []string) ([]hexutil.Bytes, error) {
+	ret := make([]hexutil.Bytes, 0, len(proof))
+	for _, node := range proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, blob)
+	}
+	return ret, nil
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -215,7 +422,7 @@ func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account,
 	}
 	var signer types.Signer = types.HomesteadSigner{}
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
 	}
 	from, _ := types.Sender(signer, tx)
 
@@ -259,6 +466,27 @@ func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
 	return receipts[t.index], nil
 }
 
+func (t *Transaction) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return tx.MarshalBinary()
+}
+
+func (t *Transaction) RawReceipt(ctx context.Context) (*hexutil.Bytes, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	// Use the consensus encoding, prefixing typed receipts with their type
+	var buf bytes.Buffer
+	types.Receipts{receipt}.EncodeIndex(0, &buf)
+
+	ret := hexutil.Bytes(buf.Bytes())
+	return &ret, nil
+}
+
 func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
 	receipt, err := t.getReceipt(ctx)
 	if err != nil || receipt == nil {
@@ -559,6 +787,22 @@ func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
 	return &ret, nil
 }
 
+func (b *Block) RawHeader(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return rlp.EncodeToBytes(header)
+}
+
+func (b *Block) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	block, err := b.resolve(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return rlp.EncodeToBytes(block)
+}
+
 func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -899,6 +1143,8 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+	events  *filters.EventSystem // chain event source for subscriptions
+	tracing bool                 // whether transaction tracing is enabled
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -985,7 +1231,7 @@ func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Has
 
 func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(args.Data, tx); err != nil {
+	if err := tx.UnmarshalBinary(args.Data); err != nil {
 		return common.Hash{}, err
 	}
 	hash, err := ethapi.SubmitTransaction(ctx, r.backend, tx)
@@ -1048,6 +1294,49 @@ func (r *Resolver) ChainID(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*r.backend.ChainConfig().ChainID), nil
 }
 
+func (r *Resolver) TraceTransaction(ctx context.Context, args struct {
+	Hash    common.Hash
+	Tracer  *string
+	Timeout *string
+	Reexec  *hexutil.Uint64
+}) (*JSON, error) {
+	if !r.tracing {
+		return nil, errTracingOff
+	}
+	tracer, ok := r.backend.(txTracer)
+	if !ok {
+		return nil, errNoTracing
+	}
+	config := &ethapi.TxTraceConfig{
+		Timeout: defaultTraceTimeout,
+		Reexec:  defaultTraceReexec,
+	}
+	if args.Tracer != nil {
+		config.Tracer = *args.Tracer // Backend rejects anything but native tracers
+	}
+	if args.Timeout != nil {
+		timeout, err := time.ParseDuration(*args.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		if timeout <= 0 || timeout > maxTraceTimeout {
+			return nil, fmt.Errorf("trace timeout %v out of range (0, %v]", timeout, maxTraceTimeout)
+		}
+		config.Timeout = timeout
+	}
+	if args.Reexec != nil {
+		if reexec := uint64(*args.Reexec); reexec > maxTraceReexec {
+			return nil, fmt.Errorf("trace reexec %d exceeds the maximum of %d", reexec, maxTraceReexec)
+		}
+		config.Reexec = uint64(*args.Reexec)
+	}
+	result, err := tracer.TraceTransaction(ctx, args.Hash, config)
+	if err != nil {
+		return nil, err
+	}
+	return &JSON{result}, nil
+}
+
 // SyncState represents the synchronisation status returned from the `syncing` accessor.
 type SyncState struct {
 	progress ethereum.SyncProgress
@@ -1092,3 +1381,120 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// NewBlocks streams the blocks added to the canonical chain until the context
+// is canceled.
+func (r *Resolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	headers := make(chan *types.Header)
+	sub := r.events.SubscribeNewHeads(headers)
+
+	blocks := make(chan *Block)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), true)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+// NewLogs streams the logs matching the given filter as they are included in
+// new blocks, until the context is canceled. Logs removed by chain reorganisations
+// are not reported. The Logs method answers the query of the same name, which is
+// why the subscription field is called newLogs.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matches := make(chan []*types.Log)
+	sub, err := r.events.SubscribeLogs(crit, matches)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matches:
+				for _, log := range batch {
+					if log.Removed {
+						continue
+					}
+					entry := &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+						log:         log,
+					}
+					select {
+					case logs <- entry:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// PendingTransactions streams the transactions entering the transaction pool
+// until the context is canceled.
+func (r *Resolver) PendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	hashes := make(chan []common.Hash)
+	sub := r.events.SubscribePendingTxs(hashes)
+
+	txs := make(chan *Transaction)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					select {
+					case txs <- &Transaction{backend: r.backend, hash: hash}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs, nil
+}
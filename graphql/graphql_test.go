@@ -17,14 +17,27 @@
 package graphql
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,7 +47,7 @@ func TestBuildSchema(t *testing.T) {
 		t.Fatalf("could not create new node: %v", err)
 	}
 	// Make sure the schema can be parsed and matched up to the object model.
-	if err := newHandler(stack, nil, []string{}, []string{}); err != nil {
+	if err := newHandler(stack, nil, false, []string{}, []string{}, false); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
@@ -90,6 +103,388 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, "404 page not found\n", string(bodyBytes))
 }
 
+// Tests that subscriptions are served over the graphql-ws protocol.
+func TestGraphQLWebsocketSubscription(t *testing.T) {
+	genesis := &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		GasLimit:   8000000,
+		Difficulty: big.NewInt(1),
+	}
+	stack, ethBackend := createChainNode(t, genesis, false)
+	defer stack.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial(strings.Replace(stack.HTTPEndpoint(), "http://", "ws://", 1)+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial graphql websocket: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(id, typ, query string) {
+		msg := wsMessage{ID: id, Type: typ}
+		if query != "" {
+			msg.Payload, _ = json.Marshal(&wsStartPayload{Query: query})
+		}
+		if err := conn.WriteJSON(&msg); err != nil {
+			t.Fatalf("failed to send %s message: %v", typ, err)
+		}
+	}
+	expect := func(id, typ, payload string) {
+		t.Helper()
+
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read %s message: %v", typ, err)
+		}
+		if msg.ID != id || msg.Type != typ {
+			t.Fatalf("message mismatch: have %s/%s, want %s/%s", msg.ID, msg.Type, id, typ)
+		}
+		if payload != "" && string(msg.Payload) != payload {
+			t.Fatalf("payload mismatch: have %s, want %s", msg.Payload, payload)
+		}
+	}
+	send("", wsConnectionInit, "")
+	expect("", wsConnectionAck, "")
+
+	// Start a subscription, then run a query which also ensures that the
+	// subscription is installed by the time its result arrives
+	send("1", wsStart, "subscription { newBlocks { number } }")
+	send("2", wsStart, "{ block { number } }")
+	expect("2", wsData, `{"data":{"block":{"number":"0x0"}}}`)
+	expect("2", wsComplete, "")
+
+	// Import a block and check that it's delivered
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 1, nil)
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block: %v", err)
+	}
+	expect("1", wsData, `{"data":{"newBlocks":{"number":"0x1"}}}`)
+
+	// Stopping the subscription should complete it
+	send("1", wsStop, "")
+	expect("1", wsComplete, "")
+	send("", wsConnectionTerminate, "")
+}
+
+// Tests that websocket upgrades are subject to the virtual host whitelist, even
+// if the request originates from the same host (e.g. DNS rebinding).
+func TestGraphQLWebsocketVHosts(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost: "127.0.0.1",
+		HTTPPort: 0,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &eth.DefaultConfig)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, false, []string{}, []string{"localhost"}, false); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	var (
+		endpoint = strings.Replace(stack.HTTPEndpoint(), "http://", "ws://", 1) + "/graphql"
+		dialer   = websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	)
+	for _, tt := range []struct {
+		host string
+		ok   bool
+	}{
+		{"localhost", true},
+		{"evil.com", false},
+	} {
+		header := http.Header{
+			"Host":            {tt.host},
+			"Origin":          {"http://" + tt.host},
+			"Accept-Encoding": {"gzip"},
+		}
+		conn, resp, err := dialer.Dial(endpoint, header)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("host %s: could not dial graphql websocket: %v", tt.host, err)
+				continue
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Errorf("host %s: websocket upgrade accepted", tt.host)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("host %s: unexpected upgrade failure: %v", tt.host, err)
+		}
+	}
+}
+
+// Tests the raw encodings, proofs, storage ranges and traces exposed over GraphQL.
+func TestGraphQLRawDataProofsAndTraces(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.YoloV2Block = big.NewInt(0)
+
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config:     &config,
+			GasLimit:   8000000,
+			Difficulty: big.NewInt(1),
+			Alloc:      core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer   = types.NewEIP2930Signer(genesis.Config.ChainID)
+		contract = crypto.CreateAddress(sender, 0)
+	)
+	stack, ethBackend := createChainNode(t, genesis, true)
+	defer stack.Close()
+
+	// Deploy a contract setting its storage slot 0 to 1, and call it with an access list
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), common.FromHex("0x600160005500")), signer, key)
+		b.AddTx(tx)
+
+		tx, _ = types.SignTx(types.NewTx(&types.AccessListTx{
+			ChainID:    genesis.Config.ChainID,
+			Nonce:      1,
+			To:         &contract,
+			Gas:        100000,
+			GasPrice:   big.NewInt(1),
+			AccessList: types.AccessList{{Address: contract, StorageKeys: []common.Hash{{}}}},
+		}), signer, key)
+		b.AddTx(tx)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block: %v", err)
+	}
+	var (
+		block    = blocks[0]
+		tx       = block.Transactions()[0]
+		receipts = ethBackend.BlockChain().GetReceiptsByHash(block.Hash())
+		receipt  = receipts[0]
+	)
+	query := fmt.Sprintf(`{
+		block(number: 1) {
+			rawHeader
+			raw
+			transactions { raw rawReceipt }
+			account(address: "%s") {
+				storageRange(count: 10) { entries { hash value } nextKey }
+				proof(slots: ["%s"]) { nonce accountProof storageProof { value proof } }
+			}
+		}
+		traceTransaction(hash: "%s")
+	}`, contract.Hex(), common.Hash{}.Hex(), tx.Hash().Hex())
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req, err := http.NewRequest(http.MethodPost, stack.HTTPEndpoint()+"/graphql", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("could not create http request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp := doHTTPRequest(t, req)
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Block struct {
+				RawHeader    hexutil.Bytes
+				Raw          hexutil.Bytes
+				Transactions []struct {
+					Raw        hexutil.Bytes
+					RawReceipt hexutil.Bytes
+				}
+				Account struct {
+					StorageRange struct {
+						Entries []struct {
+							Hash  common.Hash
+							Value common.Hash
+						}
+						NextKey *common.Hash
+					}
+					Proof struct {
+						Nonce        hexutil.Uint64
+						AccountProof []hexutil.Bytes
+						StorageProof []struct {
+							Value *hexutil.Big
+							Proof []hexutil.Bytes
+						}
+					}
+				}
+			}
+			TraceTransaction struct {
+				Gas    uint64
+				Failed bool
+			}
+		}
+		Errors []interface{}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("query failed: %v", result.Errors)
+	}
+	data := result.Data
+
+	header, _ := rlp.EncodeToBytes(block.Header())
+	assert.Equal(t, hexutil.Bytes(header), data.Block.RawHeader)
+	raw, _ := rlp.EncodeToBytes(block)
+	assert.Equal(t, hexutil.Bytes(raw), data.Block.Raw)
+	if len(data.Block.Transactions) != 2 {
+		t.Fatalf("transaction count mismatch: have %d, want 2", len(data.Block.Transactions))
+	}
+	for i, tx := range block.Transactions() {
+		// Transactions must be in the format accepted by eth_sendRawTransaction
+		rawTx, _ := tx.MarshalBinary()
+		assert.Equal(t, hexutil.Bytes(rawTx), data.Block.Transactions[i].Raw)
+
+		// Legacy receipts are plain RLP lists, typed ones are prefixed by their type
+		rawReceipt, _ := rlp.EncodeToBytes(receipts[i])
+		if tx.Type() != types.LegacyTxType {
+			if err := rlp.DecodeBytes(rawReceipt, &rawReceipt); err != nil {
+				t.Fatalf("failed to unwrap typed receipt: %v", err)
+			}
+		}
+		assert.Equal(t, hexutil.Bytes(rawReceipt), data.Block.Transactions[i].RawReceipt)
+	}
+	if raw := data.Block.Transactions[1].Raw; raw[0] != types.AccessListTxType {
+		t.Fatalf("access list transaction type mismatch: have %#x, want %#x", raw[0], types.AccessListTxType)
+	}
+	if raw := data.Block.Transactions[1].RawReceipt; raw[0] != types.AccessListTxType {
+		t.Fatalf("access list receipt type mismatch: have %#x, want %#x", raw[0], types.AccessListTxType)
+	}
+	var decoded types.Transaction
+	if err := decoded.UnmarshalBinary(data.Block.Transactions[1].Raw); err != nil || decoded.Hash() != block.Transactions()[1].Hash() {
+		t.Fatalf("raw access list transaction not decodable: %v", err)
+	}
+
+	storage := data.Block.Account.StorageRange
+	if len(storage.Entries) != 1 || storage.NextKey != nil {
+		t.Fatalf("storage range mismatch: have %d entries (next %v), want 1", len(storage.Entries), storage.NextKey)
+	}
+	assert.Equal(t, crypto.Keccak256Hash(common.Hash{}.Bytes()), storage.Entries[0].Hash)
+	assert.Equal(t, common.BigToHash(big.NewInt(1)), storage.Entries[0].Value)
+
+	proof := data.Block.Account.Proof
+	assert.Equal(t, hexutil.Uint64(1), proof.Nonce)
+	if len(proof.AccountProof) == 0 || len(proof.StorageProof) != 1 || len(proof.StorageProof[0].Proof) == 0 {
+		t.Fatalf("incomplete proof: %+v", proof)
+	}
+	assert.Equal(t, int64(1), proof.StorageProof[0].Value.ToInt().Int64())
+
+	assert.Equal(t, receipt.GasUsed, data.TraceTransaction.Gas)
+	assert.False(t, data.TraceTransaction.Failed)
+}
+
+// Tests that transaction tracing is only served if enabled, and only with native
+// tracers within the server side limits.
+func TestGraphQLTraceTransactionLimits(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   8000000,
+			Difficulty: big.NewInt(1),
+			Alloc:      core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.NewEIP155Signer(genesis.Config.ChainID)
+	)
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	hash := blocks[0].Transactions()[0].Hash()
+
+	for _, tracing := range []bool{false, true} {
+		stack, ethBackend := createChainNode(t, genesis, tracing)
+		if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+			t.Fatalf("failed to import block: %v", err)
+		}
+		for _, tt := range []struct {
+			args string
+			fail string
+		}{
+			{``, ""},
+			{`, tracer: "callTracer", timeout: "1s"`, ""},
+			{`, tracer: "{result: function() { return 1 }, fault: function() {}}"`, "unknown native tracer"},
+			{`, tracer: "callTracer", timeout: "1h"`, "out of range"},
+			{`, reexec: 100000`, "exceeds the maximum"},
+		} {
+			query := fmt.Sprintf(`{ traceTransaction(hash: "%s"%s) }`, hash.Hex(), tt.args)
+			body, _ := json.Marshal(map[string]string{"query": query})
+			req, err := http.NewRequest(http.MethodPost, stack.HTTPEndpoint()+"/graphql", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal("could not create http request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp := doHTTPRequest(t, req)
+
+			var result struct {
+				Data struct {
+					TraceTransaction json.RawMessage
+				}
+				Errors []struct {
+					Message string
+				}
+			}
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			fail := tt.fail
+			if !tracing {
+				fail = errTracingOff.Error()
+			}
+			switch {
+			case fail == "" && len(result.Errors) != 0:
+				t.Errorf("tracing %v, args %q: query failed: %v", tracing, tt.args, result.Errors)
+			case fail == "" && string(result.Data.TraceTransaction) == "null":
+				t.Errorf("tracing %v, args %q: missing trace", tracing, tt.args)
+			case fail != "" && (len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, fail)):
+				t.Errorf("tracing %v, args %q: error mismatch: have %v, want %q", tracing, tt.args, result.Errors, fail)
+			}
+		}
+		stack.Close()
+	}
+}
+
+// createChainNode creates a node with an ethash-faking Ethereum backend using the
+// given genesis and GraphQL enabled on a random HTTP port, and starts it.
+func createChainNode(t *testing.T, genesis *core.Genesis, tracing bool) (*node.Node, *eth.Ethereum) {
+	stack, err := node.New(&node.Config{
+		HTTPHost: "127.0.0.1",
+		HTTPPort: 0,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	ethConf := eth.DefaultConfig
+	ethConf.Genesis = genesis
+	ethConf.Ethash.PowMode = ethash.ModeFake
+	ethBackend, err := eth.New(stack, &ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, false, []string{}, []string{}, tracing); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	return stack, ethBackend
+}
+
 func createNode(t *testing.T, gqlEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost: "127.0.0.1",
@@ -117,7 +512,7 @@ func createGQLService(t *testing.T, stack *node.Node, endpoint string) {
 	}
 
 	// create gql service
-	err = New(stack, ethBackend.APIBackend, []string{}, []string{})
+	err = New(stack, ethBackend.APIBackend, false, []string{}, []string{}, false)
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
@@ -30,10 +30,13 @@ const schema string = `
     scalar BigInt
     # Long is a 64 bit unsigned integer.
     scalar Long
+    # JSON is an arbitrary JSON value.
+    scalar JSON
 
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -52,6 +55,60 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # StorageRange iterates over the storage of a contract account in the
+        # order of the hashed slot identifiers, starting at the given hash and
+        # returning at most count (up to 1024) entries.
+        storageRange(start: Bytes32, count: Int!): StorageRange!
+        # Proof returns the Merkle proof of the account and the given storage
+        # slots, in the same form as eth_getProof.
+        proof(slots: [Bytes32!]): AccountProof!
+    }
+
+    # StorageRange is a consecutive range of storage entries of an account.
+    type StorageRange {
+        # Entries is the list of storage entries in the range.
+        entries: [StorageEntry!]!
+        # NextKey is the hashed slot identifier of the entry following the range,
+        # or null if the range reaches the end of the storage.
+        nextKey: Bytes32
+    }
+
+    # StorageEntry is a single storage slot of an account.
+    type StorageEntry {
+        # Hash is the keccak256 hash of the slot identifier.
+        hash: Bytes32!
+        # Key is the slot identifier, if its preimage is known to the node.
+        key: Bytes32
+        # Value is the value stored in the slot.
+        value: Bytes32!
+    }
+
+    # AccountProof is the Merkle proof of an account and some of its storage slots.
+    type AccountProof {
+        # AccountProof is the list of RLP encoded trie nodes on the path from the
+        # state root to the account.
+        accountProof: [Bytes!]!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # CodeHash is the keccak256 hash of the account's code.
+        codeHash: Bytes32!
+        # Nonce is the nonce of the account.
+        nonce: Long!
+        # StorageHash is the root hash of the account's storage trie.
+        storageHash: Bytes32!
+        # StorageProof is the list of proofs of the requested storage slots.
+        storageProof: [StorageProof!]!
+    }
+
+    # StorageProof is the Merkle proof of a storage slot.
+    type StorageProof {
+        # Key is the slot identifier.
+        key: Bytes32!
+        # Value is the value stored in the slot.
+        value: BigInt!
+        # Proof is the list of RLP encoded trie nodes on the path from the storage
+        # root to the slot.
+        proof: [Bytes!]!
     }
 
     # Log is an Ethereum event log.
@@ -118,6 +175,13 @@ const schema string = `
         r: BigInt!
         s: BigInt!
         v: BigInt!
+        # Raw is the canonical encoding of the transaction. For typed transactions
+        # this is the type byte followed by the RLP encoding of the payload.
+        raw: Bytes!
+        # RawReceipt is the canonical encoding of the transaction's receipt,
+        # prefixed by the transaction type for typed transactions.
+        # If the transaction has not yet been mined, this field will be null.
+        rawReceipt: Bytes
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -209,6 +273,10 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # RawHeader is the RLP encoding of the block's header.
+        rawHeader: Bytes!
+        # Raw is the RLP encoding of the block.
+        raw: Bytes!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -316,10 +384,32 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # TraceTransaction re-executes a mined transaction and returns its trace,
+        # produced by the given native tracer (callTracer, prestateTracer or
+        # 4byteTracer), or the structured opcode logger if none is specified.
+        # JavaScript tracers are not supported. The timeout of a native tracer is
+        # capped at 10s and the number of blocks re-executed to regenerate state
+        # at 1024. This is only available on full nodes which enabled tracing on
+        # the GraphQL endpoint.
+        traceTransaction(hash: Bytes32!, tracer: String, timeout: String, reexec: Long): JSON
     }
 
     type Mutation {
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription is the root type for push notifications. Subscriptions are
+    # served over the graphql-ws websocket protocol on the GraphQL endpoint.
+    type Subscription {
+        # NewBlocks emits every block added to the canonical chain.
+        newBlocks: Block!
+        # NewLogs emits the logs matching the filter as they are included in new
+        # blocks. Logs removed by chain reorganisations are not reported. It is
+        # not named logs since all root types are served by the same resolver,
+        # which already answers Query.logs.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions emits the transactions entering the transaction pool.
+        pendingTransactions: Transaction!
+    }
 `
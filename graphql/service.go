@@ -17,32 +17,39 @@
 package graphql
 
 import (
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 )
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
+// New constructs a new GraphQL service instance. Light clients must set lightMode
+// for log subscriptions to be served from retrieved receipts. Transaction tracing
+// is only served if explicitly enabled.
+func New(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string, tracing bool) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, lightMode, cors, vhosts, tracing)
 }
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+func newHandler(stack *node.Node, backend ethapi.Backend, lightMode bool, cors, vhosts []string, tracing bool) error {
+	q := Resolver{backend: backend, tracing: tracing}
+	if backend != nil {
+		q.events = filters.NewEventSystem(backend, lightMode)
+		stack.RegisterLifecycle(&eventService{q.events})
+	}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return err
 	}
 	h := &relay.Handler{Schema: s}
-	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
+	handler := node.NewHTTPHandlerStack(newWebsocketHandler(s, h, cors), cors, vhosts)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
@@ -50,3 +57,18 @@ func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string)
 
 	return nil
 }
+
+// eventService stops the chain event source of the GraphQL subscriptions when
+// the node shuts down, ending all running subscriptions.
+type eventService struct {
+	events *filters.EventSystem
+}
+
+// Start implements node.Lifecycle, the event system is already running.
+func (s *eventService) Start() error { return nil }
+
+// Stop implements node.Lifecycle, closing the event system.
+func (s *eventService) Stop() error {
+	s.events.Close()
+	return nil
+}
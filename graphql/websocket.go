@@ -0,0 +1,292 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// wsProtocol is the websocket sub-protocol name of the graphql-ws protocol.
+	wsProtocol = "graphql-ws"
+
+	wsKeepAliveInterval = 15 * time.Second
+	wsWriteTimeout      = 10 * time.Second
+	wsReadLimit         = 1024 * 1024
+)
+
+// Message types of the graphql-ws protocol.
+const (
+	wsConnectionInit      = "connection_init"      // client -> server
+	wsConnectionTerminate = "connection_terminate" // client -> server
+	wsStart               = "start"                // client -> server
+	wsStop                = "stop"                 // client -> server
+	wsConnectionAck       = "connection_ack"       // server -> client
+	wsConnectionError     = "connection_error"     // server -> client
+	wsKeepAlive           = "ka"                   // server -> client
+	wsData                = "data"                 // server -> client
+	wsError               = "error"                // server -> client
+	wsComplete            = "complete"             // server -> client
+)
+
+// wsMessage is a graphql-ws protocol message.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsStartPayload is the payload of a start message, the operation to execute.
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsErrorPayload is the payload of error messages.
+type wsErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// newWebsocketHandler returns a handler serving GraphQL operations (notably
+// subscriptions) over websocket connections using the graphql-ws protocol. All
+// requests which aren't websocket upgrades are passed on to next.
+func newWebsocketHandler(schema *graphql.Schema, next http.Handler, cors []string) http.Handler {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{wsProtocol},
+		CheckOrigin:  wsOriginValidator(cors),
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Debug("GraphQL websocket upgrade failed", "err", err)
+			return
+		}
+		newWebsocketConn(schema, conn).serve()
+	})
+}
+
+// wsOriginValidator returns a function checking the origin of websocket upgrade
+// requests against the CORS origins. Same-origin requests are always accepted.
+func wsOriginValidator(cors []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range cors {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// websocketConn is a single graphql-ws connection, running any number of
+// concurrent operations.
+type websocketConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+
+	ctx    context.Context // canceled when the connection is closed
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // running operations
+
+	writeLock sync.Mutex // serializes writes to the connection
+
+	opsLock sync.Mutex
+	ops     map[string]context.CancelFunc // running operations by id
+}
+
+func newWebsocketConn(schema *graphql.Schema, conn *websocket.Conn) *websocketConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &websocketConn{
+		schema: schema,
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+		ops:    make(map[string]context.CancelFunc),
+	}
+}
+
+// serve reads and handles the client messages until the connection is closed
+// or terminated by the client.
+func (c *websocketConn) serve() {
+	defer func() {
+		c.cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	initialized := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			log.Trace("GraphQL websocket connection closed", "err", err)
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			if initialized {
+				continue
+			}
+			initialized = true
+			if err := c.write(&wsMessage{Type: wsConnectionAck}); err != nil {
+				return
+			}
+			c.wg.Add(1)
+			go c.keepAlive()
+
+		case wsStart:
+			if !initialized {
+				c.writeError(wsConnectionError, "", "connection not initialized")
+				return
+			}
+			var payload wsStartPayload
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				c.writeError(wsError, msg.ID, "invalid start payload: "+err.Error())
+				continue
+			}
+			c.start(msg.ID, &payload)
+
+		case wsStop:
+			c.opsLock.Lock()
+			if cancel := c.ops[msg.ID]; cancel != nil {
+				cancel()
+			}
+			c.opsLock.Unlock()
+
+		case wsConnectionTerminate:
+			return
+
+		default:
+			c.writeError(wsError, msg.ID, "unknown message type: "+msg.Type)
+		}
+	}
+}
+
+// start launches a new operation, streaming its results to the client until it
+// finishes or the client stops it.
+func (c *websocketConn) start(id string, payload *wsStartPayload) {
+	if id == "" {
+		c.writeError(wsError, id, "missing operation id")
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	c.opsLock.Lock()
+	if _, ok := c.ops[id]; ok {
+		c.opsLock.Unlock()
+		cancel()
+		c.writeError(wsError, id, "duplicate operation id")
+		return
+	}
+	c.ops[id] = cancel
+	c.opsLock.Unlock()
+
+	done := func() {
+		c.opsLock.Lock()
+		delete(c.ops, id)
+		c.opsLock.Unlock()
+		cancel()
+	}
+	results, err := c.schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		done()
+		c.writeError(wsError, id, err.Error())
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer done()
+
+		// Results are consumed until the operation ends, even if the client is
+		// gone, to let the executor shut down cleanly.
+		failed := false
+		for result := range results {
+			if failed {
+				continue
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				log.Warn("Failed to encode GraphQL result", "err", err)
+				continue
+			}
+			if err := c.write(&wsMessage{ID: id, Type: wsData, Payload: data}); err != nil {
+				failed = true
+				c.conn.Close() // unblocks the read loop, tearing down the connection
+				cancel()
+			}
+		}
+		if !failed && c.ctx.Err() == nil {
+			c.write(&wsMessage{ID: id, Type: wsComplete})
+		}
+	}()
+}
+
+// keepAlive periodically sends keep-alive messages until the connection closes.
+func (c *websocketConn) keepAlive() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(&wsMessage{Type: wsKeepAlive}); err != nil {
+				c.conn.Close() // unblocks the read loop, tearing down the connection
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// writeError sends an error message of the given type to the client.
+func (c *websocketConn) writeError(typ string, id string, message string) {
+	payload, _ := json.Marshal(&wsErrorPayload{Message: message})
+	c.write(&wsMessage{ID: id, Type: typ, Payload: payload})
+}
+
+// write sends a message to the client.
+func (c *websocketConn) write(msg *wsMessage) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
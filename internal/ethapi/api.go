@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -64,6 +65,40 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// gasPriceHistoryResult is the gas price history of a range of blocks.
+type gasPriceHistoryResult struct {
+	OldestBlock       *hexutil.Big     `json:"oldestBlock"`
+	GasPrices         [][]*hexutil.Big `json:"gasPrices,omitempty"`
+	GasUsedRatio      []float64        `json:"gasUsedRatio"`
+	SuggestedGasPrice *hexutil.Big     `json:"suggestedGasPrice"`
+}
+
+// GasPriceHistory returns the gas price history of up to blockCount blocks
+// ending with lastBlock: for each block, the requested percentiles of the gas
+// prices paid weighted by gas used, and the ratio of gas used to the gas limit.
+// The current gas price suggestion is included too.
+func (s *PublicEthereumAPI) GasPriceHistory(ctx context.Context, blockCount hexutil.Uint, lastBlock rpc.BlockNumber, percentiles []float64) (*gasPriceHistoryResult, error) {
+	history, err := s.b.GasPriceHistory(ctx, int(blockCount), lastBlock, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &gasPriceHistoryResult{
+		OldestBlock:       (*hexutil.Big)(new(big.Int).SetUint64(history.OldestBlock)),
+		GasUsedRatio:      history.GasUsedRatios,
+		SuggestedGasPrice: (*hexutil.Big)(history.Suggestion),
+	}
+	if history.Prices != nil {
+		result.GasPrices = make([][]*hexutil.Big, len(history.Prices))
+		for i, prices := range history.Prices {
+			result.GasPrices[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				result.GasPrices[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -139,6 +174,75 @@ func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	}
 }
 
+// RPCTxDrop is the record of a transaction which left the pool without being
+// included in the chain.
+type RPCTxDrop struct {
+	Hash        common.Hash    `json:"hash"`
+	From        common.Address `json:"from"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	Reason      string         `json:"reason"`
+	Replacement *common.Hash   `json:"replacement,omitempty"`
+	Time        hexutil.Uint64 `json:"time"`
+}
+
+func newRPCTxDrop(drop *core.TxDrop) *RPCTxDrop {
+	result := &RPCTxDrop{
+		Hash:   drop.Hash,
+		From:   drop.From,
+		Nonce:  hexutil.Uint64(drop.Nonce),
+		Reason: drop.Reason.String(),
+		Time:   hexutil.Uint64(drop.Time.Unix()),
+	}
+	if drop.Replacement != (common.Hash{}) {
+		result.Replacement = &drop.Replacement
+	}
+	return result
+}
+
+// DropReason returns why a transaction recently left the pool without being
+// included in the chain. Nil is returned if the transaction is still pooled, or
+// if its drop is unknown or already forgotten.
+func (s *PublicTxPoolAPI) DropReason(hash common.Hash) *RPCTxDrop {
+	if s.b.GetPoolTransaction(hash) != nil {
+		return nil
+	}
+	if drop := s.b.TxPoolDropReason(hash); drop != nil {
+		return newRPCTxDrop(drop)
+	}
+	return nil
+}
+
+// DroppedTransactions creates a subscription that is notified each time a
+// transaction leaves the pool without being included in the chain, along with
+// the reason of its removal.
+func (s *PublicTxPoolAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.TxDropEvent, 128)
+		sub := s.b.SubscribeTxDropEvent(drops)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-drops:
+				for _, drop := range ev.Drops {
+					notifier.Notify(rpcSub.ID, newRPCTxDrop(drop))
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // Inspect retrieves the content of the transaction pool and flattens it into an
 // easily inspectable list.
 func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
@@ -415,7 +519,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -759,6 +863,8 @@ type CallArgs struct {
 	GasPrice *hexutil.Big    `json:"gasPrice"`
 	Value    *hexutil.Big    `json:"value"`
 	Data     *hexutil.Bytes  `json:"data"`
+
+	AccessList *types.AccessList `json:"accessList,omitempty"`
 }
 
 // ToMessage converts CallArgs to the Message type used by the core evm
@@ -796,7 +902,11 @@ func (args *CallArgs) ToMessage(globalGasCap uint64) types.Message {
 		data = []byte(*args.Data)
 	}
 
-	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, accessList, false)
 	return msg
 }
 
@@ -822,32 +932,8 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 		return nil, err
 	}
 	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
-		// Override account nonce.
-		if account.Nonce != nil {
-			state.SetNonce(addr, uint64(*account.Nonce))
-		}
-		// Override account(contract) code.
-		if account.Code != nil {
-			state.SetCode(addr, *account.Code)
-		}
-		// Override account balance.
-		if account.Balance != nil {
-			state.SetBalance(addr, (*big.Int)(*account.Balance))
-		}
-		if account.State != nil && account.StateDiff != nil {
-			return nil, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
-		}
-		// Replace entire state if caller requires.
-		if account.State != nil {
-			state.SetStorage(addr, *account.State)
-		}
-		// Apply state diff into specified accounts.
-		if account.StateDiff != nil {
-			for key, value := range *account.StateDiff {
-				state.SetState(addr, key, value)
-			}
-		}
+	if err := applyStateOverrides(state, overrides); err != nil {
+		return nil, err
 	}
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -863,7 +949,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 
 	// Get a new instance of the EVM.
 	msg := args.ToMessage(globalGasCap)
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vmCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -891,6 +977,38 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	return result, nil
 }
 
+// applyStateOverrides overrides the fields of the specified accounts in state.
+func applyStateOverrides(state *state.StateDB, overrides map[common.Address]account) error {
+	for addr, account := range overrides {
+		// Override account nonce.
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		// Override account(contract) code.
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		// Override account balance.
+		if account.Balance != nil {
+			state.SetBalance(addr, (*big.Int)(*account.Balance))
+		}
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		// Replace entire state if caller requires.
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		// Apply state diff into specified accounts.
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
 func newRevertError(result *core.ExecutionResult) *revertError {
 	reason, errUnpack := abi.UnpackRevert(result.Revert())
 	err := errors.New("execution reverted")
@@ -943,6 +1061,159 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	return result.Return(), result.Err
 }
 
+// BundleBlockArgs overrides the fields of the block a transaction bundle is
+// simulated in. The block defaults to a child of the state block.
+type BundleBlockArgs struct {
+	Number    *hexutil.Big    `json:"number"`
+	Timestamp *hexutil.Uint64 `json:"timestamp"`
+	Coinbase  *common.Address `json:"coinbase"`
+}
+
+// BundleTxResult is the outcome of a single transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash       common.Hash    `json:"txHash"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	ReturnData   hexutil.Bytes  `json:"returnData"`
+	Logs         []*types.Log   `json:"logs"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+}
+
+// BundleResult is the outcome of a simulated transaction bundle.
+type BundleResult struct {
+	StateBlockNumber hexutil.Uint64    `json:"stateBlockNumber"`
+	Results          []*BundleTxResult `json:"results"`
+	GasUsed          hexutil.Uint64    `json:"gasUsed"`
+	CoinbaseDiff     *hexutil.Big      `json:"coinbaseDiff"`
+}
+
+// DoCallBundle applies a bundle of signed transactions in order on top of the
+// state of the given block. The transactions are executed in the context of a
+// child block, with the fields specified in blockArgs overridden.
+func DoCallBundle(ctx context.Context, b Backend, txs []*types.Transaction, blockNrOrHash rpc.BlockNumberOrHash, blockArgs *BundleBlockArgs, overrides map[common.Address]account, timeout time.Duration) (*BundleResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing bundle finished", "txs", len(txs), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(txs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	state, parent, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := applyStateOverrides(state, overrides); err != nil {
+		return nil, err
+	}
+	// Assemble the block the bundle is executed in
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+		Difficulty: parent.Difficulty,
+	}
+	if blockArgs != nil {
+		if blockArgs.Number != nil {
+			header.Number = blockArgs.Number.ToInt()
+		}
+		if blockArgs.Timestamp != nil {
+			header.Time = uint64(*blockArgs.Timestamp)
+		}
+		if blockArgs.Coinbase != nil {
+			header.Coinbase = *blockArgs.Coinbase
+		}
+	}
+	// Setup context so it may be cancelled when the bundle has completed
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		signer   = types.MakeSigner(b.ChainConfig(), header.Number)
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		coinbase = state.GetBalance(header.Coinbase)
+		result   = &BundleResult{StateBlockNumber: hexutil.Uint64(parent.Number.Uint64())}
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		state.Prepare(tx.Hash(), common.Hash{}, i)
+
+		evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		// The engine can't tell the author of an unsealed block, set it explicitly
+		evm.Coinbase = header.Coinbase
+
+		// Wait for the context to be done and cancel the evm
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%s): %w", i, tx.Hash().Hex(), err)
+		}
+		state.Finalise(b.ChainConfig().IsEIP158(header.Number))
+
+		txResult := &BundleTxResult{
+			TxHash:     tx.Hash(),
+			GasUsed:    hexutil.Uint64(res.UsedGas),
+			ReturnData: res.ReturnData,
+			Logs:       state.GetLogs(tx.Hash()),
+		}
+		if txResult.Logs == nil {
+			txResult.Logs = []*types.Log{}
+		}
+		for _, log := range txResult.Logs {
+			log.BlockNumber = header.Number.Uint64()
+		}
+		if res.Err != nil {
+			txResult.Error = res.Err.Error()
+			if reason, err := abi.UnpackRevert(res.Revert()); err == nil {
+				txResult.RevertReason = reason
+			}
+		}
+		result.Results = append(result.Results, txResult)
+		result.GasUsed += hexutil.Uint64(res.UsedGas)
+	}
+	result.CoinbaseDiff = (*hexutil.Big)(new(big.Int).Sub(state.GetBalance(header.Coinbase), coinbase))
+	return result, nil
+}
+
+// CallBundle simulates a bundle of signed transactions, applying them in order
+// on top of the state of the given block. The bundle is executed in a child of
+// that block, whose number, timestamp and coinbase may be overridden, as well as
+// the fields of any account.
+//
+// The outcome of every transaction is returned, along with the change of the
+// coinbase balance caused by the bundle. A transaction failing validation fails
+// the whole simulation.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, encodedTxs []hexutil.Bytes, blockNrOrHash rpc.BlockNumberOrHash, blockArgs *BundleBlockArgs, overrides *map[common.Address]account) (*BundleResult, error) {
+	txs := make([]*types.Transaction, len(encodedTxs))
+	for i, encoded := range encodedTxs {
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalBinary(encoded); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	var accounts map[common.Address]account
+	if overrides != nil {
+		accounts = *overrides
+	}
+	return DoCallBundle(ctx, s.b, txs, blockNrOrHash, blockArgs, accounts, 5*time.Second)
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1062,6 +1333,91 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, bl
 	return DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap())
 }
 
+// accessListResult is the result of the `eth_createAccessList` RPC call. It
+// contains an error if the transaction itself failed.
+type accessListResult struct {
+	Accesslist         *types.AccessList `json:"accessList"`
+	Error              string            `json:"error,omitempty"`
+	GasUsed            hexutil.Uint64    `json:"gasUsed"`
+	GasUsedWithoutList hexutil.Uint64    `json:"gasUsedWithoutAccessList"`
+}
+
+// CreateAccessList creates an EIP-2930 type AccessList for the given transaction.
+// BlockNrOrHash can be specified to create the accessList on top of a certain state,
+// otherwise the pending block is used.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	if err != nil {
+		return nil, err
+	}
+	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
+	if vmerr != nil {
+		result.Error = vmerr.Error()
+	}
+	// Execute the call once more without any access list to report the savings
+	args.AccessList = nil
+	plain, err := DoCall(ctx, s.b, args, bNrOrHash, nil, vm.Config{}, 0, s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	result.GasUsedWithoutList = hexutil.Uint64(plain.UsedGas)
+	return result, nil
+}
+
+// AccessList creates an access list for the given transaction.
+// If the accesslist creation fails an error is returned.
+// If the transaction itself fails, a vmErr is returned.
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args CallArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+	// Retrieve the execution context
+	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if db == nil || err != nil {
+		return nil, 0, nil, err
+	}
+	// Extract the sender and recipient, they are warm regardless of the list
+	var from common.Address
+	if args.From != nil {
+		from = *args.From
+	}
+	var to common.Address
+	if args.To != nil {
+		to = *args.To
+	} else {
+		to = crypto.CreateAddress(from, db.GetNonce(from))
+	}
+	// Retrieve the precompiles since they don't need to be added to the access list
+	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number))
+
+	// Create an initial tracer
+	prevTracer := vm.NewAccessListTracer(nil, from, to, precompiles)
+	if args.AccessList != nil {
+		prevTracer = vm.NewAccessListTracer(*args.AccessList, from, to, precompiles)
+	}
+	for {
+		// Retrieve the current access list to expand
+		accessList := prevTracer.AccessList()
+		log.Trace("Creating access list", "input", accessList)
+
+		args.AccessList = &accessList
+
+		// Apply the transaction with the access list tracer
+		tracer := vm.NewAccessListTracer(accessList, from, to, precompiles)
+		config := vm.Config{Tracer: tracer, Debug: true}
+
+		res, err := DoCall(ctx, b, args, blockNrOrHash, nil, config, 0, b.RPCGasCap())
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v", err)
+		}
+		if tracer.Equal(prevTracer) {
+			return accessList, res.UsedGas, res.Err, nil
+		}
+		prevTracer = tracer
+	}
+}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
@@ -1205,33 +1561,43 @@ func (s *PublicBlockChainAPI) rpcMarshalBlock(ctx context.Context, b *types.Bloc
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        *common.Hash    `json:"blockHash"`
-	BlockNumber      *hexutil.Big    `json:"blockNumber"`
-	From             common.Address  `json:"from"`
-	Gas              hexutil.Uint64  `json:"gas"`
-	GasPrice         *hexutil.Big    `json:"gasPrice"`
-	Hash             common.Hash     `json:"hash"`
-	Input            hexutil.Bytes   `json:"input"`
-	Nonce            hexutil.Uint64  `json:"nonce"`
-	To               *common.Address `json:"to"`
-	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
-	Value            *hexutil.Big    `json:"value"`
-	V                *hexutil.Big    `json:"v"`
-	R                *hexutil.Big    `json:"r"`
-	S                *hexutil.Big    `json:"s"`
+	BlockHash        *common.Hash      `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	Type             hexutil.Uint64    `json:"type"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
 // representation, with the given location metadata set (if available).
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
-	var signer types.Signer = types.FrontierSigner{}
+	// Determine the signer. For replay-protected transactions, use the most permissive
+	// signer, because we assume that signers are backwards-compatible with old
+	// transactions. For non-protected transactions, the homestead signer is used
+	// because the return value of ChainId is zero for those transactions.
+	var signer types.Signer
 	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	} else {
+		signer = types.HomesteadSigner{}
 	}
 	from, _ := types.Sender(signer, tx)
 	v, r, s := tx.RawSignatureValues()
 
 	result := &RPCTransaction{
+		Type:     hexutil.Uint64(tx.Type()),
 		From:     from,
 		Gas:      hexutil.Uint64(tx.Gas()),
 		GasPrice: (*hexutil.Big)(tx.GasPrice()),
@@ -1249,6 +1615,11 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
 		result.TransactionIndex = (*hexutil.Uint64)(&index)
 	}
+	if tx.Type() == types.AccessListTxType {
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
 	return result
 }
 
@@ -1272,7 +1643,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1399,7 +1770,7 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 		}
 	}
 	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1417,10 +1788,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	}
 	receipt := receipts[index]
 
-	var signer types.Signer = types.FrontierSigner{}
-	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
-	}
+	// Derive the sender.
+	bigblock := new(big.Int).SetUint64(blockNumber)
+	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
@@ -1435,6 +1805,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"type":              hexutil.Uint(tx.Type()),
 	}
 
 	// Assign receipt status or post state.
@@ -1478,6 +1849,10 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// For non-legacy transactions
+	AccessList *types.AccessList `json:"accessList,omitempty"`
+	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1523,11 +1898,12 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			input = args.Data
 		}
 		callArgs := CallArgs{
-			From:     &args.From, // From shouldn't be nil
-			To:       args.To,
-			GasPrice: args.GasPrice,
-			Value:    args.Value,
-			Data:     input,
+			From:       &args.From, // From shouldn't be nil
+			To:         args.To,
+			GasPrice:   args.GasPrice,
+			Value:      args.Value,
+			Data:       input,
+			AccessList: args.AccessList,
 		}
 		pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 		estimated, err := DoEstimateGas(ctx, b, callArgs, pendingBlockNr, b.RPCGasCap())
@@ -1537,6 +1913,10 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		args.Gas = &estimated
 		log.Trace("Estimate gas usage automatically", "gas", args.Gas)
 	}
+	if args.ChainID == nil {
+		id := (*hexutil.Big)(b.ChainConfig().ChainID)
+		args.ChainID = id
+	}
 	return nil
 }
 
@@ -1547,10 +1927,29 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Data != nil {
 		input = *args.Data
 	}
-	if args.To == nil {
-		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	var data types.TxData
+	if args.AccessList == nil {
+		data = &types.LegacyTx{
+			To:       args.To,
+			Nonce:    uint64(*args.Nonce),
+			Gas:      uint64(*args.Gas),
+			GasPrice: (*big.Int)(args.GasPrice),
+			Value:    (*big.Int)(args.Value),
+			Data:     input,
+		}
+	} else {
+		data = &types.AccessListTx{
+			To:         args.To,
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			Gas:        uint64(*args.Gas),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: *args.AccessList,
+		}
 	}
-	return types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	return types.NewTx(data)
 }
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
@@ -1618,7 +2017,7 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 	}
 	// Assemble the transaction and obtain rlp
 	tx := args.toTransaction()
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1629,12 +2028,35 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateTransaction will add the signed transaction to the transaction pool
+// without propagating it to the network: it is only included in blocks mined by
+// this node. If an expiry block is given, the transaction is dropped once the
+// chain reaches it without including the transaction.
+func (s *PublicTransactionPoolAPI) SendPrivateTransaction(ctx context.Context, encodedTx hexutil.Bytes, expiry *hexutil.Uint64) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	var block uint64
+	if expiry != nil {
+		block = uint64(*expiry)
+	}
+	if err := s.b.SendPrivateTx(ctx, tx, block); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To(), "expiry", block)
+	return tx.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -1690,7 +2112,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1714,7 +2136,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
+			signer = types.LatestSignerForChainID(tx.ChainId())
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
@@ -1755,7 +2177,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
 		if p.Protected() {
-			signer = types.NewEIP155Signer(p.ChainId())
+			signer = types.LatestSignerForChainID(p.ChainId())
 		}
 		wantSigHash := signer.Hash(matchTx)
 
@@ -1899,8 +2321,8 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 }
 
 // SetHead rewinds the head of the blockchain to a previous block.
-func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
-	api.b.SetHead(uint64(number))
+func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) error {
+	return api.b.SetHead(uint64(number))
 }
 
 // PublicNetAPI offers network related RPC methods
@@ -239,8 +239,11 @@ func (s *LightEthereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
-			Public:    true,
+			Service: filters.NewPublicFilterAPI(s.ApiBackend, true, filters.Config{
+				RangeCap:  s.config.RPCLogRangeCap,
+				ResultCap: s.config.RPCLogResultCap,
+			}),
+			Public: true,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -0,0 +1,171 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultBlockBuilder is the name of the block building strategy used unless
+// configured otherwise.
+const DefaultBlockBuilder = "price"
+
+// TransactionOrder is a sequence of transactions to fill a block with. The
+// worker repeatedly peeks at the next transaction and tries to apply it, then
+// shifts in the next transaction of the same sender if it was included, or pops
+// it, skipping all remaining transactions of the sender, if it can't be.
+type TransactionOrder interface {
+	// Peek returns the next transaction to include, nil if there are no more.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction with the following one of the same
+	// sender.
+	Shift()
+
+	// Pop removes the next transaction, along with all following ones of the
+	// same sender.
+	Pop()
+}
+
+// BlockBuilder is a block building strategy, deciding which of the pending
+// transactions get included in the blocks built by the miner, and in which
+// order.
+type BlockBuilder interface {
+	// Name returns the name the strategy is selected by.
+	Name() string
+
+	// Order returns the sequences of transactions to fill the block with the
+	// given header with, committed one after the other. Pending contains the
+	// executable transactions of the pool grouped by sender and sorted by nonce,
+	// locals the senders considered local by the pool. Pending is owned by the
+	// builder and may be modified.
+	Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) []TransactionOrder
+}
+
+// priceBuilder orders transactions by gas price, honouring the nonces of the
+// senders, committing all local transactions before remote ones.
+type priceBuilder struct{}
+
+func (priceBuilder) Name() string { return "price" }
+
+func (priceBuilder) Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) []TransactionOrder {
+	// Split the pending transactions into locals and remotes
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
+	for _, account := range locals {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	var orders []TransactionOrder
+	if len(localTxs) > 0 {
+		orders = append(orders, types.NewTransactionsByPriceAndNonce(signer, localTxs))
+	}
+	if len(remoteTxs) > 0 {
+		orders = append(orders, types.NewTransactionsByPriceAndNonce(signer, remoteTxs))
+	}
+	return orders
+}
+
+// fcfsBuilder orders transactions by the time they were first seen, honouring
+// the nonces of the senders, regardless of their gas price or origin.
+type fcfsBuilder struct{}
+
+func (fcfsBuilder) Name() string { return "fcfs" }
+
+func (fcfsBuilder) Order(header *types.Header, signer types.Signer, pending map[common.Address]types.Transactions, locals []common.Address) []TransactionOrder {
+	if len(pending) == 0 {
+		return nil
+	}
+	return []TransactionOrder{newTransactionsByTimeAndNonce(signer, pending)}
+}
+
+// txsByTime is a heap of transactions sorted by the time they were first seen.
+type txsByTime types.Transactions
+
+func (s txsByTime) Len() int { return len(s) }
+func (s txsByTime) Less(i, j int) bool {
+	if s[i].Time().Equal(s[j].Time()) {
+		return s[i].GasPriceCmp(s[j]) > 0
+	}
+	return s[i].Time().Before(s[j].Time())
+}
+func (s txsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// transactionsByTimeAndNonce is a transaction order returning the transactions
+// in the order they were first seen, while honouring the nonces of the senders.
+type transactionsByTimeAndNonce struct {
+	txs    map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads  txsByTime                             // Next transaction for each unique account (time heap)
+	signer types.Signer                          // Signer for the set of transactions
+}
+
+func newTransactionsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions) *transactionsByTimeAndNonce {
+	heads := make(txsByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		heads = append(heads, accTxs[0])
+		// Ensure the sender address is from the signer
+		acc, _ := types.Sender(signer, accTxs[0])
+		txs[acc] = accTxs[1:]
+		if from != acc {
+			delete(txs, from)
+		}
+	}
+	heap.Init(&heads)
+
+	return &transactionsByTimeAndNonce{
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
+	}
+}
+
+func (t *transactionsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+func (t *transactionsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+func (t *transactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
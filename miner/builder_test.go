@@ -0,0 +1,203 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// builderTestTx creates a signed transaction, waiting a bit so that subsequently
+// created transactions are seen strictly later.
+func builderTestTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, price int64) *types.Transaction {
+	time.Sleep(time.Millisecond)
+	tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// collectOrders drains the transaction orders, returning the transactions in
+// the order they would be committed, assuming all of them get included.
+func collectOrders(orders []TransactionOrder) []*types.Transaction {
+	var txs []*types.Transaction
+	for _, order := range orders {
+		for tx := order.Peek(); tx != nil; tx = order.Peek() {
+			txs = append(txs, tx)
+			order.Shift()
+		}
+	}
+	return txs
+}
+
+func checkOrder(t *testing.T, have []*types.Transaction, want []*types.Transaction) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i].Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have nonce %d price %v, want nonce %d price %v", i, have[i].Nonce(), have[i].GasPrice(), want[i].Nonce(), want[i].GasPrice())
+		}
+	}
+}
+
+// Tests that the first-come-first-served builder orders transactions by arrival,
+// regardless of their gas price, while keeping the nonces of the senders in order.
+func TestFCFSBlockBuilder(t *testing.T) {
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+	)
+	// Sign the second nonce of the first account before the second account's
+	// transaction arrives, it must still wait for the first nonce
+	a1 := builderTestTx(t, key1, 1, 5)
+	b0 := builderTestTx(t, key2, 0, 3)
+	a0 := builderTestTx(t, key1, 0, 1)
+	b1 := builderTestTx(t, key2, 1, 2)
+
+	pending := map[common.Address]types.Transactions{
+		addr1: {a0, a1},
+		addr2: {b0, b1},
+	}
+	orders := fcfsBuilder{}.Order(nil, types.HomesteadSigner{}, pending, []common.Address{addr2})
+	checkOrder(t, collectOrders(orders), []*types.Transaction{b0, a0, a1, b1})
+}
+
+// Tests that the default builder commits local transactions first, ordering both
+// locals and remotes by gas price.
+func TestPriceBlockBuilder(t *testing.T) {
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		key3, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		addr3   = crypto.PubkeyToAddress(key3.PublicKey)
+	)
+	a0 := builderTestTx(t, key1, 0, 1)
+	b0 := builderTestTx(t, key2, 0, 3)
+	c0 := builderTestTx(t, key3, 0, 2)
+
+	pending := map[common.Address]types.Transactions{
+		addr1: {a0},
+		addr2: {b0},
+		addr3: {c0},
+	}
+	orders := priceBuilder{}.Order(nil, types.HomesteadSigner{}, pending, []common.Address{addr1})
+	if len(orders) != 2 {
+		t.Fatalf("order count mismatch: have %d, want %d", len(orders), 2)
+	}
+	checkOrder(t, collectOrders(orders), []*types.Transaction{a0, b0, c0})
+}
+
+// testBlockBuilder is a block building strategy including no transactions.
+type testBlockBuilder struct{}
+
+func (testBlockBuilder) Name() string { return "empty" }
+
+func (testBlockBuilder) Order(*types.Header, types.Signer, map[common.Address]types.Transactions, []common.Address) []TransactionOrder {
+	return nil
+}
+
+// Tests that block building strategies can be registered and switched between.
+func TestBlockBuilderSelection(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	if name := w.blockBuilder().Name(); name != DefaultBlockBuilder {
+		t.Fatalf("default block builder mismatch: have %s, want %s", name, DefaultBlockBuilder)
+	}
+	if err := w.setBlockBuilder("empty"); err == nil {
+		t.Fatalf("unknown block builder selected")
+	}
+	if err := w.registerBlockBuilder(testBlockBuilder{}); err != nil {
+		t.Fatalf("failed to register block builder: %v", err)
+	}
+	if err := w.registerBlockBuilder(testBlockBuilder{}); err == nil {
+		t.Fatalf("block builder registered twice")
+	}
+	if err := w.setBlockBuilder("empty"); err != nil {
+		t.Fatalf("failed to select block builder: %v", err)
+	}
+	if name := w.blockBuilder().Name(); name != "empty" {
+		t.Fatalf("block builder mismatch: have %s, want %s", name, "empty")
+	}
+	if err := w.setBlockBuilder("fcfs"); err != nil {
+		t.Fatalf("failed to select block builder: %v", err)
+	}
+}
+
+// Tests that switching the block building strategy while new work is being
+// committed does not deadlock the worker.
+func TestBlockBuilderSwitchDuringCommit(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		stop = make(chan struct{})
+		done = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := w.setBlockBuilder([]string{"fcfs", DefaultBlockBuilder}[i%2]); err != nil {
+				t.Errorf("failed to select block builder: %v", err)
+				return
+			}
+			w.setExtra([]byte{byte(i)})
+			w.setEtherbase(testBankAddress)
+		}
+	}()
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		for i := 0; i < 500; i++ {
+			w.commitNewWork(nil, false, time.Now().Unix())
+		}
+	}()
+	select {
+	case <-committed:
+	case <-time.After(20 * time.Second):
+		t.Fatalf("worker deadlocked while switching block builders")
+	}
+	close(stop)
+	<-done
+}
@@ -50,6 +50,9 @@ type Config struct {
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 	Noverify  bool           // Disable remote mining solution verification(only useful in ethash).
+
+	BlockBuilder string `toml:",omitempty"` // Name of the block building strategy (default = price)
+	RemoteSeal   bool   `toml:",omitempty"` // Permit mining without a local signer, leaving sealing to the block template API (clique)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -193,6 +196,35 @@ func (miner *Miner) PendingBlock() *types.Block {
 	return miner.worker.pendingBlock()
 }
 
+// BlockTemplate returns the header of the most recent block waiting to be
+// sealed, along with the hash an external sealer needs to seal.
+func (miner *Miner) BlockTemplate() (*types.Header, common.Hash, error) {
+	return miner.worker.sealingWork()
+}
+
+// SubmitSealedBlock hands a header sealed by an external sealer to the miner,
+// which completes the matching block and imports it into the chain.
+func (miner *Miner) SubmitSealedBlock(header *types.Header) (*types.Block, error) {
+	return miner.worker.submitSealedBlock(header)
+}
+
+// RegisterBlockBuilder makes a custom block building strategy available to be
+// selected by its name.
+func (miner *Miner) RegisterBlockBuilder(builder BlockBuilder) error {
+	return miner.worker.registerBlockBuilder(builder)
+}
+
+// SetBlockBuilder switches the block building strategy of the miner, taking
+// effect from the next block built.
+func (miner *Miner) SetBlockBuilder(name string) error {
+	return miner.worker.setBlockBuilder(name)
+}
+
+// BlockBuilder returns the name of the block building strategy in use.
+func (miner *Miner) BlockBuilder() string {
+	return miner.worker.blockBuilder().Name()
+}
+
 func (miner *Miner) SetEtherbase(addr common.Address) {
 	miner.coinbase = addr
 	miner.worker.setEtherbase(addr)
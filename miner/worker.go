@@ -19,7 +19,10 @@ package miner
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -77,6 +80,12 @@ const (
 	staleThreshold = 7
 )
 
+var (
+	errNotMining          = errors.New("not mining")
+	errNoSealingWork      = errors.New("no sealing work available yet")
+	errUnknownSealingWork = errors.New("unknown or stale sealing work")
+)
+
 // environment is the worker's current environment and holds all of the current state information.
 type environment struct {
 	signer types.Signer
@@ -155,12 +164,15 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and block builder fields
 	coinbase common.Address
 	extra    []byte
+	builders map[string]BlockBuilder // Block building strategies available by name
+	builder  BlockBuilder            // Block building strategy in use
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
+	sealing      common.Hash // Seal hash of the most recent sealing task
 
 	snapshotMu    sync.RWMutex // The lock used to protect the block snapshot and state snapshot
 	snapshotBlock *types.Block
@@ -210,6 +222,17 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		startCh:            make(chan struct{}, 1),
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
+		builders:           make(map[string]BlockBuilder),
+	}
+	// Register the built-in block building strategies
+	for _, builder := range []BlockBuilder{priceBuilder{}, fcfsBuilder{}} {
+		worker.builders[builder.Name()] = builder
+	}
+	worker.builder = worker.builders[DefaultBlockBuilder]
+	if name := config.BlockBuilder; name != "" {
+		if err := worker.setBlockBuilder(name); err != nil {
+			log.Warn("Ignoring unknown block builder", "name", name)
+		}
 	}
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
@@ -250,6 +273,50 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// registerBlockBuilder makes a block building strategy available by its name.
+func (w *worker) registerBlockBuilder(builder BlockBuilder) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.builders[builder.Name()]; ok {
+		return fmt.Errorf("block builder %q already registered", builder.Name())
+	}
+	w.builders[builder.Name()] = builder
+	return nil
+}
+
+// setBlockBuilder switches the block building strategy used for new work.
+func (w *worker) setBlockBuilder(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	builder, ok := w.builders[name]
+	if !ok {
+		names := make([]string, 0, len(w.builders))
+		for name := range w.builders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown block builder %q, available: %s", name, strings.Join(names, ", "))
+	}
+	w.builder = builder
+	return nil
+}
+
+// blockBuilder returns the block building strategy in use.
+func (w *worker) blockBuilder() BlockBuilder {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.builder
+}
+
+// zeroPeriodClique returns whether the next block is sealed by a clique engine
+// without a block period, which refuses to seal empty blocks.
+func (w *worker) zeroPeriodClique() bool {
+	return w.chainConfig.Clique != nil && w.chainConfig.Clique.PeriodAt(w.chain.CurrentBlock().NumberU64()+1) == 0
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	w.resubmitIntervalCh <- interval
@@ -377,7 +444,7 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		case <-timer.C:
 			// If mining is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
-			if w.isRunning() && (w.chainConfig.Clique == nil || w.chainConfig.Clique.Period > 0) {
+			if w.isRunning() && !w.zeroPeriodClique() {
 				// Short circuit if no new transaction arrives.
 				if atomic.LoadInt32(&w.newTxs) == 0 {
 					timer.Reset(recommit)
@@ -504,7 +571,7 @@ func (w *worker) mainLoop() {
 				// Special case, if the consensus engine is 0 period clique(dev mode),
 				// submit mining work here since all empty submission will be rejected
 				// by clique. Of course the advance sealing(empty submission) is disabled.
-				if w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0 {
+				if w.zeroPeriodClique() {
 					w.commitNewWork(nil, true, time.Now().Unix())
 				}
 			}
@@ -558,6 +625,7 @@ func (w *worker) taskLoop() {
 			}
 			w.pendingMu.Lock()
 			w.pendingTasks[sealHash] = task
+			w.sealing = sealHash
 			w.pendingMu.Unlock()
 
 			if err := w.engine.Seal(w.chain, task.block, w.resultCh, stopCh); err != nil {
@@ -636,6 +704,47 @@ func (w *worker) resultLoop() {
 	}
 }
 
+// sealingWork returns the header of the most recent block waiting to be sealed,
+// along with the hash to seal.
+func (w *worker) sealingWork() (*types.Header, common.Hash, error) {
+	if !w.isRunning() {
+		return nil, common.Hash{}, errNotMining
+	}
+	w.pendingMu.RLock()
+	task, sealHash := w.pendingTasks[w.sealing], w.sealing
+	w.pendingMu.RUnlock()
+
+	if task == nil {
+		return nil, common.Hash{}, errNoSealingWork
+	}
+	return task.block.Header(), sealHash, nil
+}
+
+// submitSealedBlock completes a pending sealing task with a header sealed by
+// an external sealer, and pushes the resulting block to be written into the
+// chain and broadcast.
+func (w *worker) submitSealedBlock(header *types.Header) (*types.Block, error) {
+	sealHash := w.engine.SealHash(header)
+
+	w.pendingMu.RLock()
+	task := w.pendingTasks[sealHash]
+	w.pendingMu.RUnlock()
+
+	if task == nil {
+		return nil, errUnknownSealingWork
+	}
+	if err := w.engine.VerifySeal(w.chain, header); err != nil {
+		return nil, fmt.Errorf("invalid seal: %v", err)
+	}
+	block := task.block.WithSeal(header)
+	select {
+	case w.resultCh <- block:
+		return block, nil
+	case <-w.exitCh:
+		return nil, errors.New("worker stopped")
+	}
+}
+
 // makeCurrent creates a new environment for the current cycle.
 func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 	state, err := w.chain.StateAt(parent.Root())
@@ -643,7 +752,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 		return err
 	}
 	env := &environment{
-		signer:    types.NewEIP155Signer(w.chainConfig.ChainID),
+		signer:    types.MakeSigner(w.chainConfig, header.Number),
 		state:     state,
 		ancestors: mapset.NewSet(),
 		family:    mapset.NewSet(),
@@ -733,7 +842,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TransactionOrder, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -779,7 +888,8 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
 		//
-		// We use the eip155 signer regardless of the current hf.
+		// We use the signer of the block being built, which accepts the transaction
+		// types valid at that height.
 		from, _ := types.Sender(w.current.signer, tx)
 		// Check whether the tx is replay protected. If we're not in the EIP155 hf
 		// phase, start ignoring the sender until we do.
@@ -809,6 +919,11 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
 			txs.Pop()
 
+		case core.ErrTxTypeNotSupported:
+			// Pop the unsupported transaction without shifting in the next from the account
+			log.Trace("Skipping unsupported transaction type", "sender", from, "type", tx.Type())
+			txs.Pop()
+
 		case nil:
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
@@ -952,22 +1067,9 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.updateSnapshot()
 		return
 	}
-	// Split the pending transactions into locals and remotes
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
-		}
-	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
-		}
-	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+	// Let the block builder order the transactions and fill the block. The lock
+	// is already held, so the builder must not be read through blockBuilder.
+	for _, txs := range w.builder.Order(header, w.current.signer, pending, w.eth.TxPool().Locals()) {
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
@@ -501,3 +501,78 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+func TestRemoteSealingClique(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	chainConfig := *params.AllCliqueProtocolChanges
+	chainConfig.Clique = &params.CliqueConfig{Period: 0, Epoch: 30000}
+	engine := clique.New(chainConfig.Clique, db)
+
+	w, b := newTestWorker(t, &chainConfig, engine, db, 0)
+	defer w.close()
+
+	// Drop the local signing key, leaving the blocks to be sealed remotely
+	engine.Authorize(testBankAddress, nil)
+
+	taskCh := make(chan struct{}, 1)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 && len(task.block.Transactions()) > 0 {
+			select {
+			case taskCh <- struct{}{}:
+			default:
+			}
+		}
+	}
+	if _, _, err := w.sealingWork(); err != errNotMining {
+		t.Fatalf("template error mismatch: have %v, want %v", err, errNotMining)
+	}
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	w.start()
+	b.txPool.AddLocal(b.newRandomTx(false))
+
+	select {
+	case <-taskCh:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for sealing work")
+	}
+	header, sealHash, err := w.sealingWork()
+	if err != nil {
+		t.Fatalf("failed to retrieve block template: %v", err)
+	}
+	if sealHash != clique.SealHash(header) {
+		t.Fatalf("seal hash mismatch: have %x, want %x", sealHash, clique.SealHash(header))
+	}
+	// Submitting an unsealed or modified header must fail
+	if _, err := w.submitSealedBlock(header); err == nil {
+		t.Fatalf("unsealed block accepted")
+	}
+	modified := types.CopyHeader(header)
+	modified.GasLimit++
+	if _, err := w.submitSealedBlock(modified); err != errUnknownSealingWork {
+		t.Fatalf("modified block error mismatch: have %v, want %v", err, errUnknownSealingWork)
+	}
+	// Seal the header with the remote key and submit it
+	sig, err := crypto.Sign(sealHash.Bytes(), testBankKey)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-crypto.SignatureLength:], sig)
+
+	block, err := w.submitSealedBlock(header)
+	if err != nil {
+		t.Fatalf("failed to submit sealed block: %v", err)
+	}
+	select {
+	case ev := <-sub.Chan():
+		if mined := ev.Data.(core.NewMinedBlockEvent).Block; mined.Hash() != block.Hash() {
+			t.Fatalf("mined block mismatch: have %x, want %x", mined.Hash(), block.Hash())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for mined block")
+	}
+	if head := b.chain.CurrentBlock(); head.Hash() != block.Hash() {
+		t.Fatalf("chain head mismatch: have %x, want %x", head.Hash(), block.Hash())
+	}
+}
@@ -299,7 +299,7 @@ func (tx *Transaction) GetSigHash() *Hash { return &Hash{types.HomesteadSigner{}
 func (tx *Transaction) GetFrom(chainID *BigInt) (address *Address, _ error) {
 	var signer types.Signer = types.HomesteadSigner{}
 	if chainID != nil {
-		signer = types.NewEIP155Signer(chainID.bigint)
+		signer = types.LatestSignerForChainID(chainID.bigint)
 	}
 	from, err := types.Sender(signer, tx.tx)
 	return &Address{from}, err
@@ -315,7 +315,7 @@ func (tx *Transaction) GetTo() *Address {
 func (tx *Transaction) WithSignature(sig []byte, chainID *BigInt) (signedTx *Transaction, _ error) {
 	var signer types.Signer = types.HomesteadSigner{}
 	if chainID != nil {
-		signer = types.NewEIP155Signer(chainID.bigint)
+		signer = types.LatestSignerForChainID(chainID.bigint)
 	}
 	rawTx, err := tx.tx.WithSignature(signer, common.CopyBytes(sig))
 	return &Transaction{rawTx}, err
@@ -184,6 +184,8 @@ func (api *privateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		jwtSecret:          api.node.httpJWTSecret,
+		limits:             api.node.config.RPCLimits,
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -241,8 +243,10 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 
 	// Determine config.
 	config := wsConfig{
-		Modules: api.node.config.WSModules,
-		Origins: api.node.config.WSOrigins,
+		Modules:   api.node.config.WSModules,
+		Origins:   api.node.config.WSOrigins,
+		jwtSecret: api.node.wsJWTSecret,
+		limits:    api.node.config.RPCLimits,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
 	if apis != nil {
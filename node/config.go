@@ -70,6 +70,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the database engine backing the node's persistent key-value
+	// stores ("leveldb" or "pebble"). If empty, the engine of any pre-existing
+	// database is used, defaulting to leveldb for fresh ones.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -136,6 +141,12 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPJWTSecret is the path to the hex encoded secret used to authenticate
+	// requests to the HTTP RPC interface via HS256 signed JWT bearer tokens. If
+	// the file doesn't exist, a random secret is generated into it. If the field
+	// is empty, no authentication is required.
+	HTTPJWTSecret string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -162,6 +173,16 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSJWTSecret is the path to the hex encoded secret used to authenticate the
+	// websocket handshakes via HS256 signed JWT bearer tokens, just like for the
+	// HTTP interface. If the field is empty, no authentication is required.
+	WSJWTSecret string `toml:",omitempty"`
+
+	// RPCLimits are the resource limits (batch and response sizes, per-method
+	// concurrency and per-client request rates) enforced on the HTTP and websocket
+	// RPC interfaces.
+	RPCLimits rpc.Limits
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -176,6 +197,10 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables re-executing and tracing mined transactions through
+	// the GraphQL endpoint. It is disabled by default, as tracing is expensive.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -0,0 +1,163 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// jwtSecretLength is the length of the shared HS256 secret in bytes.
+	jwtSecretLength = 32
+
+	// jwtExpiryTimeout is the maximum allowed drift between the issuance time of
+	// a token and the local clock, in either direction.
+	jwtExpiryTimeout = 60 * time.Second
+)
+
+// jwtClaims are the claims of an authentication token. The library's own claim
+// validation is disabled as it doesn't tolerate any clock drift on 'iat', which
+// is checked by the handler instead.
+type jwtClaims struct {
+	jwt.StandardClaims
+}
+
+// Valid implements jwt.Claims, deferring all checks to the handler.
+func (c *jwtClaims) Valid() error { return nil }
+
+// jwtHandler is a handler which requires incoming requests to carry an HS256
+// signed, recently issued JWT bearer token. It guards both plain HTTP requests
+// and WebSocket upgrades.
+type jwtHandler struct {
+	keyFunc func(token *jwt.Token) (interface{}, error)
+	next    http.Handler
+}
+
+// newJWTHandler creates a http.Handler with jwt authentication support.
+func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+	return &jwtHandler{
+		keyFunc: func(token *jwt.Token) (interface{}, error) {
+			// Only HS256 is accepted
+			if token.Method != jwt.SigningMethodHS256 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return secret, nil
+		},
+		next: next,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
+	var (
+		strToken string
+		claims   jwtClaims
+	)
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		strToken = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if len(strToken) == 0 {
+		http.Error(out, "missing token", http.StatusUnauthorized)
+		return
+	}
+	token, err := jwt.ParseWithClaims(strToken, &claims, handler.keyFunc)
+
+	switch {
+	case err != nil:
+		http.Error(out, err.Error(), http.StatusUnauthorized)
+	case !token.Valid:
+		http.Error(out, "invalid token", http.StatusUnauthorized)
+	case !claims.VerifyExpiresAt(time.Now().Unix(), false): // optional
+		http.Error(out, "token is expired", http.StatusUnauthorized)
+	case claims.IssuedAt == 0:
+		http.Error(out, "missing issued-at", http.StatusUnauthorized)
+	case time.Since(time.Unix(claims.IssuedAt, 0)) > jwtExpiryTimeout:
+		http.Error(out, "stale token", http.StatusUnauthorized)
+	case time.Until(time.Unix(claims.IssuedAt, 0)) > jwtExpiryTimeout:
+		http.Error(out, "future token", http.StatusUnauthorized)
+	default:
+		handler.next.ServeHTTP(out, r)
+	}
+}
+
+// NewJWTAuth creates an rpc client authentication provider that signs a fresh
+// JWT token with the given shared secret for every request.
+func NewJWTAuth(secret []byte) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+			IssuedAt: time.Now().Unix(),
+		})
+		s, err := token.SignedString(secret)
+		if err != nil {
+			return fmt.Errorf("failed to create JWT token: %w", err)
+		}
+		h.Set("Authorization", "Bearer "+s)
+		return nil
+	}
+}
+
+// ReadJWTSecret loads a hex encoded JWT secret from the given file.
+func ReadJWTSecret(fileName string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	secret := common.FromHex(strings.TrimSpace(string(data)))
+	if len(secret) != jwtSecretLength {
+		return nil, fmt.Errorf("invalid JWT secret length in %s: have %d bytes, want %d", fileName, len(secret), jwtSecretLength)
+	}
+	return secret, nil
+}
+
+// obtainJWTSecret loads the JWT secret from the given file, or generates a fresh
+// random one and persists it there if the file doesn't exist yet.
+func obtainJWTSecret(fileName string) ([]byte, error) {
+	secret, err := ReadJWTSecret(fileName)
+	if err == nil {
+		log.Info("Loaded JWT secret file", "path", fileName)
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	secret = make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fileName, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", fileName)
+	return secret, nil
+}
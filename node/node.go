@@ -57,6 +57,9 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	httpJWTSecret []byte // Secret authenticating HTTP RPC requests, if configured
+	wsJWTSecret   []byte // Secret authenticating WebSocket RPC handshakes, if configured
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -337,12 +340,29 @@ func (n *Node) startRPC() error {
 		}
 	}
 
+	// Load the authentication secrets of the HTTP and WebSocket endpoints.
+	if n.config.HTTPJWTSecret != "" {
+		secret, err := obtainJWTSecret(n.config.ResolvePath(n.config.HTTPJWTSecret))
+		if err != nil {
+			return err
+		}
+		n.httpJWTSecret = secret
+	}
+	if n.config.WSJWTSecret != "" {
+		secret, err := obtainJWTSecret(n.config.ResolvePath(n.config.WSJWTSecret))
+		if err != nil {
+			return err
+		}
+		n.wsJWTSecret = secret
+	}
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
 		config := httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			jwtSecret:          n.httpJWTSecret,
+			limits:             n.config.RPCLimits,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -356,8 +376,10 @@ func (n *Node) startRPC() error {
 	if n.config.WSHost != "" {
 		server := n.wsServerForPort(n.config.WSPort)
 		config := wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
+			Modules:   n.config.WSModules,
+			Origins:   n.config.WSOrigins,
+			jwtSecret: n.wsJWTSecret,
+			limits:    n.config.RPCLimits,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -544,7 +566,13 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string) (
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:      n.config.DBEngine,
+			Directory: n.ResolvePath(name),
+			Namespace: namespace,
+			Cache:     cache,
+			Handles:   handles,
+		})
 	}
 
 	if err == nil {
@@ -574,10 +602,19 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		switch {
 		case freezer == "":
 			freezer = filepath.Join(root, "ancient")
+		case rawdb.IsRemoteFreezer(freezer):
+			// Served by a separate process, use the endpoint as is
 		case !filepath.IsAbs(freezer):
 			freezer = n.ResolvePath(freezer)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:      n.config.DBEngine,
+			Directory: root,
+			Freezer:   freezer,
+			Namespace: namespace,
+			Cache:     cache,
+			Handles:   handles,
+		})
 	}
 
 	if err == nil {
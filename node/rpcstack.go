@@ -39,17 +39,22 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	jwtSecret          []byte     // optional JWT secret
+	limits             rpc.Limits // resource limits of the RPC server
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins []string
-	Modules []string
+	Origins   []string
+	Modules   []string
+	jwtSecret []byte     // optional JWT secret
+	limits    rpc.Limits // resource limits of the RPC server
 }
 
 type rpcHandler struct {
 	http.Handler
-	server *rpc.Server
+	server    *rpc.Server
+	jwtSecret []byte // optional JWT secret, also guarding the mounted handlers
 }
 
 type httpServer struct {
@@ -150,6 +155,7 @@ func (h *httpServer) start() error {
 		"endpoint", listener.Addr(),
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
+		"auth", len(h.httpConfig.jwtSecret) != 0,
 	)
 
 	// Log all handlers mounted on server.
@@ -186,7 +192,11 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Requests to a path below root are handled by the mux,
 		// which has all the handlers registered via Node.RegisterHandler.
 		// These are made available when RPC is enabled.
-		h.mux.ServeHTTP(w, r)
+		var handler http.Handler = &h.mux
+		if len(rpc.jwtSecret) != 0 {
+			handler = newJWTHandler(rpc.jwtSecret, handler)
+		}
+		handler.ServeHTTP(w, r)
 		return
 	}
 	w.WriteHeader(404)
@@ -235,13 +245,19 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimits(config.limits)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.httpConfig = config
+	handler := NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts)
+	if len(config.jwtSecret) != 0 {
+		handler = newJWTHandler(config.jwtSecret, handler)
+	}
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
-		server:  srv,
+		Handler:   handler,
+		server:    srv,
+		jwtSecret: config.jwtSecret,
 	})
 	return nil
 }
@@ -267,12 +283,17 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimits(config.limits)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.wsConfig = config
+	handler := srv.WebsocketHandler(config.Origins)
+	if len(config.jwtSecret) != 0 {
+		handler = newJWTHandler(config.jwtSecret, handler)
+	}
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: handler,
 		server:  srv,
 	})
 	return nil
@@ -408,7 +429,8 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		// Connection upgrades (e.g. websockets) hijack the writer, don't compress them
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Header.Get("Upgrade") != "" {
 			next.ServeHTTP(w, r)
 			return
 		}
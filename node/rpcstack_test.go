@@ -18,12 +18,15 @@ package node
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
@@ -88,6 +91,70 @@ func TestIsWebsocket(t *testing.T) {
 	assert.True(t, isWebsocket(r))
 }
 
+// TestJWT makes sure requests to a JWT protected endpoint are only served if
+// they carry a valid, recently issued token.
+func TestJWT(t *testing.T) {
+	var secret = []byte("secret")
+	issueTokenWith := func(method jwt.SigningMethod, secret []byte, iat time.Time) string {
+		token := jwt.NewWithClaims(method, jwt.StandardClaims{
+			IssuedAt: iat.Unix(),
+		})
+		s, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + s
+	}
+	issueToken := func(secret []byte, iat time.Time) string {
+		return issueTokenWith(jwt.SigningMethodHS256, secret, iat)
+	}
+	srv := createAndStartServer(t, httpConfig{jwtSecret: secret}, true, wsConfig{Origins: []string{"*"}, jwtSecret: secret})
+	defer srv.stop()
+
+	tests := []struct {
+		token string
+		ok    bool
+	}{
+		{"", false},
+		{"Bearer ", false},
+		{issueToken(secret, time.Now()), true},
+		{issueToken(secret, time.Now().Add(-30*time.Second)), true},
+		{issueToken(secret, time.Now().Add(30*time.Second)), true},
+		{issueToken(secret, time.Now().Add(-2*jwtExpiryTimeout)), false},
+		{issueToken(secret, time.Now().Add(2*jwtExpiryTimeout)), false},
+		{issueToken([]byte("other"), time.Now()), false},
+		{issueTokenWith(jwt.SigningMethodHS512, secret, time.Now()), false},
+		{issueToken(secret, time.Now())[len("Bearer "):], false},
+	}
+	for i, tt := range tests {
+		resp := testRequest(t, "Authorization", tt.token, "", srv)
+		if ok := resp.StatusCode != http.StatusUnauthorized; ok != tt.ok {
+			t.Errorf("test %d: http request authorized mismatch: have %v, want %v (status %d)", i, ok, tt.ok, resp.StatusCode)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+srv.listenAddr(), http.Header{
+			"Authorization": []string{tt.token},
+		})
+		if conn != nil {
+			conn.Close()
+		}
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("test %d: websocket upgrade authorized mismatch: have %v, want %v (%v)", i, ok, tt.ok, err)
+		}
+	}
+	// Ensure the rpc clients can authenticate with the shared secret
+	for _, url := range []string{"http://" + srv.listenAddr(), "ws://" + srv.listenAddr()} {
+		client, err := rpc.DialWithAuth(context.Background(), url, NewJWTAuth(secret))
+		if err != nil {
+			t.Fatalf("%s: failed to dial: %v", url, err)
+		}
+		var modules map[string]string
+		if err := client.Call(&modules, "rpc_modules"); err != nil {
+			t.Errorf("%s: authenticated call failed: %v", url, err)
+		}
+		client.Close()
+	}
+}
+
 func createAndStartServer(t *testing.T, conf httpConfig, ws bool, wsConf wsConfig) *httpServer {
 	t.Helper()
 
@@ -18,8 +18,10 @@ package params
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -341,13 +343,103 @@ func (c *EthashConfig) String() string {
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	Forks []*CliqueFork `json:"forks,omitempty"` // Scheduled parameter and signer set changes, in ascending block order
+}
+
+// CliqueFork is a scheduled change of the proof-of-authority parameters or the
+// signer set, taking effect from a given block onwards.
+type CliqueFork struct {
+	Block   uint64           `json:"block"`             // Number of the first block the changes apply to
+	Period  *uint64          `json:"period,omitempty"`  // New number of seconds between blocks, unchanged if nil
+	Epoch   *uint64          `json:"epoch,omitempty"`   // New epoch length, unchanged if nil
+	Signers []common.Address `json:"signers,omitempty"` // Forced signer set, discarding all pending votes
 }
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *CliqueConfig) String() string {
+	if len(c.Forks) > 0 {
+		return fmt.Sprintf("clique (%d scheduled forks)", len(c.Forks))
+	}
 	return "clique"
 }
 
+// PeriodAt returns the block period in effect at the given block.
+func (c *CliqueConfig) PeriodAt(number uint64) uint64 {
+	period := c.Period
+	for _, fork := range c.Forks {
+		if fork.Block > number {
+			break
+		}
+		if fork.Period != nil {
+			period = *fork.Period
+		}
+	}
+	return period
+}
+
+// EpochAt returns the epoch length in effect at the given block.
+func (c *CliqueConfig) EpochAt(number uint64) uint64 {
+	epoch := c.Epoch
+	for _, fork := range c.Forks {
+		if fork.Block > number {
+			break
+		}
+		if fork.Epoch != nil {
+			epoch = *fork.Epoch
+		}
+	}
+	return epoch
+}
+
+// SignersAt returns the signer set forced from the given block onwards, or nil
+// if the schedule doesn't replace the signers at that block.
+func (c *CliqueConfig) SignersAt(number uint64) []common.Address {
+	for _, fork := range c.Forks {
+		if fork.Block == number && len(fork.Signers) > 0 {
+			return fork.Signers
+		}
+	}
+	return nil
+}
+
+// CheckForks checks that the fork schedule is well formed.
+func (c *CliqueConfig) CheckForks() error {
+	var last uint64
+	for i, fork := range c.Forks {
+		if fork.Block == 0 {
+			return errors.New("clique fork at genesis, configure the genesis instead")
+		}
+		if i > 0 && fork.Block <= last {
+			return fmt.Errorf("unsupported clique fork ordering: fork at %d after fork at %d", fork.Block, last)
+		}
+		if fork.Epoch != nil && *fork.Epoch == 0 {
+			return fmt.Errorf("invalid zero epoch in clique fork at %d", fork.Block)
+		}
+		last = fork.Block
+	}
+	return nil
+}
+
+// forkDivergence returns the first block from which two fork schedules differ.
+func (c *CliqueConfig) forkDivergence(other *CliqueConfig) *big.Int {
+	for i := 0; i < len(c.Forks) || i < len(other.Forks); i++ {
+		switch {
+		case i >= len(c.Forks):
+			return new(big.Int).SetUint64(other.Forks[i].Block)
+		case i >= len(other.Forks):
+			return new(big.Int).SetUint64(c.Forks[i].Block)
+		case !reflect.DeepEqual(c.Forks[i], other.Forks[i]):
+			block := c.Forks[i].Block
+			if other.Forks[i].Block < block {
+				block = other.Forks[i].Block
+			}
+			return new(big.Int).SetUint64(block)
+		}
+	}
+	return nil
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -497,6 +589,9 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Clique != nil {
+		return c.Clique.CheckForks()
+	}
 	return nil
 }
 
@@ -547,6 +642,11 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		if block := c.Clique.forkDivergence(newcfg.Clique); isForked(block, head) {
+			return newCompatError("Clique fork schedule", block, block)
+		}
+	}
 	return nil
 }
 
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -87,6 +89,23 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored:  &ChainConfig{Clique: &CliqueConfig{Forks: []*CliqueFork{{Block: 10}}}},
+			new:     &ChainConfig{Clique: &CliqueConfig{Forks: []*CliqueFork{{Block: 10}, {Block: 20}}}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Clique: &CliqueConfig{Forks: []*CliqueFork{{Block: 10}, {Block: 20}}}},
+			new:    &ChainConfig{Clique: &CliqueConfig{Forks: []*CliqueFork{{Block: 10}, {Block: 15}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Clique fork schedule",
+				StoredConfig: big.NewInt(15),
+				NewConfig:    big.NewInt(15),
+				RewindTo:     14,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -96,3 +115,46 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCliqueForkSchedule(t *testing.T) {
+	period, epoch := uint64(5), uint64(100)
+	config := &CliqueConfig{
+		Period: 15,
+		Epoch:  30000,
+		Forks: []*CliqueFork{
+			{Block: 10, Period: &period},
+			{Block: 20, Signers: []common.Address{{0x01}}},
+			{Block: 30, Epoch: &epoch},
+		},
+	}
+	if err := config.CheckForks(); err != nil {
+		t.Fatalf("valid schedule rejected: %v", err)
+	}
+	for _, tt := range []struct {
+		number  uint64
+		period  uint64
+		epoch   uint64
+		signers bool
+	}{
+		{0, 15, 30000, false},
+		{9, 15, 30000, false},
+		{10, 5, 30000, false},
+		{20, 5, 30000, true},
+		{21, 5, 30000, false},
+		{30, 5, 100, false},
+	} {
+		if have := config.PeriodAt(tt.number); have != tt.period {
+			t.Errorf("block %d: period mismatch: have %d, want %d", tt.number, have, tt.period)
+		}
+		if have := config.EpochAt(tt.number); have != tt.epoch {
+			t.Errorf("block %d: epoch mismatch: have %d, want %d", tt.number, have, tt.epoch)
+		}
+		if have := config.SignersAt(tt.number) != nil; have != tt.signers {
+			t.Errorf("block %d: forced signers mismatch: have %v, want %v", tt.number, have, tt.signers)
+		}
+	}
+	config.Forks[1].Block = 10
+	if err := config.CheckForks(); err == nil {
+		t.Errorf("unordered schedule accepted")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -77,6 +78,7 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	limiter  *limiter // server side resource limits, nil for clients
 
 	idCounter uint32
 
@@ -111,7 +113,7 @@ type clientConn struct {
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.limiter)
 	return &clientConn{conn, handler}
 }
 
@@ -185,6 +187,28 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	}
 }
 
+// HTTPAuth is a provider of authentication headers for the HTTP requests made by
+// a client, including the WebSocket handshake. It is invoked for every request
+// (or connection attempt), so short-lived credentials stay fresh.
+type HTTPAuth func(h http.Header) error
+
+// DialWithAuth creates a new RPC client for the given HTTP or WebSocket URL, just
+// like DialContext, but authenticating every request with the given provider.
+func DialWithAuth(ctx context.Context, rawurl string, auth HTTPAuth) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return dialHTTP(rawurl, new(http.Client), auth)
+	case "ws", "wss":
+		return dialWebsocket(ctx, rawurl, "", newWebsocketDialer(), auth)
+	default:
+		return nil, fmt.Errorf("no authenticated transport for URL scheme %q", u.Scheme)
+	}
+}
+
 // Client retrieves the client from the context, if any. This can be used to perform
 // 'reverse calls' in a handler method.
 func ClientFromContext(ctx context.Context) (*Client, bool) {
@@ -197,17 +221,18 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, limiter *limiter) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		limiter:     limiter,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
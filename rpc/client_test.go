@@ -310,6 +310,38 @@ func TestClientSubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that a subscription terminated by the server reports the
+// error after all preceding notifications, and is dropped on both ends.
+func TestClientSubscribeTerminated(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 10
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "failingSubscription", count)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	select {
+	case err := <-sub.Err():
+		if re, ok := err.(Error); !ok || re.ErrorCode() != (testError{}).ErrorCode() || err.Error() != (testError{}).Error() {
+			t.Fatalf("wrong termination error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not terminated within 1s")
+	}
+	if err := client.Call(nil, "nftest_unsubscribe", sub.subid); err == nil || err.Error() != ErrSubscriptionNotFound.Error() {
+		t.Fatalf("terminated subscription not dropped by the server: %v", err)
+	}
+}
+
 // In this test, the connection drops while Subscribe is waiting for a response.
 func TestClientSubscribeClose(t *testing.T) {
 	server := newTestServer()
@@ -61,6 +61,7 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limiter        *limiter // resource limits, nil if unlimited
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +72,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limiter *limiter) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -84,6 +85,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		limiter:        limiter,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -101,6 +103,24 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Refuse batches exceeding the configured size limit without executing any
+	// of the calls, answering each of them so clients can match the responses:
+	if h.limiter != nil && h.limiter.batchTooLarge(len(msgs)) {
+		rpcBatchLimitMeter.Mark(1)
+		h.startCallProc(func(cp *callProc) {
+			answers := make([]*jsonrpcMessage, 0, len(msgs))
+			for _, msg := range msgs {
+				if msg.isCall() {
+					answers = append(answers, msg.errorResponse(errBatchTooLarge))
+				}
+			}
+			if len(answers) == 0 {
+				answers = append(answers, errorMessage(errBatchTooLarge))
+			}
+			h.conn.writeJSON(cp.ctx, answers)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -114,9 +134,24 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
 		for _, msg := range calls {
+			// Once the response limit is hit, refuse to execute the remaining calls
+			if h.limiter != nil && h.limiter.responseTooLarge(size) {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(errResponseTooLarge))
+				}
+				continue
+			}
 			if answer := h.handleCallMsg(cp, msg); answer != nil {
+				size += len(answer.Result)
+				if h.limiter != nil && h.limiter.responseTooLarge(size) {
+					rpcResponseLimitMeter.Mark(1)
+					answer = msg.errorResponse(errResponseTooLarge)
+				}
 				answers = append(answers, answer)
 			}
 		}
@@ -137,6 +172,10 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	}
 	h.startCallProc(func(cp *callProc) {
 		answer := h.handleCallMsg(cp, msg)
+		if answer != nil && h.limiter != nil && h.limiter.responseTooLarge(len(answer.Result)) {
+			rpcResponseLimitMeter.Mark(1)
+			answer = msg.errorResponse(errResponseTooLarge)
+		}
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -254,9 +293,18 @@ func (h *handler) handleSubscriptionResult(msg *jsonrpcMessage) {
 		h.log.Debug("Dropping invalid subscription message")
 		return
 	}
-	if h.clientSubs[result.ID] != nil {
-		h.clientSubs[result.ID].deliver(result.Result)
+	sub := h.clientSubs[result.ID]
+	if sub == nil {
+		return
 	}
+	if result.Error != nil {
+		// The server ended the subscription, report its error after the
+		// notifications delivered before it.
+		delete(h.clientSubs, result.ID)
+		sub.terminate(result.Error)
+		return
+	}
+	sub.deliver(result.Result)
 }
 
 // handleResponse processes method call responses.
@@ -317,6 +365,13 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.limiter != nil && !msg.isUnsubscribe() {
+		release, err := h.limiter.acquire(h.conn.remoteAddr(), msg.Method)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		defer release()
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -46,6 +46,7 @@ type httpConn struct {
 	closeCh   chan interface{}
 	mu        sync.Mutex // protects headers
 	headers   http.Header
+	auth      HTTPAuth // Optional provider of per-request authentication headers
 }
 
 // httpConn is treated specially by Client.
@@ -105,6 +106,12 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return dialHTTP(endpoint, client, nil)
+}
+
+// dialHTTP creates a new HTTP RPC client, optionally authenticating all requests
+// using the given provider.
+func dialHTTP(endpoint string, client *http.Client, auth HTTPAuth) (*Client, error) {
 	// Sanity check URL so we don't end up with a client that will fail every request.
 	_, err := url.Parse(endpoint)
 	if err != nil {
@@ -119,6 +126,7 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 		hc := &httpConn{
 			client:  client,
 			headers: headers,
+			auth:    auth,
 			url:     endpoint,
 			closeCh: make(chan interface{}),
 		}
@@ -188,6 +196,12 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req.Header = hc.headers.Clone()
 	hc.mu.Unlock()
 
+	if hc.auth != nil {
+		if err := hc.auth(req.Header); err != nil {
+			return nil, err
+		}
+	}
+
 	// do request
 	resp, err := hc.client.Do(req)
 	if err != nil {
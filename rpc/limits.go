@@ -0,0 +1,169 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// clientLimitSweep is the interval after which idle per-client rate limiters
+// are garbage collected.
+const clientLimitSweep = time.Minute
+
+// Limits configures the resource limits a Server enforces on the requests it
+// serves. The zero value imposes no limits.
+type Limits struct {
+	// BatchItems is the maximum number of requests allowed in a single batch.
+	BatchItems int
+
+	// ResponseBytes is the maximum total size of the results returned for a
+	// single request or batch. Calls beyond the limit are answered with an error.
+	ResponseBytes int
+
+	// MethodConcurrency caps the number of concurrently executing calls of the
+	// given methods, summed across all connections of the server.
+	MethodConcurrency map[string]int
+
+	// RateLimit is the sustained number of calls per second allowed for a remote
+	// host, with RateBurst calls permitted above it. Connections without a remote
+	// address (in-process, IPC) are not rate limited.
+	RateLimit float64
+	RateBurst int
+}
+
+// limitExceededError is returned when a request is refused due to a configured
+// server limit.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+var (
+	errBatchTooLarge    = &limitExceededError{"batch too large"}
+	errResponseTooLarge = &limitExceededError{"response too large"}
+	errRateLimited      = &limitExceededError{"request rate limit exceeded"}
+	errTooManyCalls     = &limitExceededError{"too many concurrent calls to method"}
+)
+
+// limiter enforces a set of Limits on behalf of a server. It is shared among all
+// the handlers of the server.
+type limiter struct {
+	limits  Limits
+	methods map[string]chan struct{} // concurrency semaphores, read-only after creation
+	idle    time.Duration            // time after which a client bucket is full again
+
+	lock      sync.Mutex
+	clients   map[string]*clientLimit // token buckets keyed by remote host
+	lastSweep time.Time
+}
+
+type clientLimit struct {
+	bucket *rate.Limiter
+	seen   time.Time
+}
+
+func newLimiter(limits Limits) *limiter {
+	l := &limiter{
+		limits:    limits,
+		methods:   make(map[string]chan struct{}),
+		clients:   make(map[string]*clientLimit),
+		lastSweep: time.Now(),
+	}
+	for method, n := range limits.MethodConcurrency {
+		if n > 0 {
+			l.methods[method] = make(chan struct{}, n)
+		}
+	}
+	if limits.RateLimit > 0 {
+		if l.limits.RateBurst <= 0 {
+			l.limits.RateBurst = int(math.Ceil(limits.RateLimit))
+		}
+		l.idle = time.Duration(float64(l.limits.RateBurst) / limits.RateLimit * float64(time.Second))
+		if l.idle < clientLimitSweep {
+			l.idle = clientLimitSweep
+		}
+	}
+	return l
+}
+
+// acquire admits a call to the given method from the given remote address. The
+// returned release function must be called when the call is done.
+func (l *limiter) acquire(remote string, method string) (func(), error) {
+	if !l.allow(remote) {
+		rpcRateLimitMeter.Mark(1)
+		return nil, errRateLimited
+	}
+	sem, ok := l.methods[method]
+	if !ok {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	default:
+		rpcConcurrencyLimitMeter.Mark(1)
+		return nil, errTooManyCalls
+	}
+}
+
+// allow consumes a token from the bucket of the given remote address, reporting
+// whether the call may proceed.
+func (l *limiter) allow(remote string) bool {
+	if l.limits.RateLimit <= 0 || remote == "" {
+		return true
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	now := time.Now()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Drop the buckets of clients idle long enough to be full again
+	if now.Sub(l.lastSweep) > clientLimitSweep {
+		for host, client := range l.clients {
+			if now.Sub(client.seen) > l.idle {
+				delete(l.clients, host)
+			}
+		}
+		l.lastSweep = now
+	}
+	client := l.clients[remote]
+	if client == nil {
+		client = &clientLimit{bucket: rate.NewLimiter(rate.Limit(l.limits.RateLimit), l.limits.RateBurst)}
+		l.clients[remote] = client
+	}
+	client.seen = now
+	return client.bucket.AllowN(now, 1)
+}
+
+// batchTooLarge reports whether a batch of the given size exceeds the limit.
+func (l *limiter) batchTooLarge(n int) bool {
+	return l.limits.BatchItems > 0 && n > l.limits.BatchItems
+}
+
+// responseTooLarge reports whether a response of the given size exceeds the limit.
+func (l *limiter) responseTooLarge(size int) bool {
+	return l.limits.ResponseBytes > 0 && size > l.limits.ResponseBytes
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// limitsTestClient starts an HTTP server with the given limits and dials it.
+func limitsTestClient(t *testing.T, limits Limits) (*Client, func()) {
+	server := newTestServer()
+	server.SetLimits(limits)
+	hs := httptest.NewServer(server)
+
+	client, err := DialHTTP(hs.URL)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	return client, func() {
+		client.Close()
+		hs.Close()
+		server.Stop()
+	}
+}
+
+// checkLimitError verifies that err is the given limit error.
+func checkLimitError(t *testing.T, err error, want *limitExceededError) {
+	t.Helper()
+
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("wrong error type %T (%v), want limit error", err, err)
+	}
+	if rpcErr.ErrorCode() != want.ErrorCode() || rpcErr.Error() != want.Error() {
+		t.Fatalf("wrong error: have %d %q, want %d %q", rpcErr.ErrorCode(), rpcErr.Error(), want.ErrorCode(), want.Error())
+	}
+}
+
+func TestBatchItemLimit(t *testing.T) {
+	client, stop := limitsTestClient(t, Limits{BatchItems: 2})
+	defer stop()
+
+	makeBatch := func(n int) []BatchElem {
+		batch := make([]BatchElem, n)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, &echoArgs{"y"}}, Result: new(echoResult)}
+		}
+		return batch
+	}
+	batch := makeBatch(2)
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d failed: %v", i, elem.Error)
+		}
+	}
+	batch = makeBatch(3)
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for _, elem := range batch {
+		checkLimitError(t, elem.Error, errBatchTooLarge)
+	}
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	client, stop := limitsTestClient(t, Limits{ResponseBytes: 100})
+	defer stop()
+
+	// A single short response should be served, a long one refused
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1, &echoArgs{"y"}); err != nil {
+		t.Fatal("short call failed:", err)
+	}
+	long := make([]byte, 200)
+	for i := range long {
+		long[i] = 'x'
+	}
+	err := client.Call(&result, "test_echo", string(long), 1, &echoArgs{"y"})
+	checkLimitError(t, err, errResponseTooLarge)
+
+	// In a batch, the limit applies to the sum of all responses
+	batch := make([]BatchElem, 5)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, &echoArgs{"y"}}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("first batch element failed: %v", batch[0].Error)
+	}
+	checkLimitError(t, batch[len(batch)-1].Error, errResponseTooLarge)
+}
+
+func TestRateLimit(t *testing.T) {
+	client, stop := limitsTestClient(t, Limits{RateLimit: 0.001, RateBurst: 2})
+	defer stop()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	checkLimitError(t, client.Call(nil, "test_noArgsRets"), errRateLimited)
+
+	// Connections without a remote address are not subject to the rate limit
+	l := newLimiter(Limits{RateLimit: 0.001, RateBurst: 1})
+	for i := 0; i < 3; i++ {
+		if !l.allow("") {
+			t.Fatalf("local call %d rate limited", i)
+		}
+	}
+	// Ports of a remote host share its limit
+	if !l.allow("127.0.0.1:1000") {
+		t.Fatal("first remote call rate limited")
+	}
+	if l.allow("127.0.0.1:2000") {
+		t.Fatal("remote host exceeded its rate limit from a different port")
+	}
+}
+
+func TestMethodConcurrencyLimit(t *testing.T) {
+	l := newLimiter(Limits{MethodConcurrency: map[string]int{"test_block": 2}})
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := l.acquire("", "test_block")
+		if err != nil {
+			t.Fatalf("call %d refused: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+	_, err := l.acquire("", "test_block")
+	checkLimitError(t, err, errTooManyCalls)
+
+	// Other methods are not limited, finished calls free up slots
+	if _, err := l.acquire("", "test_echo"); err != nil {
+		t.Fatalf("unlimited method refused: %v", err)
+	}
+	releases[0]()
+	if _, err := l.acquire("", "test_block"); err != nil {
+		t.Fatalf("call refused after release: %v", err)
+	}
+}
@@ -27,6 +27,11 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rpcBatchLimitMeter       = metrics.NewRegisteredMeter("rpc/limits/batch", nil)
+	rpcResponseLimitMeter    = metrics.NewRegisteredMeter("rpc/limits/response", nil)
+	rpcRateLimitMeter        = metrics.NewRegisteredMeter("rpc/limits/rate", nil)
+	rpcConcurrencyLimitMeter = metrics.NewRegisteredMeter("rpc/limits/concurrency", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -97,8 +97,10 @@ type Notifier struct {
 	mu           sync.Mutex
 	sub          *Subscription
 	buffer       []json.RawMessage
+	final        *subscriptionResult // Termination notice held back until activation
 	callReturned bool
 	activated    bool
+	terminated   bool
 }
 
 // CreateSubscription returns a new subscription that is coupled to the
@@ -141,6 +143,43 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Terminate ends the subscription with the given error, sending it to the client
+// as the final notification. The subscription is dropped from the connection and
+// its error channel is closed, the same as if the client unsubscribed.
+func (n *Notifier) Terminate(id ID, err error) error {
+	n.mu.Lock()
+	if n.sub == nil {
+		panic("can't Terminate before subscription is created")
+	} else if n.sub.ID != id {
+		panic("Terminate with wrong ID")
+	}
+	if n.terminated {
+		n.mu.Unlock()
+		return nil
+	}
+	n.terminated = true
+
+	final := &subscriptionResult{ID: string(id), Error: errorMessage(err).Error}
+	var sendErr error
+	if n.activated {
+		sendErr = n.sendResult(final)
+	} else {
+		n.final = final
+	}
+	n.mu.Unlock()
+
+	// Drop the subscription if it was already registered on the connection,
+	// otherwise it won't ever be
+	n.h.subLock.Lock()
+	defer n.h.subLock.Unlock()
+
+	if s := n.h.serverSubs[id]; s == n.sub {
+		close(s.err)
+		delete(n.h.serverSubs, id)
+	}
+	return sendErr
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -153,6 +192,9 @@ func (n *Notifier) takeSubscription() *Subscription {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	n.callReturned = true
+	if n.terminated {
+		return nil
+	}
 	return n.sub
 }
 
@@ -168,12 +210,22 @@ func (n *Notifier) activate() error {
 			return err
 		}
 	}
+	if n.final != nil {
+		if err := n.sendResult(n.final); err != nil {
+			return err
+		}
+	}
 	n.activated = true
 	return nil
 }
 
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
-	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
+	return n.sendResult(&subscriptionResult{ID: string(sub.ID), Result: data})
+}
+
+// sendResult writes a subscription notification to the connection.
+func (n *Notifier) sendResult(result *subscriptionResult) error {
+	params, _ := json.Marshal(result)
 	ctx := context.Background()
 	return n.h.conn.writeJSON(ctx, &jsonrpcMessage{
 		Version: vsn,
@@ -209,6 +261,7 @@ type ClientSubscription struct {
 	namespace string
 	subid     string
 	in        chan json.RawMessage
+	ended     chan error // receives the error the server ended the subscription with
 
 	quitOnce sync.Once     // ensures quit is closed once
 	quit     chan struct{} // quit is closed when the subscription exits
@@ -225,6 +278,7 @@ func newClientSubscription(c *Client, namespace string, channel reflect.Value) *
 		quit:      make(chan struct{}),
 		err:       make(chan error, 1),
 		in:        make(chan json.RawMessage),
+		ended:     make(chan error, 1),
 	}
 	return sub
 }
@@ -275,6 +329,15 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 	}
 }
 
+// terminate reports that the server ended the subscription with the given error.
+// Notifications delivered before are still forwarded before the error.
+func (sub *ClientSubscription) terminate(err error) {
+	select {
+	case sub.ended <- err:
+	default:
+	}
+}
+
 func (sub *ClientSubscription) start() {
 	sub.quitWithError(sub.forward())
 }
@@ -283,19 +346,26 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 	cases := []reflect.SelectCase{
 		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.quit)},
 		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.in)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.ended)},
 		{Dir: reflect.SelectSend, Chan: sub.channel},
 	}
-	buffer := list.New()
+	var (
+		buffer = list.New()
+		ended  error // set once the server ended the subscription
+	)
 	defer buffer.Init()
 	for {
+		if ended != nil && buffer.Len() == 0 {
+			return false, ended
+		}
 		var chosen int
 		var recv reflect.Value
 		if buffer.Len() == 0 {
 			// Idle, omit send case.
-			chosen, recv, _ = reflect.Select(cases[:2])
+			chosen, recv, _ = reflect.Select(cases[:3])
 		} else {
 			// Non-empty buffer, send the first queued item.
-			cases[2].Send = reflect.ValueOf(buffer.Front().Value)
+			cases[3].Send = reflect.ValueOf(buffer.Front().Value)
 			chosen, recv, _ = reflect.Select(cases)
 		}
 
@@ -311,8 +381,12 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 				return true, ErrSubscriptionQueueOverflow
 			}
 			buffer.PushBack(val)
-		case 2: // sub.channel<-
-			cases[2].Send = reflect.Value{} // Don't hold onto the value.
+		case 2: // <-sub.ended
+			// Nothing follows the error, flush the buffer before reporting it.
+			ended = recv.Interface().(error)
+			cases[1].Chan, cases[2].Chan = reflect.Value{}, reflect.Value{}
+		case 3: // sub.channel<-
+			cases[3].Send = reflect.Value{} // Don't hold onto the value.
 			buffer.Remove(buffer.Front())
 		}
 	}
@@ -758,6 +758,110 @@ func TestEmptyRangeProof(t *testing.T) {
 	}
 }
 
+// TestSingleLeafRangeProof tests the range proof on tries where the root node
+// is a shortnode, either because the trie contains a single leaf or because all
+// the leaves share a common prefix. The edge proofs can be both existent and
+// non-existent ones, in which case the entire trie is covered by the range.
+func TestSingleLeafRangeProof(t *testing.T) {
+	for _, n := range []byte{1, 2, 3} {
+		trie := new(Trie)
+		var entries entrySlice
+		for i := byte(1); i <= n; i++ {
+			value := &kv{common.LeftPadBytes([]byte{0xa, i}, 32), []byte{i}, false}
+			trie.Update(value.k, value.v)
+			entries = append(entries, value)
+		}
+		var keys, vals [][]byte
+		for _, entry := range entries {
+			keys = append(keys, entry.k)
+			vals = append(vals, entry.v)
+		}
+		var cases = []struct {
+			first, last []byte
+		}{
+			{keys[0], keys[len(keys)-1]},
+			{decreseKey(common.CopyBytes(keys[0])), keys[len(keys)-1]},
+			{keys[0], increseKey(common.CopyBytes(keys[len(keys)-1]))},
+			{decreseKey(common.CopyBytes(keys[0])), increseKey(common.CopyBytes(keys[len(keys)-1]))},
+			{common.Hash{}.Bytes(), common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff").Bytes()},
+		}
+		for i, c := range cases {
+			proof := memorydb.New()
+			if err := trie.Prove(c.first, 0, proof); err != nil {
+				t.Fatalf("Failed to prove the first node %v", err)
+			}
+			if err := trie.Prove(c.last, 0, proof); err != nil {
+				t.Fatalf("Failed to prove the last node %v", err)
+			}
+			err, cont := VerifyRangeProof(trie.Hash(), c.first, c.last, keys, vals, proof)
+			if err != nil {
+				t.Fatalf("%d leaves, case %d: expected no error, got %v", n, i, err)
+			}
+			if cont {
+				t.Fatalf("%d leaves, case %d: expected no more elements", n, i)
+			}
+		}
+		// Zero elements beyond the last leaf should also be provable
+		proof := memorydb.New()
+		first := increseKey(common.CopyBytes(keys[len(keys)-1]))
+		if err := trie.Prove(first, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the first node %v", err)
+		}
+		if err, _ := VerifyRangeProof(trie.Hash(), first, nil, nil, nil, proof); err != nil {
+			t.Fatalf("%d leaves: expected no error for empty range, got %v", n, err)
+		}
+		// Zero elements before the first leaf must be rejected
+		proof = memorydb.New()
+		first = decreseKey(common.CopyBytes(keys[0]))
+		if err := trie.Prove(first, 0, proof); err != nil {
+			t.Fatalf("Failed to prove the first node %v", err)
+		}
+		if err, _ := VerifyRangeProof(trie.Hash(), first, nil, nil, nil, proof); err == nil {
+			t.Fatalf("%d leaves: expected error for empty range with remaining leaves", n)
+		}
+	}
+}
+
+// TestOutOfBoundRangeProof tests that leaves outside of the proven edges are
+// rejected, even if they would be accepted as part of a wider range.
+func TestOutOfBoundRangeProof(t *testing.T) {
+	trie, vals := randomTrie(4096)
+	var entries entrySlice
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Sort(entries)
+
+	start, end := 100, 200
+	var keys, values [][]byte
+	for i := start; i < end; i++ {
+		keys = append(keys, entries[i].k)
+		values = append(values, entries[i].v)
+	}
+	// Prove a range narrower than the supplied leaves on the left side
+	proof := memorydb.New()
+	if err := trie.Prove(entries[start+1].k, 0, proof); err != nil {
+		t.Fatalf("Failed to prove the first node %v", err)
+	}
+	if err := trie.Prove(entries[end-1].k, 0, proof); err != nil {
+		t.Fatalf("Failed to prove the last node %v", err)
+	}
+	if err, _ := VerifyRangeProof(trie.Hash(), entries[start+1].k, entries[end-1].k, keys, values, proof); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	// Prove a range narrower than the supplied leaves on the right side
+	proof = memorydb.New()
+	if err := trie.Prove(entries[start].k, 0, proof); err != nil {
+		t.Fatalf("Failed to prove the first node %v", err)
+	}
+	if err := trie.Prove(entries[end-2].k, 0, proof); err != nil {
+		t.Fatalf("Failed to prove the last node %v", err)
+	}
+	if err, _ := VerifyRangeProof(trie.Hash(), entries[start].k, entries[end-2].k, keys, values, proof); err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+}
+
 // mutateByte changes one byte in b.
 func mutateByte(b []byte) {
 	for r := mrand.Intn(len(b)); ; {
@@ -3,6 +3,7 @@ package trie
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math/big"
 	mrand "math/rand"
 	"testing"
@@ -164,24 +165,20 @@ func (d *dummyDerivableList) Len() int {
 	return d.len
 }
 
-func (d *dummyDerivableList) GetRlp(i int) []byte {
+func (d *dummyDerivableList) EncodeIndex(i int, w *bytes.Buffer) {
 	src := mrand.NewSource(int64(d.seed + i))
 	// max item size 256, at least 1 byte per item
 	size := 1 + src.Int63()&0x00FF
-	data := make([]byte, size)
-	_, err := mrand.New(src).Read(data)
-	if err != nil {
-		panic(err)
-	}
-	return data
+	io.CopyN(w, mrand.New(src), size)
 }
 
 func printList(l types.DerivableList) {
 	fmt.Printf("list length: %d\n", l.Len())
 	fmt.Printf("{\n")
 	for i := 0; i < l.Len(); i++ {
-		v := l.GetRlp(i)
-		fmt.Printf("\"0x%x\",\n", v)
+		var buf bytes.Buffer
+		l.EncodeIndex(i, &buf)
+		fmt.Printf("\"0x%x\",\n", buf.Bytes())
 	}
 	fmt.Printf("},\n")
 }
@@ -210,8 +207,8 @@ func newFlatList(rlpvals []string) *flatList {
 func (f *flatList) Len() int {
 	return len(f.rlpvals)
 }
-func (f *flatList) GetRlp(i int) []byte {
-	return hexutil.MustDecode(f.rlpvals[i])
+func (f *flatList) EncodeIndex(i int, w *bytes.Buffer) {
+	w.Write(hexutil.MustDecode(f.rlpvals[i]))
 }
 
 // TestDerivableList contains testcases found via fuzzing